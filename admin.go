@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// misc -----------------------------------------------------------------------
+
+// scheduled job status; a POST with a job name starts that job now
+func adminJobsHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+		if !watcher.IsAdmin() {
+			http.NotFound(w, r)
+			return
+		}
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Logger()
+
+		if r.Method == http.MethodPost {
+			name := r.FormValue("job")
+			job, ok := getJob(name)
+			if ok {
+				sublog.Info().Str("job", name).Msg("job started from admin page")
+				go runJob(deps, deps.logger.With().Str("task", "scheduler").Logger(), job)
+				rc.messages = append(rc.messages, Message{fmt.Sprintf("Started %s, refresh to see how it went", name), "success"})
+			} else {
+				rc.messages = append(rc.messages, Message{fmt.Sprintf("Sorry, there is no job called %q", name), "error"})
+			}
+		}
+
+		webdata["jobs"] = getJobStatuses(deps, sublog)
+		renderTemplate(w, r, deps, sublog, "admin-jobs")
+	})
+}
@@ -0,0 +1,463 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	alertPriceAbove     = "price_above"
+	alertPriceBelow     = "price_below"
+	alertPctChange      = "pct_change"
+	alertVolumeMultiple = "volume_multiple"
+	alertNewHigh52w     = "new_high_52w"
+	alertNewLow52w      = "new_low_52w"
+
+	alertArmed     = "armed"
+	alertTriggered = "triggered"
+
+	alertLockKey = "scheduler/alerts"
+)
+
+var alertTypes = map[string]string{
+	alertPriceAbove:     "Price rises above",
+	alertPriceBelow:     "Price falls below",
+	alertPctChange:      "Change on the day reaches %",
+	alertVolumeMultiple: "Volume reaches multiple of average",
+	alertNewHigh52w:     "New 52-week high",
+	alertNewLow52w:      "New 52-week low",
+}
+
+type Alert struct {
+	AlertId         uint64 `db:"alert_id"`
+	EId             string
+	WatcherId       uint64       `db:"watcher_id"`
+	TickerId        uint64       `db:"ticker_id"`
+	TickerSymbol    string       `db:"ticker_symbol"`
+	ExchangeId      uint64       `db:"exchange_id"`
+	AlertType       string       `db:"alert_type"`
+	AlertValue      float64      `db:"alert_value"`
+	AlertStatus     string       `db:"alert_status"`
+	CooldownMinutes int          `db:"cooldown_minutes"`
+	LastTriggered   sql.NullTime `db:"last_triggered_datetime"`
+	CreateDatetime  time.Time    `db:"create_datetime"`
+	UpdateDatetime  time.Time    `db:"update_datetime"`
+}
+
+type AlertTrigger struct {
+	AlertTriggerId uint64 `db:"alert_trigger_id"`
+	EId            string
+	AlertId        uint64    `db:"alert_id"`
+	WatcherId      uint64    `db:"watcher_id"`
+	TickerId       uint64    `db:"ticker_id"`
+	TriggerPrice   float64   `db:"trigger_price"`
+	TriggerMessage string    `db:"trigger_message"`
+	Delivered      bool      `db:"delivered"`
+	CreateDatetime time.Time `db:"create_datetime"`
+	UpdateDatetime time.Time `db:"update_datetime"`
+}
+
+// the day-level numbers some alert types compare the live quote against
+type AlertTickerStats struct {
+	AvgVolume float64
+	High52w   float64
+	Low52w    float64
+}
+
+// object methods -------------------------------------------------------------
+
+func (a *Alert) create(deps *Dependencies, sublog zerolog.Logger) error {
+	db := deps.db
+
+	insert := "INSERT INTO alert (watcher_id, ticker_id, alert_type, alert_value, alert_status, cooldown_minutes) VALUES (?, ?, ?, ?, ?, ?)"
+	res, err := db.Exec(insert, a.WatcherId, a.TickerId, a.AlertType, a.AlertValue, alertArmed, a.CooldownMinutes)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on INSERT")
+		return err
+	}
+	alertId, err := res.LastInsertId()
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on LAST_INSERTID")
+		return err
+	}
+	a.AlertId = uint64(alertId)
+	a.AlertStatus = alertArmed
+	a.EId = encryptId(deps, sublog, "alert", a.AlertId)
+	return nil
+}
+
+func (a *Alert) delete(deps *Dependencies, sublog zerolog.Logger) error {
+	db := deps.db
+
+	_, err := db.Exec("DELETE FROM alert WHERE alert_id=? AND watcher_id=?", a.AlertId, a.WatcherId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on DELETE")
+	}
+	return err
+}
+
+func (a *Alert) setStatus(deps *Dependencies, sublog zerolog.Logger, status string) error {
+	db := deps.db
+
+	var err error
+	if status == alertTriggered {
+		_, err = db.Exec("UPDATE alert SET alert_status=?, last_triggered_datetime=CURRENT_TIMESTAMP, update_datetime=CURRENT_TIMESTAMP WHERE alert_id=?", status, a.AlertId)
+	} else {
+		_, err = db.Exec("UPDATE alert SET alert_status=?, update_datetime=CURRENT_TIMESTAMP WHERE alert_id=?", status, a.AlertId)
+	}
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on UPDATE")
+		return err
+	}
+	a.AlertStatus = status
+	return nil
+}
+
+func (a Alert) Description() string {
+	switch a.AlertType {
+	case alertPriceAbove, alertPriceBelow:
+		return fmt.Sprintf("%s $%.2f", alertTypes[a.AlertType], a.AlertValue)
+	case alertPctChange:
+		return fmt.Sprintf("%s %+.2f%%", strings.TrimSuffix(alertTypes[a.AlertType], " %"), a.AlertValue)
+	case alertVolumeMultiple:
+		return fmt.Sprintf("Volume reaches %gx average", a.AlertValue)
+	}
+	return alertTypes[a.AlertType]
+}
+
+// is the alert's condition true for this quote; the message says why
+func (a Alert) isMet(quote Quote, stats AlertTickerStats) (bool, string) {
+	switch a.AlertType {
+	case alertPriceAbove:
+		return quote.QuotePrice >= a.AlertValue, fmt.Sprintf("%s is at $%.2f, above your $%.2f alert", a.TickerSymbol, quote.QuotePrice, a.AlertValue)
+	case alertPriceBelow:
+		return quote.QuotePrice > 0 && quote.QuotePrice <= a.AlertValue, fmt.Sprintf("%s is at $%.2f, below your $%.2f alert", a.TickerSymbol, quote.QuotePrice, a.AlertValue)
+	case alertPctChange:
+		if quote.QuotePrevClose == 0 {
+			return false, ""
+		}
+		changePct := (quote.QuotePrice - quote.QuotePrevClose) / quote.QuotePrevClose * 100
+		met := (a.AlertValue >= 0 && changePct >= a.AlertValue) || (a.AlertValue < 0 && changePct <= a.AlertValue)
+		return met, fmt.Sprintf("%s is %+.2f%% on the day, past your %+.2f%% alert", a.TickerSymbol, changePct, a.AlertValue)
+	case alertVolumeMultiple:
+		if stats.AvgVolume == 0 {
+			return false, ""
+		}
+		multiple := float64(quote.QuoteVolume) / stats.AvgVolume
+		return multiple >= a.AlertValue, fmt.Sprintf("%s volume is %.1fx its average, past your %gx alert", a.TickerSymbol, multiple, a.AlertValue)
+	case alertNewHigh52w:
+		return stats.High52w > 0 && quote.QuoteHigh > stats.High52w, fmt.Sprintf("%s hit a new 52-week high of $%.2f", a.TickerSymbol, quote.QuoteHigh)
+	case alertNewLow52w:
+		return stats.Low52w > 0 && quote.QuoteLow > 0 && quote.QuoteLow < stats.Low52w, fmt.Sprintf("%s hit a new 52-week low of $%.2f", a.TickerSymbol, quote.QuoteLow)
+	}
+	return false, ""
+}
+
+func (a Alert) inCooldown() bool {
+	if !a.LastTriggered.Valid {
+		return false
+	}
+	return time.Since(a.LastTriggered.Time) < time.Duration(a.CooldownMinutes)*time.Minute
+}
+
+func (at *AlertTrigger) create(deps *Dependencies, sublog zerolog.Logger) error {
+	db := deps.db
+
+	insert := "INSERT INTO alert_trigger (alert_id, watcher_id, ticker_id, trigger_price, trigger_message, delivered) VALUES (?, ?, ?, ?, ?, false)"
+	res, err := db.Exec(insert, at.AlertId, at.WatcherId, at.TickerId, at.TriggerPrice, at.TriggerMessage)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on INSERT")
+		return err
+	}
+	alertTriggerId, err := res.LastInsertId()
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on LAST_INSERTID")
+		return err
+	}
+	at.AlertTriggerId = uint64(alertTriggerId)
+	return nil
+}
+
+// misc -----------------------------------------------------------------------
+
+func isAlertType(alertType string) bool {
+	_, ok := alertTypes[alertType]
+	return ok
+}
+
+func getAlerts(deps *Dependencies, sublog zerolog.Logger, where string, args ...interface{}) ([]Alert, error) {
+	db := deps.db
+
+	var alert Alert
+	alerts := make([]Alert, 0)
+
+	rows, err := db.Queryx("SELECT alert.*, ticker.ticker_symbol, ticker.exchange_id FROM alert JOIN ticker USING (ticker_id) WHERE "+where+" ORDER BY alert.create_datetime", args...)
+	if err != nil {
+		return alerts, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&alert)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+		} else {
+			alerts = append(alerts, alert)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return alerts, err
+	}
+
+	return alerts, nil
+}
+
+func getWatcherAlerts(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) ([]Alert, error) {
+	alerts, err := getAlerts(deps, sublog, "alert.watcher_id=?", watcher.WatcherId)
+	for x := range alerts {
+		alerts[x].EId = encryptId(deps, sublog, "alert", alerts[x].AlertId)
+	}
+	return alerts, err
+}
+
+func getWatcherAlertTriggers(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, limit int) ([]AlertTrigger, error) {
+	db := deps.db
+
+	var alertTrigger AlertTrigger
+	alertTriggers := make([]AlertTrigger, 0)
+
+	rows, err := db.Queryx("SELECT * FROM alert_trigger WHERE watcher_id=? ORDER BY create_datetime DESC LIMIT ?", watcher.WatcherId, limit)
+	if err != nil {
+		return alertTriggers, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&alertTrigger)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+		} else {
+			alertTriggers = append(alertTriggers, alertTrigger)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return alertTriggers, err
+	}
+
+	return alertTriggers, nil
+}
+
+// any alerts that fired since the watcher last loaded a page, as messages to
+// show them; they are marked delivered on the way out
+func deliverWatcherAlerts(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) []Message {
+	db := deps.db
+	messages := []Message{}
+
+	if watcher.WatcherId == 0 {
+		return messages
+	}
+
+	var alertTrigger AlertTrigger
+	rows, err := db.Queryx("SELECT * FROM alert_trigger WHERE watcher_id=? AND delivered=false ORDER BY create_datetime", watcher.WatcherId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on SELECT")
+		return messages
+	}
+	defer rows.Close()
+
+	var lastId uint64
+	for rows.Next() {
+		err = rows.StructScan(&alertTrigger)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+			continue
+		}
+		messages = append(messages, Message{alertTrigger.TriggerMessage, "alert"})
+		lastId = alertTrigger.AlertTriggerId
+	}
+	if lastId == 0 {
+		return messages
+	}
+
+	_, err = db.Exec("UPDATE alert_trigger SET delivered=true WHERE watcher_id=? AND alert_trigger_id<=?", watcher.WatcherId, lastId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on UPDATE")
+	}
+	return messages
+}
+
+// the year before today, split-adjusted like the charts: the EODs are stored
+// as traded, and a quote is in today's shares
+func getAlertTickerStats(deps *Dependencies, sublog zerolog.Logger, tickerId uint64) AlertTickerStats {
+	ticker := Ticker{TickerId: tickerId}
+	today := time.Now().Format(sqlDateParseType)
+	dailies, err := deps.tickers.GetTickerDailies(sublog, tickerId, time.Now().AddDate(-1, 0, 0).Format(sqlDateParseType), today)
+	if err != nil {
+		sublog.Warn().Err(err).Uint64("ticker_id", tickerId).Msg("failed to get a year of EODs")
+		return AlertTickerStats{}
+	}
+	splits, err := ticker.getSplits(deps, sublog)
+	if err != nil {
+		sublog.Warn().Err(err).Uint64("ticker_id", tickerId).Msg("failed to get splits, skipping 52-week and volume alerts")
+		return AlertTickerStats{}
+	}
+	return calcAlertTickerStats(adjustDailiesForSplits(sublog, dailies, splits))
+}
+
+// the high and low of the dailies given, and the average volume of the last
+// alertAvgVolumeDays of them
+func calcAlertTickerStats(dailies []TickerDaily) AlertTickerStats {
+	stats := AlertTickerStats{}
+	for x, daily := range dailies {
+		if x == 0 || daily.HighPrice > stats.High52w {
+			stats.High52w = daily.HighPrice
+		}
+		if x == 0 || daily.LowPrice < stats.Low52w {
+			stats.Low52w = daily.LowPrice
+		}
+	}
+
+	recent := dailies
+	if len(recent) > alertAvgVolumeDays {
+		recent = recent[len(recent)-alertAvgVolumeDays:]
+	}
+	if len(recent) > 0 {
+		total := 0.0
+		for _, daily := range recent {
+			total += float64(daily.Volume)
+		}
+		stats.AvgVolume = total / float64(len(recent))
+	}
+	return stats
+}
+
+// check every alert against a fresh quote: an armed alert whose condition is
+// met (and isn't cooling down) fires once and waits; a triggered alert re-arms
+// only after the cooldown is over and its condition has gone false again
+func evaluateAlerts(deps *Dependencies, sublog zerolog.Logger) {
+	alerts, err := getAlerts(deps, sublog, "1=1")
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to load alerts")
+		return
+	}
+	if len(alerts) == 0 {
+		return
+	}
+
+	// each ticker is only checked while its own exchange is open
+	marketOpen := map[uint64]bool{}
+	openAlerts := make([]Alert, 0, len(alerts))
+	for _, alert := range alerts {
+		open, ok := marketOpen[alert.ExchangeId]
+		if !ok {
+			exchange, err := getExchangeById(deps, sublog, alert.ExchangeId)
+			if err != nil {
+				sublog.Warn().Err(err).Uint64("exchange_id", alert.ExchangeId).Msg("failed to get exchange for alert")
+			}
+			open = err == nil && isMarketOpen(exchange)
+			marketOpen[alert.ExchangeId] = open
+		}
+		if open {
+			openAlerts = append(openAlerts, alert)
+		}
+	}
+	alerts = openAlerts
+	if len(alerts) == 0 {
+		return
+	}
+
+	symbols := make([]string, 0)
+	seen := map[string]bool{}
+	for _, alert := range alerts {
+		if !seen[alert.TickerSymbol] {
+			symbols = append(symbols, alert.TickerSymbol)
+			seen[alert.TickerSymbol] = true
+		}
+	}
+
+	quotes := map[string]Quote{}
+	for start := 0; start < len(symbols); start += alertQuoteBatchSize {
+		end := start + alertQuoteBatchSize
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+		batch, err := loadMultiTickerQuotes(deps, sublog, symbols[start:end])
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to get quotes for alerts")
+			continue
+		}
+		for symbol, quote := range batch {
+			quotes[symbol] = quote
+		}
+	}
+
+	stats := map[uint64]AlertTickerStats{}
+	for _, alert := range alerts {
+		quote, ok := quotes[alert.TickerSymbol]
+		if !ok {
+			continue
+		}
+		alertlog := sublog.With().Uint64("alert_id", alert.AlertId).Str("symbol", alert.TickerSymbol).Str("alert_type", alert.AlertType).Logger()
+
+		if _, ok := stats[alert.TickerId]; !ok {
+			stats[alert.TickerId] = getAlertTickerStats(deps, alertlog, alert.TickerId)
+		}
+		met, message := alert.isMet(quote, stats[alert.TickerId])
+
+		switch {
+		case alert.AlertStatus == alertTriggered && !met && !alert.inCooldown():
+			alert.setStatus(deps, alertlog, alertArmed)
+			alertlog.Info().Msg("alert re-armed")
+		case alert.AlertStatus == alertArmed && met && !alert.inCooldown():
+			trigger := AlertTrigger{
+				AlertId:        alert.AlertId,
+				WatcherId:      alert.WatcherId,
+				TickerId:       alert.TickerId,
+				TriggerPrice:   quote.QuotePrice,
+				TriggerMessage: message,
+			}
+			if err := trigger.create(deps, alertlog); err != nil {
+				continue
+			}
+			alert.setStatus(deps, alertlog, alertTriggered)
+			alertlog.Info().Float64("price", quote.QuotePrice).Msg("alert triggered")
+		}
+	}
+}
+
+// check the alerts once an AlertCheckInterval until ctx is done. Like the
+// scheduler, the worker runs this (and the server too, with the local
+// backend); the redis lock keeps two of them from evaluating at once
+func runAlertEvaluator(deps *Dependencies, ctx context.Context) {
+	sublog := deps.logger.With().Str("task", "alerts").Logger()
+
+	interval := time.NewTicker(time.Duration(deps.config.AlertCheckInterval) * time.Second)
+	defer interval.Stop()
+	sublog.Info().Int("interval", deps.config.AlertCheckInterval).Msg("started alert evaluator")
+
+	for {
+		select {
+		case <-ctx.Done():
+			sublog.Info().Msg("stopped alert evaluator")
+			return
+		case <-interval.C:
+		}
+
+		locked, err := lockJob(deps, alertLockKey)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to lock alert evaluator")
+			continue
+		}
+		if !locked {
+			sublog.Debug().Msg("alerts are being evaluated elsewhere")
+			continue
+		}
+		evaluateAlerts(deps, sublog)
+		expireJobLock(deps, sublog, alertLockKey, 0)
+	}
+}
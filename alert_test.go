@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/weirdtangent/yhfinance"
+)
+
+func dailyOn(date string, high, low float64, volume int64) TickerDaily {
+	return TickerDaily{PriceDatetime: parsePortfolioDate(date), HighPrice: high, LowPrice: low, Volume: volume}
+}
+
+// a 4:1 split on 2024-02-01, with the EODs on either side of it as traded
+var splitDailies = []TickerDaily{
+	dailyOn("2024-01-02", 400, 380, 1000),
+	dailyOn("2024-01-03", 410, 390, 1000),
+	dailyOn("2024-02-01", 104, 100, 4000),
+	dailyOn("2024-02-02", 106, 99, 4000),
+}
+var fourForOne = []TickerSplit{splitOn("2024-02-01", "4:1")}
+
+func TestCalcAlertTickerStats(t *testing.T) {
+	// 10 quiet days and then the 50 that count
+	busy := make([]TickerDaily, 0, 60)
+	for x := 0; x < 60; x++ {
+		volume := int64(200)
+		if x < 10 {
+			volume = 100
+		}
+		busy = append(busy, dailyOn("2024-01-02", 10, 9, volume))
+	}
+
+	tests := []struct {
+		name    string
+		dailies []TickerDaily
+		splits  []TickerSplit
+		want    AlertTickerStats
+	}{
+		{"no splits", splitDailies[2:], nil, AlertTickerStats{AvgVolume: 4000, High52w: 106, Low52w: 99}},
+		// in post-split shares the high is after the split and the low before it
+		{"split inside the year", splitDailies, fourForOne, AlertTickerStats{AvgVolume: 4000, High52w: 106, Low52w: 95}},
+		{"split after the last EOD", splitDailies[:2], []TickerSplit{splitOn("2024-06-03", "4:1")},
+			AlertTickerStats{AvgVolume: 4000, High52w: 102.5, Low52w: 95}},
+		{"reverse split", splitDailies[:2], []TickerSplit{splitOn("2024-02-01", "1:10")},
+			AlertTickerStats{AvgVolume: 100, High52w: 4100, Low52w: 3800}},
+		{"average of the last 50 days", busy, nil, AlertTickerStats{AvgVolume: 200, High52w: 10, Low52w: 9}},
+		{"no dailies", []TickerDaily{}, fourForOne, AlertTickerStats{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calcAlertTickerStats(adjustDailiesForSplits(zerolog.Nop(), tt.dailies, tt.splits))
+			near := func(a, b float64) bool { return math.Abs(a-b) < 0.0001 }
+			if !near(got.AvgVolume, tt.want.AvgVolume) || !near(got.High52w, tt.want.High52w) || !near(got.Low52w, tt.want.Low52w) {
+				t.Errorf("calcAlertTickerStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlertIsMet(t *testing.T) {
+	stats := calcAlertTickerStats(adjustDailiesForSplits(zerolog.Nop(), splitDailies, fourForOne))
+	quote := func(high, low float64, volume int64) Quote {
+		return Quote{YHQuote: yhfinance.YHQuote{QuotePrice: high, QuoteHigh: high, QuoteLow: low, QuoteVolume: volume}}
+	}
+
+	tests := []struct {
+		name      string
+		alertType string
+		value     float64
+		quote     Quote
+		want      bool
+	}{
+		// 110 is a new high after the split, though the EODs as traded went to 410
+		{"new high past the split", alertNewHigh52w, 0, quote(110, 105, 4000), true},
+		{"inside the range", alertNewHigh52w, 0, quote(105, 100, 4000), false},
+		{"new low past the split", alertNewLow52w, 0, quote(96, 90, 4000), true},
+		// 96 is below every low as traded but not the 95 of the pre-split days
+		{"no new low", alertNewLow52w, 0, quote(100, 96, 4000), false},
+		{"twice the post-split volume", alertVolumeMultiple, 2, quote(100, 99, 8000), true},
+		// over twice the average of the volumes as traded, not of the adjusted ones
+		{"no spike against the adjusted average", alertVolumeMultiple, 2, quote(100, 99, 5500), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			alert := Alert{TickerSymbol: "TEST", AlertType: tt.alertType, AlertValue: tt.value}
+			if got, _ := alert.isMet(tt.quote, stats); got != tt.want {
+				t.Errorf("isMet(%+v) = %t, want %t", tt.quote, got, tt.want)
+			}
+		})
+	}
+}
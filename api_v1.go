@@ -25,17 +25,20 @@ type jsonResponseData struct {
 //   /api/quotes
 //   /api/recents
 //   /api/chart
+//   /api/costbasis
+//   /api/alerts
+//   /api/watchlists
 
 func apiV1Handler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		watcher := checkAuthState(w, r, deps, *deps.logger)
+		rc := getRequestContext(r)
+		watcher := checkAuthState(w, r, deps, *rc.logger)
 
 		w.Header().Add("Content-Type", "application/json")
 
 		// get already inplace nonce from the current page and use it so our answer is allowed
 		reqHeader := r.Header
 		nonce := reqHeader.Get("X-Nonce")
-		deps.nonce = nonce // we don't use webdata in api, so no need to fix that one
 
 		params := mux.Vars(r)
 		endpoint := params["endpoint"]
@@ -46,7 +49,7 @@ func apiV1Handler(deps *Dependencies) http.HandlerFunc {
 			Success:    false,
 			Data:       make(map[string]interface{}),
 		}
-		sublog := deps.logger.With().Str("api_version", jsonResponse.ApiVersion).Str("endpoint", jsonResponse.Endpoint).Logger()
+		sublog := rc.logger.With().Str("api_version", jsonResponse.ApiVersion).Str("endpoint", jsonResponse.Endpoint).Logger()
 
 		switch endpoint {
 		case "version":
@@ -72,13 +75,68 @@ func apiV1Handler(deps *Dependencies) http.HandlerFunc {
 		case "chart":
 			chart := r.FormValue("chart")
 			symbol := r.FormValue("symbol")
-			timespan, err := strconv.Atoi(r.FormValue("timespan"))
+			chartRange, err := parseChartRange(r.FormValue("timespan"), r.FormValue("from"), r.FormValue("to"), r.FormValue("interval"))
 			if err != nil {
 				jsonResponse.Success = false
-				jsonResponse.Message = "failure: invalid timespan"
+				jsonResponse.Message = "failure: " + err.Error()
 				break
 			}
-			apiChart(deps, sublog, nonce, chart, symbol, timespan, &jsonResponse)
+			chartRange.TotalReturn = r.FormValue("totalreturn") == "1"
+			// no indicators parameter at all means the chart's defaults, an
+			// empty one means none
+			var indicators []Indicator
+			if _, ok := r.Form["indicators"]; ok {
+				indicators = parseIndicators(r.FormValue("indicators"))
+			}
+			if chart == "compare" {
+				apiCompareChart(deps, sublog, nonce, symbol, r.FormValue("benchmark"), chartRange, &jsonResponse)
+				break
+			}
+			apiChart(deps, sublog, nonce, chart, symbol, chartRange, indicators, &jsonResponse)
+
+		case "costbasis":
+			method := r.FormValue("method")
+			apiCostBasis(deps, sublog, watcher, method, &jsonResponse)
+
+		case "alerts":
+			if r.FormValue("delete") != "" {
+				apiAlertsDelete(deps, sublog, watcher, r.FormValue("delete"), &jsonResponse)
+			} else if r.FormValue("create") != "" {
+				value, _ := strconv.ParseFloat(r.FormValue("value"), 64)
+				cooldown, err := strconv.Atoi(r.FormValue("cooldown"))
+				if err != nil || cooldown <= 0 {
+					cooldown = defaultAlertCooldown
+				}
+				apiAlertsCreate(deps, sublog, watcher, r.FormValue("create"), r.FormValue("type"), value, cooldown, &jsonResponse)
+			} else {
+				apiAlertsList(deps, sublog, watcher, &jsonResponse)
+			}
+
+		case "watchlists":
+			watchlistEId := r.FormValue("watchlist")
+			switch {
+			case r.FormValue("create") != "":
+				apiWatchlistsCreate(deps, sublog, watcher, r.FormValue("create"), &jsonResponse)
+			case watchlistEId == "":
+				apiWatchlistsList(deps, sublog, watcher, "", &jsonResponse)
+			case r.FormValue("add") != "":
+				apiWatchlistsTickers(deps, sublog, watcher, watchlistEId, "add", r.FormValue("add"), r.FormValue("note"), 0, &jsonResponse)
+			case r.FormValue("remove") != "":
+				apiWatchlistsTickers(deps, sublog, watcher, watchlistEId, "remove", r.FormValue("remove"), "", 0, &jsonResponse)
+			case r.FormValue("move") != "":
+				position, _ := strconv.Atoi(r.FormValue("position"))
+				apiWatchlistsTickers(deps, sublog, watcher, watchlistEId, "move", r.FormValue("move"), "", position, &jsonResponse)
+			case r.FormValue("symbol") != "":
+				apiWatchlistsTickers(deps, sublog, watcher, watchlistEId, "note", r.FormValue("symbol"), r.FormValue("note"), 0, &jsonResponse)
+			case r.FormValue("rename") != "":
+				apiWatchlistsUpdate(deps, sublog, watcher, watchlistEId, "rename", r.FormValue("rename"), &jsonResponse)
+			case r.FormValue("share") != "":
+				apiWatchlistsUpdate(deps, sublog, watcher, watchlistEId, "share", r.FormValue("share"), &jsonResponse)
+			case r.FormValue("delete") != "":
+				apiWatchlistsUpdate(deps, sublog, watcher, watchlistEId, "delete", "", &jsonResponse)
+			default:
+				apiWatchlistsList(deps, sublog, watcher, watchlistEId, &jsonResponse)
+			}
 
 		default:
 			jsonResponse.Success = false
@@ -98,6 +156,10 @@ func apiQuotes(deps *Dependencies, sublog zerolog.Logger, symbolStr string, json
 		return
 	}
 
+	// the page refreshes quickly while any of its tickers is trading, and
+	// not so quickly while any is only in extended hours
+	quotesMarketOpen := false
+	quotesSession := sessionClosed
 	for _, quote := range quotes {
 		symbol := quote.Symbol
 		ticker, err := getTickerBySymbol(deps, sublog, symbol)
@@ -123,7 +185,27 @@ func apiQuotes(deps *Dependencies, sublog zerolog.Logger, symbolStr string, json
 			jsonR.Data[symbol+":change_dir"] = "unchanged"
 		}
 		jsonR.Data[symbol+":volume"] = fmt.Sprintf("%d", ticker.MarketVolume)
-		if isMarketOpen() {
+		exchange, err := getExchangeById(deps, sublog, ticker.ExchangeId)
+		if err != nil {
+			exchange = homeExchange
+		}
+		marketOpen := isMarketOpen(exchange)
+		quotesMarketOpen = quotesMarketOpen || marketOpen
+		if marketOpen {
+			ticker.saveIntraday(deps, sublog, quote)
+		}
+		session := marketSession(exchange)
+		if session == sessionRegular || (session != sessionClosed && quotesSession == sessionClosed) {
+			quotesSession = session
+		}
+		jsonR.Data[symbol+":session"] = session
+		if extended := quote.extendedHours(session); extended.Price > 0 {
+			jsonR.Data[symbol+":ext_price"] = fmt.Sprintf("$%.2f", extended.Price)
+			jsonR.Data[symbol+":ext_change_amt"] = fmt.Sprintf("$%.2f", extended.ChangeAmt)
+			jsonR.Data[symbol+":ext_change_pct"] = fmt.Sprintf("%.2f%%", extended.ChangePct)
+			jsonR.Data[symbol+":ext_asof"] = extended.Time.Format("Jan 02 15:04")
+		}
+		if marketOpen {
 			jsonR.Data[symbol+":asof"] = ticker.MarketPriceDatetime.Format("Jan 02 15:04:05")
 		} else {
 			jsonR.Data[symbol+":asof"] = ticker.MarketPriceDatetime.Format("Jan 02 15:04")
@@ -139,7 +221,9 @@ func apiQuotes(deps *Dependencies, sublog zerolog.Logger, symbolStr string, json
 	jsonR.Data["last_checked_since"] = lastCheckedSince
 	jsonR.Data["updating_news_now"] = updatingNewsNow
 
-	jsonR.Data["is_market_open"] = isMarketOpen()
+	jsonR.Data["is_market_open"] = isMarketOpen(homeExchange)
+	jsonR.Data["quotes_market_open"] = quotesMarketOpen
+	jsonR.Data["quotes_session"] = quotesSession
 	jsonR.Success = true
 }
 
@@ -188,11 +272,17 @@ func apiRecents(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, acti
 	jsonR.Message = "ok"
 }
 
-func apiChart(deps *Dependencies, sublog zerolog.Logger, nonce string, chart string, symbol string, timespan int, jsonR *jsonResponseData) {
-	sublog = sublog.With().Str("chart", chart).Str("symbol", symbol).Int("timespan", timespan).Logger()
+func apiChart(deps *Dependencies, sublog zerolog.Logger, nonce string, chart string, symbol string, chartRange ChartRange, indicators []Indicator, jsonR *jsonResponseData) {
+	sublog = sublog.With().Str("chart", chart).Str("symbol", symbol).Int("timespan", chartRange.Days).Str("from", chartRange.From).Str("to", chartRange.To).Str("interval", chartRange.interval()).Logger()
 
 	start := time.Now()
 
+	if strings.HasPrefix(chart, "index") {
+		apiIndexChart(deps, sublog, nonce, chart, symbol, chartRange, indicators, jsonR)
+		sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: build chart")
+		return
+	}
+
 	ticker, err := getTickerBySymbol(deps, sublog, symbol)
 	if err != nil {
 		sublog.Error().Msg("failed to find symbol {symbol}")
@@ -207,55 +297,84 @@ func apiChart(deps *Dependencies, sublog zerolog.Logger, nonce string, chart str
 		jsonR.Message = "failure: unknown symbol"
 		return
 	}
+	location := getMarketCalendar(exchange).location()
 
 	switch chart {
 	case "symbolLine":
-		ticker_dailies, _ := ticker.getTickerEODs(deps, sublog, timespan)
+		ticker_dailies, _ := ticker.getChartEODs(deps, sublog, chartRange, location)
 		webwatches, _ := loadWebWatches(deps, sublog, ticker.TickerId)
-		chartHTML := chartHandlerTickerDailyLine(deps, sublog, ticker, &exchange, ticker_dailies, webwatches)
+		dividends, _ := ticker.getDividends(deps, sublog)
+		chartHTML := chartHandlerTickerDailyLine(deps, sublog, nonce, ticker, &exchange, ticker_dailies, webwatches, dividends, indicators, chartRange)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "symbolKline":
-		ticker_dailies, _ := ticker.getTickerEODs(deps, sublog, timespan)
+		ticker_dailies, _ := ticker.getChartEODs(deps, sublog, chartRange, location)
 		webwatches, _ := loadWebWatches(deps, sublog, ticker.TickerId)
-		chartHTML := chartHandlerTickerDailyKLine(deps, sublog, ticker, &exchange, ticker_dailies, webwatches)
+		dividends, _ := ticker.getDividends(deps, sublog)
+		chartHTML := chartHandlerTickerDailyKLine(deps, sublog, nonce, ticker, &exchange, ticker_dailies, webwatches, dividends, indicators, chartRange)
+		jsonR.Data["chartHTML"] = chartHTML
+		jsonR.Success = true
+		jsonR.Message = "ok"
+	case "symbolIntraday":
+		intradays, err := ticker.getSessionIntradays(deps, sublog, exchange)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to load intraday samples")
+		}
+		chartHTML := chartHandlerTickerIntraday(deps, sublog, nonce, ticker, &exchange, intradays)
+		jsonR.Data["chartHTML"] = chartHTML
+		jsonR.Success = true
+		jsonR.Message = "ok"
+	case "symbolBenchmark":
+		ticker_dailies, _ := ticker.getChartEODs(deps, sublog, chartRange, location)
+		mi, err := getBenchmarkIndex(deps, sublog, exchange)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to find benchmark index")
+			jsonR.Success = false
+			jsonR.Message = "failure: no benchmark index"
+			return
+		}
+		if mi.needEODs(deps, sublog) {
+			fetchMarketIndexEODs(deps, sublog, mi)
+		}
+		index_dailies, _ := mi.getChartEODs(deps, sublog, chartRange)
+		chartHTML := chartHandlerBenchmarkCompare(deps, sublog, nonce, ticker, &exchange, ticker_dailies, mi, index_dailies, chartRange)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "financialQuarterlyBar":
 		qtrBarStrs, qtrBarValues, _ := ticker.GetFinancials(deps, sublog, "Quarterly", "bar", 0)
-		chartHTML := chartHandlerFinancialsBar(deps, sublog, ticker, &exchange, qtrBarStrs, qtrBarValues)
+		chartHTML := chartHandlerFinancialsBar(deps, sublog, nonce, ticker, &exchange, qtrBarStrs, qtrBarValues)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "financialAnnualBar":
 		annBarStrs, annBarValues, _ := ticker.GetFinancials(deps, sublog, "Annual", "bar", 0)
-		chartHTML := chartHandlerFinancialsBar(deps, sublog, ticker, &exchange, annBarStrs, annBarValues)
+		chartHTML := chartHandlerFinancialsBar(deps, sublog, nonce, ticker, &exchange, annBarStrs, annBarValues)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "financialQuarterlyLine":
 		qtrLineStrs, qtrLineValues, _ := ticker.GetFinancials(deps, sublog, "Quarterly", "line", 0)
-		chartHTML := chartHandlerFinancialsLine(deps, sublog, ticker, &exchange, qtrLineStrs, qtrLineValues, 0)
+		chartHTML := chartHandlerFinancialsLine(deps, sublog, nonce, ticker, &exchange, qtrLineStrs, qtrLineValues, 0)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "financialAnnualLine":
 		annLineStrs, annLineValues, _ := ticker.GetFinancials(deps, sublog, "Annual", "line", 0)
-		chartHTML := chartHandlerFinancialsLine(deps, sublog, ticker, &exchange, annLineStrs, annLineValues, 0)
+		chartHTML := chartHandlerFinancialsLine(deps, sublog, nonce, ticker, &exchange, annLineStrs, annLineValues, 0)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "financialQuarterlyPerc":
 		qtrPercStrs, qtrPercValues, _ := ticker.GetFinancials(deps, sublog, "Quarterly", "line", 1)
-		chartHTML := chartHandlerFinancialsLine(deps, sublog, ticker, &exchange, qtrPercStrs, qtrPercValues, 1)
+		chartHTML := chartHandlerFinancialsLine(deps, sublog, nonce, ticker, &exchange, qtrPercStrs, qtrPercValues, 1)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
 	case "financialAnnualcwPercLine":
 		annPercStrs, annPercValues, _ := ticker.GetFinancials(deps, sublog, "Annual", "line", 1)
-		chartHTML := chartHandlerFinancialsLine(deps, sublog, ticker, &exchange, annPercStrs, annPercValues, 1)
+		chartHTML := chartHandlerFinancialsLine(deps, sublog, nonce, ticker, &exchange, annPercStrs, annPercValues, 1)
 		jsonR.Data["chartHTML"] = chartHTML
 		jsonR.Success = true
 		jsonR.Message = "ok"
@@ -267,3 +386,388 @@ func apiChart(deps *Dependencies, sublog zerolog.Logger, nonce string, chart str
 
 	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: build chart")
 }
+
+// symbolStr is the comma-separated list of tickers, benchmark an optional
+// index symbol
+func apiCompareChart(deps *Dependencies, sublog zerolog.Logger, nonce string, symbolStr string, benchmark string, chartRange ChartRange, jsonR *jsonResponseData) {
+	sublog = sublog.With().Str("chart", "compare").Str("symbols", symbolStr).Int("timespan", chartRange.Days).Str("from", chartRange.From).Str("to", chartRange.To).Logger()
+
+	start := time.Now()
+
+	symbols := parseCompareSymbols(symbolStr)
+	if len(symbols) == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: no symbols"
+		return
+	}
+
+	series := loadCompareSeries(deps, sublog, symbols, benchmark, chartRange)
+	names := make([]string, 0, len(series))
+	for _, s := range series {
+		names = append(names, s.Name)
+	}
+	jsonR.Data["chartHTML"] = chartHandlerCompare(deps, sublog, nonce, strings.Join(names, " vs "), series, chartRange)
+	jsonR.Success = true
+	jsonR.Message = "ok"
+
+	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: build chart")
+}
+
+// index charts reuse the ticker charts with the index standing in for a
+// ticker; there are no watches or dividends to mark on them
+func apiIndexChart(deps *Dependencies, sublog zerolog.Logger, nonce string, chart string, symbol string, chartRange ChartRange, indicators []Indicator, jsonR *jsonResponseData) {
+	mi, err := getMarketIndex(deps, sublog, symbol)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to find market index")
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown index"
+		return
+	}
+	ticker := mi.asTicker()
+	exchange := mi.exchange()
+
+	switch chart {
+	case "indexLine":
+		dailies, _ := mi.getChartEODs(deps, sublog, chartRange)
+		jsonR.Data["chartHTML"] = chartHandlerTickerDailyLine(deps, sublog, nonce, ticker, &exchange, dailies, nil, nil, indicators, chartRange)
+	case "indexKline":
+		dailies, _ := mi.getChartEODs(deps, sublog, chartRange)
+		jsonR.Data["chartHTML"] = chartHandlerTickerDailyKLine(deps, sublog, nonce, ticker, &exchange, dailies, nil, nil, indicators, chartRange)
+	case "indexIntraday":
+		intradays, err := mi.getSessionIntradays(deps, sublog)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to load intraday samples")
+		}
+		ticker.MarketPrevClose = mi.priorClose(deps, sublog)
+		jsonR.Data["chartHTML"] = chartHandlerTickerIntraday(deps, sublog, nonce, ticker, &exchange, intradays)
+	default:
+		sublog.Error().Msg("unknown chart type {chart_type}")
+		jsonR.Success = false
+		jsonR.Message = "Failure: unknown chart"
+		return
+	}
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+func apiCostBasis(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, method string, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+	if !isCostBasisMethod(method) {
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown cost basis method"
+		return
+	}
+
+	err := updateWatcherCostBasisMethod(deps, sublog, watcher, method)
+	if err != nil {
+		jsonR.Success = false
+		jsonR.Message = "failure: could not update cost basis method"
+		return
+	}
+
+	jsonR.Data["method"] = method
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+func apiAlertsList(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+
+	alerts, err := getWatcherAlerts(deps, sublog, watcher)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to get alerts")
+		jsonR.Success = false
+		jsonR.Message = "failure: could not load alerts"
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(alerts))
+	for _, alert := range alerts {
+		list = append(list, map[string]interface{}{
+			"id":             alert.EId,
+			"symbol":         alert.TickerSymbol,
+			"type":           alert.AlertType,
+			"value":          alert.AlertValue,
+			"description":    alert.Description(),
+			"status":         alert.AlertStatus,
+			"cooldown":       alert.CooldownMinutes,
+			"last_triggered": alert.LastTriggered.Time,
+		})
+	}
+	jsonR.Data["alerts"] = list
+
+	triggers, err := getWatcherAlertTriggers(deps, sublog, watcher, 50)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to get alert triggers")
+	}
+	history := make([]map[string]interface{}, 0, len(triggers))
+	for _, trigger := range triggers {
+		history = append(history, map[string]interface{}{
+			"message":   trigger.TriggerMessage,
+			"price":     trigger.TriggerPrice,
+			"triggered": trigger.CreateDatetime,
+		})
+	}
+	jsonR.Data["history"] = history
+
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+func apiAlertsCreate(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, symbol, alertType string, value float64, cooldown int, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+	if !isAlertType(alertType) {
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown alert type"
+		return
+	}
+	if (alertType == alertPriceAbove || alertType == alertPriceBelow || alertType == alertVolumeMultiple) && value <= 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: invalid alert value"
+		return
+	}
+
+	ticker, err := getTickerBySymbol(deps, sublog, symbol)
+	if err != nil {
+		sublog.Error().Str("symbol", symbol).Msg("failed to find ticker")
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown symbol"
+		return
+	}
+
+	alert := Alert{
+		WatcherId:       watcher.WatcherId,
+		TickerId:        ticker.TickerId,
+		TickerSymbol:    ticker.TickerSymbol,
+		AlertType:       alertType,
+		AlertValue:      value,
+		CooldownMinutes: cooldown,
+	}
+	err = alert.create(deps, sublog)
+	if err != nil {
+		jsonR.Success = false
+		jsonR.Message = "failure: could not create alert"
+		return
+	}
+
+	jsonR.Data["id"] = alert.EId
+	jsonR.Data["description"] = alert.Description()
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+func apiAlertsDelete(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, alertEId string, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+
+	alerts, err := getWatcherAlerts(deps, sublog, watcher)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to get alerts")
+		jsonR.Success = false
+		jsonR.Message = "failure: could not load alerts"
+		return
+	}
+	for _, alert := range alerts {
+		if alert.EId == alertEId {
+			if err := alert.delete(deps, sublog); err != nil {
+				jsonR.Success = false
+				jsonR.Message = "failure: could not delete alert"
+				return
+			}
+			jsonR.Success = true
+			jsonR.Message = "ok"
+			return
+		}
+	}
+
+	jsonR.Success = false
+	jsonR.Message = "failure: unknown alert"
+}
+
+// every watchlist of the watcher's, or just the one asked for, with its
+// symbols in order
+func apiWatchlistsList(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, watchlistEId string, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+
+	watchlists, err := getWatcherWatchlists(deps, sublog, watcher)
+	if err != nil {
+		jsonR.Success = false
+		jsonR.Message = "failure: could not load watchlists"
+		return
+	}
+	list := make([]map[string]interface{}, 0, len(watchlists))
+	for _, watchlist := range watchlists {
+		if watchlistEId != "" && watchlist.EId != watchlistEId {
+			continue
+		}
+		entries, err := watchlist.entries(deps, sublog)
+		if err != nil {
+			jsonR.Success = false
+			jsonR.Message = "failure: could not load watchlists"
+			return
+		}
+		tickers := make([]map[string]interface{}, 0, len(entries))
+		for x, entry := range entries {
+			tickers = append(tickers, map[string]interface{}{
+				"symbol":   entry.TickerSymbol,
+				"note":     entry.Note,
+				"position": x + 1,
+			})
+		}
+		list = append(list, map[string]interface{}{
+			"id":      watchlist.EId,
+			"name":    watchlist.WatchlistName,
+			"shared":  watchlist.ShareToken != "",
+			"share":   watchlist.SharePath(),
+			"tickers": tickers,
+		})
+	}
+	if watchlistEId != "" && len(list) == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown watchlist"
+		return
+	}
+	jsonR.Data["watchlists"] = list
+
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+func apiWatchlistsCreate(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, name string, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+
+	watchlist, err := createWatchlist(deps, sublog, watcher, name)
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to create watchlist")
+		jsonR.Success = false
+		jsonR.Message = "failure: " + err.Error()
+		return
+	}
+
+	jsonR.Data["id"] = watchlist.EId
+	jsonR.Data["name"] = watchlist.WatchlistName
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+// rename, share (value "1" shares it, anything else stops sharing) or delete
+func apiWatchlistsUpdate(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, watchlistEId, action, value string, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+	watchlist, err := getWatcherWatchlist(deps, sublog, watcher, watchlistEId)
+	if err != nil {
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown watchlist"
+		return
+	}
+
+	switch action {
+	case "rename":
+		name := cleanWatchlistName(value)
+		if name == "" {
+			jsonR.Success = false
+			jsonR.Message = "failure: watchlist needs a name"
+			return
+		}
+		watchlist.WatchlistName = name
+		err = watchlist.update(deps, sublog)
+	case "share":
+		err = watchlist.share(deps, sublog, value == "1")
+	case "delete":
+		err = watchlist.delete(deps, sublog)
+	}
+	if err != nil {
+		jsonR.Success = false
+		jsonR.Message = "failure: could not " + action + " watchlist"
+		return
+	}
+
+	jsonR.Data["id"] = watchlist.EId
+	jsonR.Data["name"] = watchlist.WatchlistName
+	jsonR.Data["share"] = watchlist.SharePath()
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
+
+// add or remove symbols (comma separated), move one to a position, or set
+// the note on one
+func apiWatchlistsTickers(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, watchlistEId, action, symbolStr, note string, position int, jsonR *jsonResponseData) {
+	if watcher.WatcherId == 0 {
+		jsonR.Success = false
+		jsonR.Message = "failure: not signed in"
+		return
+	}
+	watchlist, err := getWatcherWatchlist(deps, sublog, watcher, watchlistEId)
+	if err != nil {
+		jsonR.Success = false
+		jsonR.Message = "failure: unknown watchlist"
+		return
+	}
+	note = cleanWatchlistNote(note)
+
+	for _, symbol := range strings.Split(symbolStr, ",") {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" {
+			continue
+		}
+
+		// a symbol we haven't seen yet is looked up when it is added
+		var ticker Ticker
+		if action == "add" {
+			ticker, err = getFreshTicker(deps, sublog, symbol)
+		} else {
+			ticker, err = getTickerBySymbol(deps, sublog, symbol)
+		}
+		if err != nil {
+			sublog.Error().Str("symbol", symbol).Msg("failed to find ticker")
+			jsonR.Success = false
+			jsonR.Message = "failure: unknown symbol " + symbol
+			return
+		}
+
+		switch action {
+		case "add":
+			err = watchlist.addTicker(deps, sublog, ticker, note)
+		case "remove":
+			err = watchlist.removeTicker(deps, sublog, ticker)
+		case "move":
+			err = watchlist.moveTicker(deps, sublog, ticker, position)
+		case "note":
+			err = watchlist.setNote(deps, sublog, ticker, note)
+		}
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", symbol).Str("action", action).Msg("failed to change watchlist")
+			jsonR.Success = false
+			jsonR.Message = "failure: " + err.Error()
+			return
+		}
+	}
+
+	jsonR.Success = true
+	jsonR.Message = "ok"
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"crypto/sha256"
 	"database/sql"
+	"errors"
 	"fmt"
 	"html/template"
 	"regexp"
@@ -10,7 +11,6 @@ import (
 
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 )
 
 type Article struct {
@@ -95,9 +95,57 @@ type WebArticle struct {
 
 // misc -----------------------------------------------------------------------
 
-func getArticlesByTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, max int, goBack time.Duration) ([]WebArticle, error) {
-	db := deps.db
+// save a news item as an article, linked to the tickers it mentions. Articles
+// are known by their publisher and the provider's id for them, so seeing the
+// same one again is a no-op; the bool says whether this one was new
+func saveNewsItem(deps *Dependencies, sublog zerolog.Logger, item NewsItem) (bool, error) {
+	sourceId, err := getOrCreateSource(deps, item.Publisher)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = deps.articles.GetArticleId(sourceId, item.ExternalId)
+	if err == nil {
+		return false, nil
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return false, err
+	}
+
+	article := Article{
+		SourceId:          sourceId,
+		ExternalId:        item.ExternalId,
+		PublishedDatetime: sql.NullTime{Valid: !item.Published.IsZero(), Time: item.Published},
+		Title:             item.Title,
+		Body:              item.Summary,
+		ArticleURL:        item.URL,
+		ImageURL:          item.ImageURL,
+	}
+	article.ArticleId, err = deps.articles.CreateArticle(article)
+	if err != nil {
+		return false, err
+	}
+
+	for _, symbol := range item.Symbols {
+		// we don't track every ticker a story mentions, those keep a 0 id
+		ticker, _ := getTickerBySymbol(deps, sublog, symbol)
+		err = deps.articles.CreateArticleTicker(ArticleTicker{ArticleId: article.ArticleId, TickerSymbol: symbol, TickerId: ticker.TickerId})
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", symbol).Uint64("article_id", article.ArticleId).Msg("failed to link article to ticker")
+		}
+	}
+
+	return true, nil
+}
 
+func getOrCreateSource(deps *Dependencies, sourceName string) (uint64, error) {
+	sourceId, err := deps.articles.GetSourceId(sourceName)
+	if errors.Is(err, sql.ErrNoRows) {
+		return deps.articles.CreateSource(Source{SourceCompany: sourceName, SourceName: sourceName})
+	}
+	return sourceId, err
+}
+
+func getArticlesByTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, max int, goBack time.Duration) ([]WebArticle, error) {
 	if max < 1 || max > 20 {
 		max = 20
 	}
@@ -108,40 +156,14 @@ func getArticlesByTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticke
 	}
 
 	fromDate := time.Now().Add(goBack).Format(sqlDatetimeSearchType)
-	query := `SELECT article.article_id, article.source_id, article.external_id, article.published_datetime, article.pubupdated_datetime,
-	            article.title, article.body, article.article_url, article.image_url,
-                ANY_VALUE(article_author.byline) AS author_byline,
-				ANY_VALUE(article_author.long_bio) AS author_long_bio,
-				ANY_VALUE(article_author.image_url) AS author_image_url,
-				source.source_name AS source_name,
-				GROUP_CONCAT(DISTINCT article_keyword.keyword ORDER BY article_keyword.keyword SEPARATOR ', ') AS keywords,
-				GROUP_CONCAT(DISTINCT article_tag.tag ORDER BY article_tag.tag SEPARATOR ', ') AS tags,
-				GROUP_CONCAT(DISTINCT article_ticker.ticker_symbol ORDER BY article_ticker.ticker_symbol SEPARATOR ', ') AS symbols
-			  FROM article
- 			  LEFT JOIN article_author USING (article_id)
-			  LEFT JOIN article_ticker USING (article_id)
-			  LEFT JOIN article_keyword USING (article_id)
-			  LEFT JOIN article_tag USING (article_id)
-			  LEFT JOIN source USING (source_id)
-			  WHERE published_datetime > ? AND ticker_id=?
-  			  GROUP BY article_id
-			  ORDER BY published_datetime DESC
-    		  LIMIT ?`
-	rows, err := db.Queryx(query, fromDate, ticker.TickerId, max)
+	found, err := deps.articles.GetTickerArticles(sublog, ticker.TickerId, fromDate, max)
 	if err != nil {
 		return []WebArticle{}, err
 	}
 
-	defer rows.Close()
 	bodySHA256 := make(map[string]bool)
-	var article WebArticle
 	articles := make([]WebArticle, 0)
-	for rows.Next() {
-		err = rows.StructScan(&article)
-		if err != nil {
-			log.Warn().Err(err).Msg("error reading row")
-			continue
-		}
+	for _, article := range found {
 		article.EId = encryptId(deps, sublog, "article", article.ArticleId)
 		sha := fmt.Sprintf("%x", sha256.Sum256([]byte(article.Title)))
 		// skip this one if we've seen the same title already
@@ -155,9 +177,6 @@ func getArticlesByTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticke
 		}
 		articles = append(articles, article)
 	}
-	if err := rows.Err(); err != nil {
-		return []WebArticle{}, err
-	}
 
 	return articles, nil
 }
@@ -180,41 +199,19 @@ func cleanArticleText(text string) string {
 }
 
 func getRecentArticles(deps *Dependencies, sublog zerolog.Logger) []WebArticle {
-	db := deps.db
-
-	// go back as far as 30 days but limited to 30 articles
+	// go back as far as 30 days but limited to 50 articles
 	fromDate := time.Now().Add(-1 * 30 * 24 * time.Hour).Format(sqlDatetimeSearchType)
 
-	query := `SELECT article.article_id, article.source_id, article.external_id, article.published_datetime, article.pubupdated_datetime,
-		        article.title, article.body, article.article_url, article.image_url,
-              	article_author.byline AS author_byline,
-				article_author.long_bio AS author_long_bio,
-			    article_author.image_url AS author_image_url,
-				source.source_name AS source_name
-			  FROM article
-			  LEFT JOIN article_author USING (article_id)
-			  LEFT JOIN source USING (source_id)
-			  WHERE published_datetime > ?
-			  ORDER BY published_datetime DESC
-			  LIMIT 50`
-	rows, err := db.Queryx(query, fromDate)
-
+	found, err := deps.articles.GetRecentArticles(sublog, fromDate, 50)
 	if err != nil {
 		sublog.Warn().Err(err).Msg("failed to check for articles")
 		return []WebArticle{}
 	}
-	defer rows.Close()
 
 	bodySHA256 := make(map[string]bool)
 
-	var article WebArticle
 	articles := make([]WebArticle, 0)
-	for rows.Next() {
-		err = rows.StructScan(&article)
-		if err != nil {
-			sublog.Warn().Err(err).Msg("error reading row")
-			continue
-		}
+	for _, article := range found {
 		article.EId = encryptId(deps, sublog, "article", article.ArticleId)
 		sha := fmt.Sprintf("%x", sha256.Sum256([]byte(article.Title)))
 		// skip this one if we've seen the same title already
@@ -231,10 +228,6 @@ func getRecentArticles(deps *Dependencies, sublog zerolog.Logger) []WebArticle {
 
 		articles = append(articles, article)
 	}
-	if err := rows.Err(); err != nil {
-		sublog.Warn().Err(err).Msg("error reading rows")
-		return []WebArticle{}
-	}
 
 	return articles
 }
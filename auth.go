@@ -14,14 +14,16 @@ import (
 	"time"
 
 	"github.com/dgryski/go-skip32"
+	"github.com/gorilla/sessions"
 	"github.com/markbates/goth"
 	"github.com/markbates/goth/gothic"
 	"github.com/rs/zerolog"
 )
 
 func checkAuthState(w http.ResponseWriter, r *http.Request, deps *Dependencies, sublog zerolog.Logger) Watcher {
-	webdata := deps.webdata
-	session := deps.session
+	rc := getRequestContext(r)
+	webdata := rc.webdata
+	session := rc.session
 
 	if session.Values["encWatcherId"] != nil {
 		encWatcherId := session.Values["encWatcherId"].(string)
@@ -30,12 +32,12 @@ func checkAuthState(w http.ResponseWriter, r *http.Request, deps *Dependencies,
 			watcher, err := getWatcherById(deps, watcherId)
 			if err != nil {
 				sublog.Error().Err(err).Str("encWatcherId", encWatcherId).Msg("failed to load watcher via encWatcherId {encWatcherId}")
-				signoutWatcher(deps)
+				signoutWatcher(deps, session)
 				return Watcher{}
 			}
 			if watcher.WatcherStatus != "active" {
 				sublog.Error().Err(err).Str("encWatcherId", encWatcherId).Str("status", watcher.WatcherStatus).Msg("watcher is not active: {status}")
-				signoutWatcher(deps)
+				signoutWatcher(deps, session)
 				return Watcher{}
 			}
 
@@ -65,7 +67,8 @@ func checkAuthState(w http.ResponseWriter, r *http.Request, deps *Dependencies,
 
 func authLoginHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sublog := deps.logger.With().Str("handler", "authLoginHandler").Logger()
+		rc := getRequestContext(r)
+		sublog := rc.logger.With().Str("handler", "authLoginHandler").Logger()
 
 		if user, err := gothic.CompleteUserAuth(w, r); err == nil {
 			signinUser(deps, sublog, w, r, user)
@@ -78,12 +81,13 @@ func authLoginHandler(deps *Dependencies) http.HandlerFunc {
 
 func authCallbackHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sublog := deps.logger.With().Str("handler", "authCallbackHandler").Logger()
+		rc := getRequestContext(r)
+		sublog := rc.logger.With().Str("handler", "authCallbackHandler").Logger()
 
 		user, err := gothic.CompleteUserAuth(w, r)
 		if err != nil {
 			sublog.Error().Err(err).Msg("failed to complete auth")
-			deps.messages = append(deps.messages, Message{Text: fmt.Sprintf("Sorry, failed to complete oauth - %s", err), Level: "error"})
+			rc.messages = append(rc.messages, Message{Text: fmt.Sprintf("Sorry, failed to complete oauth - %s", err), Level: "error"})
 			renderTemplate(w, r, deps, sublog, "home")
 			return
 		}
@@ -92,7 +96,7 @@ func authCallbackHandler(deps *Dependencies) http.HandlerFunc {
 }
 
 func signinUser(deps *Dependencies, sublog zerolog.Logger, w http.ResponseWriter, r *http.Request, gothUser goth.User) {
-	session := deps.session
+	session := getRequestContext(r).session
 
 	// get (or create) watcher account based on oauth properties
 	// specifically, based on the oauth_sub value, because email addresses can change
@@ -106,7 +110,7 @@ func signinUser(deps *Dependencies, sublog zerolog.Logger, w http.ResponseWriter
 		WatcherLevel:    "standard",
 		WatcherTimezone: "",
 		WatcherPicURL:   gothUser.AvatarURL,
-		SessionId:       deps.session.ID,
+		SessionId:       session.ID,
 		CreateDatetime:  time.Now(),
 		UpdateDatetime:  time.Now(),
 	}
@@ -154,18 +158,15 @@ func signinUser(deps *Dependencies, sublog zerolog.Logger, w http.ResponseWriter
 
 func signoutHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		signoutWatcher(deps)
+		signoutWatcher(deps, getRequestContext(r).session)
 		gothic.Logout(w, r)
 		http.Redirect(w, r, "/", http.StatusFound)
 	})
 }
 
-func signoutWatcher(deps *Dependencies) {
-	session := deps.session
-	db := deps.db
-
+func signoutWatcher(deps *Dependencies, session *sessions.Session) {
 	session.Values["encWatcherId"] = ""
-	db.Exec("UPDATE watcher SET session_id='' WHERE session_id=?", session.ID)
+	deps.watchers.ClearWatcherSession(session.ID)
 }
 
 const (
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+// private files (favicons, CSP reports) kept by key, in S3 in production or
+// under a local directory otherwise
+type BlobStore interface {
+	Get(key string) ([]byte, error)
+	Put(key string, contents []byte) error
+}
+
+type s3BlobStore struct {
+	awssess *session.Session
+	bucket  string
+}
+
+type localBlobStore struct {
+	dir string
+}
+
+// object methods -------------------------------------------------------------
+
+func (bs s3BlobStore) Get(key string) ([]byte, error) {
+	s3svc := s3.New(bs.awssess)
+
+	resp, err := s3svc.GetObject(&s3.GetObjectInput{
+		Bucket: aws.String(bs.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+func (bs s3BlobStore) Put(key string, contents []byte) error {
+	s3svc := s3.New(bs.awssess)
+
+	_, err := s3svc.PutObject(&s3.PutObjectInput{
+		Body:   bytes.NewReader(contents),
+		Bucket: aws.String(bs.bucket),
+		Key:    aws.String(key),
+	})
+	return err
+}
+
+// keys are cleaned as if rooted, so one can't climb out of the directory
+func (bs localBlobStore) filename(key string) string {
+	return filepath.Join(bs.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
+
+func (bs localBlobStore) Get(key string) ([]byte, error) {
+	return os.ReadFile(bs.filename(key))
+}
+
+func (bs localBlobStore) Put(key string, contents []byte) error {
+	filename := bs.filename(key)
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filename, contents, 0644)
+}
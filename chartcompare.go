@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+	"github.com/rs/zerolog"
+)
+
+// CompareSeries is one line on a comparison chart: a ticker or an index, with
+// the location its dailies are dated in
+type CompareSeries struct {
+	Name     string
+	Location *time.Location
+	Dailies  []TickerDaily
+}
+
+// the ticker against its benchmark index
+func chartHandlerBenchmarkCompare(deps *Dependencies, sublog zerolog.Logger, nonce string, ticker Ticker, exchange *Exchange, dailies []TickerDaily, mi MarketIndex, indexDailies []TickerDaily, chartRange ChartRange) template.HTML {
+	series := []CompareSeries{
+		{ticker.TickerSymbol, getMarketCalendar(*exchange).location(), dailies},
+		{mi.MarketIndexName, getMarketCalendar(mi.exchange()).location(), indexDailies},
+	}
+	title := fmt.Sprintf("%s/%s vs %s", ticker.TickerSymbol, strings.ToLower(exchange.ExchangeAcronym), mi.MarketIndexName)
+	return chartHandlerCompare(deps, sublog, nonce, title, series, chartRange)
+}
+
+// every series as % change from its first day shown so the scales line up;
+// days are matched by calendar date, so a day only some of them traded
+// leaves a gap in the others
+func chartHandlerCompare(deps *Dependencies, sublog zerolog.Logger, nonce string, title string, series []CompareSeries, chartRange ChartRange) template.HTML {
+
+	mainX := "700px"
+	mainY := "420px"
+
+	// build data needed
+	closes := make([]map[string]float64, len(series))
+	dateSet := map[string]bool{}
+	for i, s := range series {
+		closes[i] = make(map[string]float64, len(s.Dailies))
+		for _, daily := range s.Dailies {
+			if daily.ClosePrice <= 0 {
+				continue
+			}
+			day := daily.PriceDatetime.In(s.Location).Format(sqlDateParseType)
+			closes[i][day] = daily.ClosePrice
+			dateSet[day] = true
+		}
+	}
+	dates := make([]string, 0, len(dateSet))
+	for day := range dateSet {
+		dates = append(dates, day)
+	}
+	sort.Strings(dates)
+
+	days := len(dates)
+	if days == 0 {
+		html, _ := renderTemplateToString(deps, sublog, "_emptychart", nil)
+		return html
+	}
+
+	x_axis := make([]string, 0, days)
+	for _, day := range dates {
+		date, _ := time.Parse(sqlDateParseType, day)
+		x_axis = append(x_axis, date.Format(chartRange.labelFormat()))
+	}
+
+	legendStrs := make([]string, 0, len(series))
+	selected := make(map[string]bool, len(series))
+	lineData := make([][]opts.LineData, len(series))
+	for i, s := range series {
+		legendStrs = append(legendStrs, s.Name)
+		selected[s.Name] = true
+		lineData[i] = make([]opts.LineData, 0, days)
+		var base float64
+		for _, day := range dates {
+			closePrice, ok := closes[i][day]
+			if ok && base == 0 {
+				base = closePrice
+			}
+			if ok {
+				lineData[i] = append(lineData[i], opts.LineData{Value: math.Round((closePrice/base-1)*10000) / 100, Symbol: "none"})
+			} else {
+				lineData[i] = append(lineData[i], opts.LineData{Value: "-"})
+			}
+		}
+	}
+
+	// construct line chart
+	compare := charts.NewLine()
+	compare.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:      mainX,
+			Height:     mainY,
+			Theme:      types.ThemeVintage,
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:    true,
+			Trigger: "axis",
+		}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    title,
+			Subtitle: "% Change",
+			Target:   nonce,
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show:     true,
+			Data:     legendStrs,
+			Orient:   "horizontal",
+			Selected: selected,
+			Left:     "center",
+			Top:      "top",
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Type: "category",
+			Data: x_axis,
+			AxisLabel: &opts.AxisLabel{
+				Rotate: 60,
+			},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Scale: true,
+		}),
+		charts.WithDataZoomOpts(chartDataZoom(days)...),
+	)
+
+	// Put data into instance
+	compare.SetXAxis(x_axis)
+	for i, s := range series {
+		compare.
+			AddSeries(s.Name, lineData[i],
+				charts.WithLineChartOpts(opts.LineChart{Smooth: true}))
+	}
+
+	compare.Renderer = newSnippetRenderer(compare, compare.Validate)
+
+	return renderToHtml(deps, compare)
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// a dividend on the chart is a small "D" on the close of the bar its
+// ex-dividend date falls in; ones off the chart are left off
+func dividendMarkOpts(dividends []TickerDividend, dailies []TickerDaily, x_axis []string, location *time.Location) []charts.SeriesOpts {
+	if len(dividends) == 0 || len(dailies) == 0 {
+		return []charts.SeriesOpts{}
+	}
+
+	days := make([]string, len(dailies))
+	for x := range dailies {
+		days[x] = dailies[x].PriceDatetime.In(location).Format(sqlDateParseType)
+	}
+
+	points := make([]opts.MarkPointNameCoordItem, 0, len(dividends))
+	for _, dividend := range dividends {
+		bar := chartBar(days, dividend.date())
+		if bar < 0 {
+			continue
+		}
+		points = append(points, opts.MarkPointNameCoordItem{
+			Name:       fmt.Sprintf("Dividend $%.4g, ex %s", dividend.Amount, dividend.DividendDate.Format("Jan 02 2006")),
+			Coordinate: []interface{}{x_axis[bar], dailies[bar].ClosePrice},
+			Symbol:     "circle",
+			SymbolSize: 14,
+			ItemStyle:  &opts.ItemStyle{Color: "darkgreen"},
+			Label:      &opts.Label{Show: true, Formatter: "D", Position: "inside", Color: "white"},
+		})
+	}
+	if len(points) == 0 {
+		return []charts.SeriesOpts{}
+	}
+
+	return []charts.SeriesOpts{
+		charts.WithMarkPointNameCoordItemOpts(points...),
+	}
+}
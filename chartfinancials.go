@@ -11,8 +11,7 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func chartHandlerFinancialsBar(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, exchange *Exchange, periodStrs []string, barValues []map[string]float64) template.HTML {
-	nonce := deps.nonce
+func chartHandlerFinancialsBar(deps *Dependencies, sublog zerolog.Logger, nonce string, ticker Ticker, exchange *Exchange, periodStrs []string, barValues []map[string]float64) template.HTML {
 	mainX := "700px"
 	mainY := "400px"
 
@@ -34,7 +33,7 @@ func chartHandlerFinancialsBar(deps *Dependencies, sublog zerolog.Logger, ticker
 			Width:      mainX,
 			Height:     mainY,
 			Theme:      types.ThemeVintage,
-			AssetsHost: "https://stockwatch.graystorm.com/static/vendor/echarts/dist/",
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
 		}),
 		charts.WithTitleOpts(opts.Title{
 			Title:  fmt.Sprintf("%s/%s - %s", ticker.TickerSymbol, strings.ToLower(exchange.ExchangeAcronym), ticker.TickerName),
@@ -76,8 +75,7 @@ func chartHandlerFinancialsBar(deps *Dependencies, sublog zerolog.Logger, ticker
 	return renderToHtml(deps, barChart)
 }
 
-func chartHandlerFinancialsLine(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, exchange *Exchange, periodStrs []string, lineValues []map[string]float64, isPercentage int) template.HTML {
-	nonce := deps.nonce
+func chartHandlerFinancialsLine(deps *Dependencies, sublog zerolog.Logger, nonce string, ticker Ticker, exchange *Exchange, periodStrs []string, lineValues []map[string]float64, isPercentage int) template.HTML {
 	mainX := "700px"
 	mainY := "400px"
 
@@ -102,7 +100,7 @@ func chartHandlerFinancialsLine(deps *Dependencies, sublog zerolog.Logger, ticke
 			Width:      mainX,
 			Height:     mainY,
 			Theme:      types.ThemeVintage,
-			AssetsHost: "https://stockwatch.graystorm.com/static/vendor/echarts/dist/",
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
 		}),
 		charts.WithTitleOpts(opts.Title{
 			Title:  fmt.Sprintf("%s/%s - %s", ticker.TickerSymbol, strings.ToLower(exchange.ExchangeAcronym), ticker.TickerName),
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"html/template"
+	"math"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+)
+
+// the price-pane indicators as one line chart to Overlap onto the prices,
+// and their names for the legend
+func indicatorOverlays(x_axis []string, dailies []TickerDaily, indicators []Indicator) (*charts.Line, []string) {
+	overlay := charts.NewLine()
+	overlay.SetXAxis(x_axis)
+	names := make([]string, 0, len(indicators))
+	for _, indicator := range indicators {
+		if !indicator.isOverlay() {
+			continue
+		}
+		for _, series := range indicator.series(dailies) {
+			names = append(names, series.Name)
+			overlay.AddSeries(series.Name, indicatorLineData(series.Values),
+				charts.WithLineChartOpts(opts.LineChart{Smooth: true}),
+				charts.WithLineStyleOpts(opts.LineStyle{Width: 1}))
+		}
+	}
+	return overlay, names
+}
+
+// a small pane under the volume for each oscillator, in the order asked for
+func indicatorPanes(deps *Dependencies, nonce string, x_axis []string, dailies []TickerDaily, indicators []Indicator) template.HTML {
+	var html template.HTML
+	for _, indicator := range indicators {
+		if indicator.isOverlay() {
+			continue
+		}
+
+		pane := charts.NewLine()
+		pane.SetGlobalOptions(
+			charts.WithInitializationOpts(opts.Initialization{
+				Width:      "700px",
+				Height:     "160px",
+				Theme:      types.ThemeVintage,
+				AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
+			}),
+			charts.WithTooltipOpts(opts.Tooltip{
+				Show:    true,
+				Trigger: "axis",
+			}),
+			charts.WithTitleOpts(opts.Title{
+				Subtitle: indicator.Name(),
+				Target:   nonce,
+			}),
+			charts.WithXAxisOpts(opts.XAxis{
+				Show: false,
+			}),
+			charts.WithYAxisOpts(opts.YAxis{
+				Scale: true,
+			}),
+		)
+		pane.SetXAxis(x_axis)
+
+		for _, series := range indicator.series(dailies) {
+			if series.Bar {
+				bars := charts.NewBar()
+				bars.SetXAxis(x_axis).AddSeries(series.Name, indicatorBarData(series.Values))
+				pane.Overlap(bars)
+				continue
+			}
+			pane.AddSeries(series.Name, indicatorLineData(series.Values),
+				charts.WithLineChartOpts(opts.LineChart{Smooth: true}),
+				charts.WithLineStyleOpts(opts.LineStyle{Width: 1}))
+		}
+
+		pane.Renderer = newSnippetRenderer(pane, pane.Validate)
+		html += renderToHtml(deps, pane)
+	}
+	return html
+}
+
+// utils ----------------------------------------------------------------------
+
+func indicatorLineData(values []float64) []opts.LineData {
+	lineData := make([]opts.LineData, 0, len(values))
+	for _, value := range values {
+		if math.IsNaN(value) {
+			lineData = append(lineData, opts.LineData{Value: "-"})
+		} else {
+			lineData = append(lineData, opts.LineData{Value: math.Round(value*100) / 100, Symbol: "none"})
+		}
+	}
+	return lineData
+}
+
+func indicatorBarData(values []float64) []opts.BarData {
+	barData := make([]opts.BarData, 0, len(values))
+	for _, value := range values {
+		if math.IsNaN(value) {
+			barData = append(barData, opts.BarData{Value: "-"})
+		} else {
+			barData = append(barData, opts.BarData{Value: math.Round(value*100) / 100})
+		}
+	}
+	return barData
+}
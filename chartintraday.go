@@ -0,0 +1,164 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+	"github.com/rs/zerolog"
+)
+
+// one session of intraday samples, with the prior close and the session's
+// VWAP drawn over the price
+func chartHandlerTickerIntraday(deps *Dependencies, sublog zerolog.Logger, nonce string, ticker Ticker, exchange *Exchange, intradays []TickerIntraday) template.HTML {
+
+	mainX := "700px"
+	mainY := "280px"
+	smallX := "700px"
+	smallY := "200px"
+	legendStrs := []string{ticker.TickerSymbol, "VWAP", "Prior Close"}
+
+	// build data needed
+	moments := len(intradays)
+	if moments == 0 {
+		html, _ := renderTemplateToString(deps, sublog, "_emptychart", nil)
+		return html
+	}
+
+	// label each sample by the exchange's own clock
+	location := getMarketCalendar(*exchange).location()
+	sessionDate := ""
+
+	x_axis := make([]string, 0, moments)
+	lineData := make([]opts.LineData, 0, moments)
+	vwapData := make([]opts.LineData, 0, moments)
+	closeData := make([]opts.LineData, 0, moments)
+	volumeData := make([]opts.BarData, 0, moments)
+
+	// samples carry the day's running volume, so what traded between two of
+	// them is the difference; the first sample only sets the baseline
+	var priceVolume, totalVolume float64
+	lastVolume := intradays[0].Volume
+	for x := range intradays {
+		at, err := time.ParseInLocation(sqlDatetimeSearchType, intradays[x].PriceDate, time.UTC)
+		if err != nil {
+			sublog.Warn().Err(err).Str("price_date", intradays[x].PriceDate).Msg("skipping intraday sample with a bad time")
+			continue
+		}
+		at = at.In(location)
+		sessionDate = at.Format("Jan 02")
+
+		var traded int64
+		if intradays[x].Volume > lastVolume {
+			traded = intradays[x].Volume - lastVolume
+		}
+		lastVolume = intradays[x].Volume
+		priceVolume += intradays[x].LastPrice * float64(traded)
+		totalVolume += float64(traded)
+
+		x_axis = append(x_axis, at.Format("15:04"))
+		lineData = append(lineData, opts.LineData{Value: intradays[x].LastPrice})
+		if totalVolume > 0 {
+			vwapData = append(vwapData, opts.LineData{Value: math.Round(priceVolume/totalVolume*100) / 100, Symbol: "none"})
+		} else {
+			vwapData = append(vwapData, opts.LineData{Value: "-"})
+		}
+		closeData = append(closeData, opts.LineData{Value: ticker.MarketPrevClose, Symbol: "none"})
+		volumeData = append(volumeData, opts.BarData{Value: float64(traded) / intradayVolumeUnits})
+	}
+
+	// construct line chart
+	prices := charts.NewLine()
+	prices.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:      mainX,
+			Height:     mainY,
+			Theme:      types.ThemeVintage,
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:    true,
+			Trigger: "axis",
+		}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    fmt.Sprintf("%s/%s - %s", ticker.TickerSymbol, strings.ToLower(exchange.ExchangeAcronym), ticker.TickerName),
+			Subtitle: "Intraday Share Price, " + sessionDate,
+			Target:   nonce,
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show:     true,
+			Data:     legendStrs,
+			Orient:   "horizontal",
+			Selected: map[string]bool{ticker.TickerSymbol: true, "VWAP": true, "Prior Close": true},
+			Left:     "center",
+			Top:      "top",
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Type: "category",
+			Data: x_axis,
+			AxisLabel: &opts.AxisLabel{
+				Show:  false,
+				Color: "white",
+			},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Scale: true,
+		}),
+	)
+
+	volume := charts.NewBar()
+	volume.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:      smallX,
+			Height:     smallY,
+			Theme:      types.ThemeVintage,
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:    true,
+			Trigger: "axis",
+		}),
+		charts.WithTitleOpts(opts.Title{
+			Subtitle: "Volume in thousands",
+			Target:   nonce,
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			AxisLabel: &opts.AxisLabel{
+				Rotate: 60,
+			},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Show: false,
+			AxisLabel: &opts.AxisLabel{
+				Show: false,
+			},
+		}),
+	)
+
+	// Put data into instance
+	prices.SetXAxis(x_axis).
+		AddSeries(ticker.TickerSymbol, lineData,
+			charts.WithLineChartOpts(opts.LineChart{Smooth: true}))
+	prices.
+		AddSeries("VWAP", vwapData,
+			charts.WithLineChartOpts(opts.LineChart{Smooth: true}),
+			charts.WithLineStyleOpts(opts.LineStyle{Width: 1}))
+	if ticker.MarketPrevClose > 0 {
+		prices.
+			AddSeries("Prior Close", closeData,
+				charts.WithLineStyleOpts(opts.LineStyle{Width: 1, Type: "dashed"}))
+	}
+
+	volume.SetXAxis(x_axis).
+		AddSeries("volume", volumeData, charts.WithLabelOpts(opts.Label{Show: false}))
+
+	prices.Renderer = newSnippetRenderer(prices, prices.Validate)
+	volume.Renderer = newSnippetRenderer(volume, volume.Validate)
+
+	return renderToHtml(deps, prices) + renderToHtml(deps, volume)
+}
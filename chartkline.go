@@ -11,8 +11,7 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, exchange *Exchange, dailies []TickerDaily, webwatches []WebWatch) template.HTML {
-	nonce := deps.nonce
+func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, nonce string, ticker Ticker, exchange *Exchange, dailies []TickerDaily, webwatches []WebWatch, dividends []TickerDividend, indicators []Indicator, chartRange ChartRange) template.HTML {
 
 	mainX := "700px"
 	mainY := "280px"
@@ -26,14 +25,19 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 		return html
 	}
 
+	// label each bar by the exchange's own calendar date, with the year once
+	// the range is long enough to need it
+	location := getMarketCalendar(*exchange).location()
+	labelFormat := chartRange.labelFormat()
 	x_axis := make([]string, 0, days)
 	candleData := make([]opts.KlineData, 0, days)
 	volumeData := make([]opts.BarData, 0, days)
 	for x := range dailies {
-		x_axis = append(x_axis, dailies[x].PriceDatetime.Format("Jan 02"))
+		x_axis = append(x_axis, dailies[x].PriceDatetime.In(location).Format(labelFormat))
 		candleData = append(candleData, opts.KlineData{Value: [4]float64{dailies[x].OpenPrice, dailies[x].ClosePrice, dailies[x].LowPrice, dailies[x].HighPrice}})
 		volumeData = append(volumeData, opts.BarData{Value: dailies[x].Volume / volumeUnits})
 	}
+	overlay, overlayNames := indicatorOverlays(x_axis, dailies, indicators)
 
 	// build charts
 	prices := charts.NewKLine()
@@ -42,7 +46,7 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 			Width:      mainX,
 			Height:     mainY,
 			Theme:      types.ThemeVintage,
-			AssetsHost: "https://stockwatch.graystorm.com/static/vendor/echarts/dist/",
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
 		}),
 		charts.WithTooltipOpts(opts.Tooltip{
 			Show:    true,
@@ -53,6 +57,12 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 			Subtitle: "Share Price",
 			Target:   nonce,
 		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: true,
+			Data: overlayNames,
+			Left: "center",
+			Top:  "top",
+		}),
 		charts.WithXAxisOpts(opts.XAxis{
 			Show: false,
 			AxisLabel: &opts.AxisLabel{
@@ -66,6 +76,7 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 			},
 			Scale: true,
 		}),
+		charts.WithDataZoomOpts(chartDataZoom(days)...),
 	)
 
 	volume := charts.NewBar()
@@ -74,7 +85,7 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 			Width:      smallX,
 			Height:     smallY,
 			Theme:      types.ThemeVintage,
-			AssetsHost: "https://stockwatch.graystorm.com/static/vendor/echarts/dist/",
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
 		}),
 		charts.WithTooltipOpts(opts.Tooltip{
 			Show:    true,
@@ -108,6 +119,9 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 				BorderColor0: "red",
 			}),
 		)
+	prices.SetSeriesOptions(watchMarkOpts(webwatches, dailies, x_axis, location)...)
+	prices.SetSeriesOptions(dividendMarkOpts(dividends, dailies, x_axis, location)...)
+	prices.Overlap(overlay)
 	volume.SetXAxis(x_axis).
 		AddSeries("volume", volumeData,
 			charts.WithLabelOpts(opts.Label{
@@ -117,5 +131,5 @@ func chartHandlerTickerDailyKLine(deps *Dependencies, sublog zerolog.Logger, tic
 	prices.Renderer = newSnippetRenderer(prices, prices.Validate)
 	volume.Renderer = newSnippetRenderer(volume, volume.Validate)
 
-	return renderToHtml(deps, prices) + renderToHtml(deps, volume)
+	return renderToHtml(deps, prices) + renderToHtml(deps, volume) + indicatorPanes(deps, nonce, x_axis, dailies, indicators)
 }
@@ -3,8 +3,6 @@ package main
 import (
 	"fmt"
 	"html/template"
-	"math"
-	"reflect"
 	"strings"
 
 	"github.com/go-echarts/go-echarts/v2/charts"
@@ -13,15 +11,12 @@ import (
 	"github.com/rs/zerolog"
 )
 
-func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, exchange *Exchange, dailies []TickerDaily, webwatches []WebWatch) template.HTML {
-	nonce := deps.nonce
+func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, nonce string, ticker Ticker, exchange *Exchange, dailies []TickerDaily, webwatches []WebWatch, dividends []TickerDividend, indicators []Indicator, chartRange ChartRange) template.HTML {
 
 	mainX := "700px"
 	mainY := "280px"
 	smallX := "700px"
 	smallY := "200px"
-	legendStrs := []string{"Closing Price", "20-Day MA", "50-Day MA", "200-Day MA"}
-
 	// build data needed
 	days := len(dailies)
 	if days == 0 {
@@ -29,15 +24,30 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 		return html
 	}
 
+	// label each bar by the exchange's own calendar date, with the year once
+	// the range is long enough to need it
+	location := getMarketCalendar(*exchange).location()
+	labelFormat := chartRange.labelFormat()
 	x_axis := make([]string, 0, days)
 	lineData := make([]opts.LineData, 0, days)
 	volumeData := make([]opts.BarData, 0, days)
 	for x := range dailies {
-		x_axis = append(x_axis, dailies[x].PriceDatetime.Format("Jan 02"))
+		x_axis = append(x_axis, dailies[x].PriceDatetime.In(location).Format(labelFormat))
 		lineData = append(lineData, opts.LineData{Value: dailies[x].ClosePrice})
 		volumeData = append(volumeData, opts.BarData{Value: dailies[x].Volume / volumeUnits})
 	}
 
+	// a request without indicators gets the usual moving averages
+	if indicators == nil {
+		indicators = defaultLineIndicators
+	}
+	overlay, overlayNames := indicatorOverlays(x_axis, dailies, indicators)
+	legendStrs := append([]string{ticker.TickerSymbol}, overlayNames...)
+	selected := make(map[string]bool, len(legendStrs))
+	for _, name := range legendStrs {
+		selected[name] = true
+	}
+
 	// construct line chart
 	prices := charts.NewLine()
 	prices.SetGlobalOptions(
@@ -45,7 +55,7 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 			Width:      mainX,
 			Height:     mainY,
 			Theme:      types.ThemeVintage,
-			AssetsHost: "https://stockwatch.graystorm.com/static/vendor/echarts/dist/",
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
 		}),
 		charts.WithTooltipOpts(opts.Tooltip{
 			Show:    true,
@@ -60,7 +70,7 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 			Show:     true,
 			Data:     legendStrs,
 			Orient:   "horizontal",
-			Selected: map[string]bool{ticker.TickerSymbol: true, "MA20": true, "MA50": true, "MA200": true},
+			Selected: selected,
 			Left:     "center",
 			Top:      "top",
 		}),
@@ -75,6 +85,7 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 		charts.WithYAxisOpts(opts.YAxis{
 			Scale: true,
 		}),
+		charts.WithDataZoomOpts(chartDataZoom(days)...),
 	)
 
 	volume := charts.NewBar()
@@ -83,7 +94,7 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 			Width:      smallX,
 			Height:     smallY,
 			Theme:      types.ThemeVintage,
-			AssetsHost: "https://stockwatch.graystorm.com/static/vendor/echarts/dist/",
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
 		}),
 		charts.WithTooltipOpts(opts.Tooltip{
 			Show:    true,
@@ -110,18 +121,9 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 	prices.SetXAxis(x_axis).
 		AddSeries(ticker.TickerSymbol, lineData,
 			charts.WithLineChartOpts(opts.LineChart{Smooth: true}))
-	prices.
-		AddSeries("MA20", calcMovingLineAvg(20, lineData),
-			charts.WithLineChartOpts(opts.LineChart{Smooth: true}),
-			charts.WithLineStyleOpts(opts.LineStyle{Width: 1}))
-	prices.
-		AddSeries("MA50", calcMovingLineAvg(50, lineData),
-			charts.WithLineChartOpts(opts.LineChart{Smooth: true}),
-			charts.WithLineStyleOpts(opts.LineStyle{Width: 1}))
-	prices.
-		AddSeries("MA200", calcMovingLineAvg(200, lineData),
-			charts.WithLineChartOpts(opts.LineChart{Smooth: true}),
-			charts.WithLineStyleOpts(opts.LineStyle{Width: 1}))
+	prices.SetSeriesOptions(watchMarkOpts(webwatches, dailies, x_axis, location)...)
+	prices.SetSeriesOptions(dividendMarkOpts(dividends, dailies, x_axis, location)...)
+	prices.Overlap(overlay)
 
 	volume.SetXAxis(x_axis).
 		AddSeries("volume", volumeData, charts.WithLabelOpts(opts.Label{Show: false}))
@@ -129,27 +131,5 @@ func chartHandlerTickerDailyLine(deps *Dependencies, sublog zerolog.Logger, tick
 	prices.Renderer = newSnippetRenderer(prices, prices.Validate)
 	volume.Renderer = newSnippetRenderer(volume, volume.Validate)
 
-	return renderToHtml(deps, prices) + renderToHtml(deps, volume)
-}
-
-// utils ----------------------------------------------------------------------
-
-func calcMovingLineAvg(days float64, prices []opts.LineData) []opts.LineData {
-	movingAvg := make([]opts.LineData, 0, 100)
-	for i := range prices {
-		if i < int(days) {
-			movingAvg = append(movingAvg, opts.LineData{Value: "-"})
-		} else {
-			var sum float64 = 0
-			for j := 0; j < int(days); j++ {
-				val := reflect.ValueOf(prices[i-j])
-				sum += val.FieldByName("Value").Interface().(float64)
-			}
-			movingAvg = append(movingAvg, opts.LineData{
-				Value:  math.Round((sum/days)*100) / 100,
-				Symbol: "none",
-			})
-		}
-	}
-	return movingAvg
+	return renderToHtml(deps, prices) + renderToHtml(deps, volume) + indicatorPanes(deps, nonce, x_axis, dailies, indicators)
 }
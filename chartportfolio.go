@@ -0,0 +1,132 @@
+package main
+
+import (
+	"html/template"
+	"math"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/go-echarts/go-echarts/v2/types"
+	"github.com/rs/zerolog"
+)
+
+// what the portfolio was worth each day, against the money put into it
+func chartHandlerPortfolioValue(deps *Dependencies, sublog zerolog.Logger, nonce string, days []PortfolioDay, chartRange ChartRange) template.HTML {
+
+	mainX := "700px"
+	mainY := "320px"
+
+	// build data needed
+	if len(days) == 0 {
+		html, _ := renderTemplateToString(deps, sublog, "_emptychart", nil)
+		return html
+	}
+
+	labelFormat := chartRange.labelFormat()
+	x_axis := make([]string, 0, len(days))
+	valueData := make([]opts.LineData, 0, len(days))
+	investedData := make([]opts.LineData, 0, len(days))
+	for _, day := range days {
+		x_axis = append(x_axis, parsePortfolioDate(day.Date).Format(labelFormat))
+		valueData = append(valueData, opts.LineData{Value: math.Round(day.Value*100) / 100, Symbol: "none"})
+		investedData = append(investedData, opts.LineData{Value: math.Round(day.Invested*100) / 100, Symbol: "none"})
+	}
+	legendStrs := []string{"Market Value", "Net Invested"}
+
+	// construct line chart
+	value := charts.NewLine()
+	value.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:      mainX,
+			Height:     mainY,
+			Theme:      types.ThemeVintage,
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:    true,
+			Trigger: "axis",
+		}),
+		charts.WithTitleOpts(opts.Title{
+			Title:    "Portfolio Value",
+			Subtitle: "$ at each close",
+			Target:   nonce,
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show:   true,
+			Data:   legendStrs,
+			Orient: "horizontal",
+			Left:   "center",
+			Top:    "top",
+		}),
+		charts.WithXAxisOpts(opts.XAxis{
+			Type: "category",
+			Data: x_axis,
+			AxisLabel: &opts.AxisLabel{
+				Rotate: 60,
+			},
+		}),
+		charts.WithYAxisOpts(opts.YAxis{
+			Scale: true,
+		}),
+		charts.WithDataZoomOpts(chartDataZoom(len(days))...),
+	)
+
+	// Put data into instance
+	value.SetXAxis(x_axis).
+		AddSeries(legendStrs[0], valueData,
+			charts.WithLineChartOpts(opts.LineChart{Smooth: true})).
+		AddSeries(legendStrs[1], investedData)
+
+	value.Renderer = newSnippetRenderer(value, value.Validate)
+
+	return renderToHtml(deps, value)
+}
+
+// a pie of the market value held, a slice per sector or industry
+func chartHandlerAllocation(deps *Dependencies, sublog zerolog.Logger, nonce string, title string, slices []AllocationSlice) template.HTML {
+
+	mainX := "345px"
+	mainY := "320px"
+
+	// build data needed
+	if len(slices) == 0 {
+		html, _ := renderTemplateToString(deps, sublog, "_emptychart", nil)
+		return html
+	}
+
+	pieData := make([]opts.PieData, 0, len(slices))
+	for _, slice := range slices {
+		pieData = append(pieData, opts.PieData{Name: slice.Name, Value: math.Round(slice.Value*100) / 100})
+	}
+
+	// construct pie chart
+	allocation := charts.NewPie()
+	allocation.SetGlobalOptions(
+		charts.WithInitializationOpts(opts.Initialization{
+			Width:      mainX,
+			Height:     mainY,
+			Theme:      types.ThemeVintage,
+			AssetsHost: deps.config.siteURL("/static/vendor/echarts/dist/"),
+		}),
+		charts.WithTooltipOpts(opts.Tooltip{
+			Show:    true,
+			Trigger: "item",
+		}),
+		charts.WithTitleOpts(opts.Title{
+			Title:  title,
+			Target: nonce,
+		}),
+		charts.WithLegendOpts(opts.Legend{
+			Show: false,
+		}),
+	)
+
+	// Put data into instance
+	allocation.AddSeries(title, pieData,
+		charts.WithPieChartOpts(opts.PieChart{Radius: []string{"35%", "65%"}, Center: []string{"50%", "55%"}}),
+		charts.WithLabelOpts(opts.Label{Show: true, Formatter: "{b}: {d}%"}))
+
+	allocation.Renderer = newSnippetRenderer(allocation, allocation.Validate)
+
+	return renderToHtml(deps, allocation)
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/opts"
+	"github.com/weirdtangent/mytime"
+)
+
+// ChartRange is the stretch of history a chart shows: the last Days days,
+// or From/To dates when they are given, as daily, weekly or monthly bars
+type ChartRange struct {
+	Days        int
+	From        string
+	To          string
+	Interval    string // "" picks one from the length of the range
+	TotalReturn bool   // a ticker's prices with its dividends reinvested
+}
+
+const (
+	intervalDaily   = "1d"
+	intervalWeekly  = "1w"
+	intervalMonthly = "1mo"
+)
+
+// object methods -------------------------------------------------------------
+
+func (cr ChartRange) fromDate() string {
+	if cr.From != "" {
+		return cr.From
+	}
+	return mytime.DateStr(cr.Days * -1)
+}
+
+// how many days the range covers, for picking an interval
+func (cr ChartRange) span() int {
+	if cr.From == "" {
+		return cr.Days
+	}
+	from, _ := time.Parse(sqlDateParseType, cr.From)
+	to := time.Now()
+	if cr.To != "" {
+		to, _ = time.Parse(sqlDateParseType, cr.To)
+	}
+	return int(to.Sub(from).Hours() / 24)
+}
+
+// daily bars up to about a year, weekly up to five, monthly past that, so long
+// histories stay readable and the payload stays small
+func (cr ChartRange) interval() string {
+	if cr.Interval != "" {
+		return cr.Interval
+	}
+	switch span := cr.span(); {
+	case span <= maxDailyBarDays:
+		return intervalDaily
+	case span <= maxWeeklyBarDays:
+		return intervalWeekly
+	}
+	return intervalMonthly
+}
+
+// the day after To, as the query's upper bound; empty when there is no To
+func (cr ChartRange) beforeDate() string {
+	if cr.To == "" {
+		return ""
+	}
+	to, _ := time.Parse(sqlDateParseType, cr.To)
+	return to.AddDate(0, 0, 1).Format(sqlDateParseType)
+}
+
+// the query bounds a ticker's EODs by their UTC date; this trims by the date
+// on the exchange's own calendar
+func (cr ChartRange) clip(dailies []TickerDaily, location *time.Location) []TickerDaily {
+	if cr.To == "" {
+		return dailies
+	}
+	clipped := make([]TickerDaily, 0, len(dailies))
+	for _, daily := range dailies {
+		if daily.PriceDatetime.In(location).Format(sqlDateParseType) <= cr.To {
+			clipped = append(clipped, daily)
+		}
+	}
+	return clipped
+}
+
+// daily bars on short ranges are labeled as before; anything longer needs
+// the year
+func (cr ChartRange) labelFormat() string {
+	switch {
+	case cr.interval() == intervalMonthly:
+		return "Jan 2006"
+	case cr.span() > maxDailyBarDays:
+		return "Jan 02 '06"
+	}
+	return "Jan 02"
+}
+
+// misc -----------------------------------------------------------------------
+
+// timespan is a number of days, from and to are dates; from/to win when
+// given, and an empty interval is picked from the length of the range
+func parseChartRange(timespan, from, to, interval string) (ChartRange, error) {
+	chartRange := ChartRange{From: from, To: to, Interval: interval}
+
+	switch interval {
+	case "", intervalDaily, intervalWeekly, intervalMonthly:
+	default:
+		return chartRange, fmt.Errorf("unknown interval %q", interval)
+	}
+	for _, date := range []string{from, to} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(sqlDateParseType, date); err != nil {
+			return chartRange, fmt.Errorf("invalid date %q", date)
+		}
+	}
+	if from != "" && to != "" && from > to {
+		return chartRange, fmt.Errorf("from date is after to date")
+	}
+
+	if from == "" {
+		days, err := strconv.Atoi(timespan)
+		if err != nil || days <= 0 {
+			return chartRange, fmt.Errorf("invalid timespan %q", timespan)
+		}
+		chartRange.Days = days
+	}
+	return chartRange, nil
+}
+
+// weekly or monthly OHLCV bars, each dated by its first trading day
+func resampleDailies(dailies []TickerDaily, interval string, location *time.Location) []TickerDaily {
+	if interval != intervalWeekly && interval != intervalMonthly {
+		return dailies
+	}
+
+	period := func(t time.Time) string {
+		t = t.In(location)
+		if interval == intervalWeekly {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}
+		return t.Format("2006-01")
+	}
+
+	bars := make([]TickerDaily, 0, len(dailies)/4+1)
+	lastPeriod := ""
+	for _, daily := range dailies {
+		thisPeriod := period(daily.PriceDatetime)
+		if thisPeriod != lastPeriod {
+			lastPeriod = thisPeriod
+			bar := daily
+			bar.TickerDailyId = 0
+			bars = append(bars, bar)
+			continue
+		}
+		bar := &bars[len(bars)-1]
+		if daily.HighPrice > bar.HighPrice {
+			bar.HighPrice = daily.HighPrice
+		}
+		if daily.LowPrice < bar.LowPrice {
+			bar.LowPrice = daily.LowPrice
+		}
+		bar.ClosePrice = daily.ClosePrice
+		bar.Volume += daily.Volume
+	}
+	return bars
+}
+
+// drag or wheel to zoom inside the chart, plus a slider under it once there
+// are enough bars to be worth zooming
+func chartDataZoom(bars int) []opts.DataZoom {
+	zoom := []opts.DataZoom{{Type: "inside", Start: 0, End: 100}}
+	if bars > minZoomSliderBars {
+		zoom = append(zoom, opts.DataZoom{Type: "slider", Start: 0, End: 100})
+	}
+	return zoom
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-echarts/go-echarts/v2/charts"
+	"github.com/go-echarts/go-echarts/v2/opts"
+)
+
+// a watch with a target date on the chart is a pin at the bar that date
+// falls in and its price; one without, or dated off the chart, is a line
+// across at its price
+func watchMarkOpts(webwatches []WebWatch, dailies []TickerDaily, x_axis []string, location *time.Location) []charts.SeriesOpts {
+	if len(webwatches) == 0 || len(dailies) == 0 {
+		return []charts.SeriesOpts{}
+	}
+
+	days := make([]string, len(dailies))
+	for x := range dailies {
+		days[x] = dailies[x].PriceDatetime.In(location).Format(sqlDateParseType)
+	}
+
+	points := make([]opts.MarkPointNameCoordItem, 0, len(webwatches))
+	lines := make([]opts.MarkLineNameYAxisItem, 0, len(webwatches))
+	for _, webwatch := range webwatches {
+		if webwatch.TargetPrice <= 0 {
+			continue
+		}
+		name := webwatch.label()
+		if webwatch.TargetDate.Valid && webwatch.TargetDate.String != "" {
+			targetDay := datePart(webwatch.TargetDate.String)
+			if bar := chartBar(days, targetDay); bar >= 0 {
+				points = append(points, opts.MarkPointNameCoordItem{
+					Name:       fmt.Sprintf("%s $%.2f", name, webwatch.TargetPrice),
+					Coordinate: []interface{}{x_axis[bar], webwatch.TargetPrice},
+				})
+				continue
+			}
+			name = fmt.Sprintf("%s, by %s", name, targetDay)
+		}
+		lines = append(lines, opts.MarkLineNameYAxisItem{Name: name, YAxis: webwatch.TargetPrice})
+	}
+
+	return []charts.SeriesOpts{
+		charts.WithMarkPointNameCoordItemOpts(points...),
+		charts.WithMarkPointStyleOpts(opts.MarkPointStyle{
+			Symbol:     []string{"pin"},
+			SymbolSize: 30,
+			Label:      &opts.Label{Show: true, Formatter: "{b}", Position: "top"},
+		}),
+		charts.WithMarkLineNameYAxisItemOpts(lines...),
+		charts.WithMarkLineStyleOpts(opts.MarkLineStyle{
+			Symbol: []string{"none", "none"},
+			Label:  &opts.Label{Show: true, Formatter: "{b}: ${c}", Position: "insideEndTop"},
+		}),
+	}
+}
+
+// the bar a date falls in, given the date each bar starts on; -1 when it is
+// before the first bar or after the last one starts
+func chartBar(days []string, day string) int {
+	bar := sort.SearchStrings(days, day)
+	if bar == len(days) || days[bar] != day {
+		bar-- // the bar before is the one the date falls in
+	}
+	if bar < 0 || day > days[len(days)-1] {
+		return -1
+	}
+	return bar
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// CompareStats is one row of the table under the comparison chart; all of
+// them are percents over the timespan shown
+type CompareStats struct {
+	Name        string
+	TotalReturn float32
+	Volatility  float32 // annualized, from daily returns
+	MaxDrawdown float32 // largest fall from a prior high
+}
+
+func compareHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+
+		symbols := parseCompareSymbols(r.FormValue("symbols"))
+		benchmark := r.FormValue("benchmark")
+		timespan, err := strconv.Atoi(r.FormValue("timespan"))
+		if err != nil || timespan <= 0 {
+			timespan = 180
+		}
+		totalReturn := r.FormValue("totalreturn") == "1"
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("symbols", strings.Join(symbols, ",")).Logger()
+
+		// a symbol we haven't seen yet gets its ticker and EODs loaded, but
+		// only the EODs: the chart has no use for a live quote or the news
+		found := make([]string, 0, len(symbols))
+		for _, symbol := range symbols {
+			ticker, err := getFreshTicker(deps, sublog, symbol)
+			if err != nil {
+				sublog.Warn().Err(err).Str("symbol", symbol).Msg("getFreshTicker failed, leaving symbol out of comparison")
+				rc.messages = append(rc.messages, Message{fmt.Sprintf("Sorry, ticker symbol %s could not be found", symbol), "error"})
+				continue
+			}
+			if ticker.needEODs(deps, sublog) {
+				err = fetchTickerEODs(deps, sublog, ticker)
+				if err != nil {
+					sublog.Warn().Err(err).Str("symbol", symbol).Msg("failed to fetch tickerEODs, comparing what we have")
+				}
+			}
+			found = append(found, symbol)
+		}
+
+		// the stats assume daily returns, whatever the length of the range
+		series := loadCompareSeries(deps, sublog, found, benchmark, ChartRange{Days: timespan, Interval: intervalDaily, TotalReturn: totalReturn})
+		stats := make([]CompareStats, 0, len(series))
+		for _, s := range series {
+			stats = append(stats, calcCompareStats(s.Name, s.Dailies))
+		}
+
+		webdata["CompareSymbols"] = strings.Join(found, ",")
+		webdata["CompareBenchmark"] = strings.TrimPrefix(strings.ToUpper(benchmark), "^")
+		webdata["CompareStats"] = stats
+		webdata["Indexes"] = benchmarkIndexes
+		webdata["timespan"] = timespan
+		webdata["TotalReturn"] = totalReturn
+
+		renderTemplate(w, r, deps, sublog, "compare")
+	})
+}
+
+// misc -----------------------------------------------------------------------
+
+// upper-cased, without blanks or repeats, and no more than the chart can show
+func parseCompareSymbols(symbolStr string) []string {
+	symbols := make([]string, 0, maxCompareSymbols)
+	seen := map[string]bool{}
+	for _, symbol := range strings.Split(symbolStr, ",") {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol == "" || seen[symbol] {
+			continue
+		}
+		seen[symbol] = true
+		symbols = append(symbols, symbol)
+		if len(symbols) == maxCompareSymbols {
+			break
+		}
+	}
+	return symbols
+}
+
+// the split-adjusted EODs of each known symbol, then the benchmark index if
+// one was asked for; unknown symbols are left out. Indexes are price-only,
+// there is no total return for them
+func loadCompareSeries(deps *Dependencies, sublog zerolog.Logger, symbols []string, benchmark string, chartRange ChartRange) []CompareSeries {
+	series := make([]CompareSeries, 0, len(symbols)+1)
+	for _, symbol := range symbols {
+		ticker, err := getTickerBySymbol(deps, sublog, symbol)
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", symbol).Msg("failed to find ticker for comparison")
+			continue
+		}
+		exchange, err := getExchangeById(deps, sublog, ticker.ExchangeId)
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", symbol).Msg("failed to find exchange for comparison")
+			continue
+		}
+		location := getMarketCalendar(exchange).location()
+		dailies, err := ticker.getChartEODs(deps, sublog, chartRange, location)
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", symbol).Msg("failed to load EODs for comparison")
+		}
+		series = append(series, CompareSeries{ticker.TickerSymbol, location, dailies})
+	}
+
+	if benchmark != "" {
+		mi, err := getMarketIndex(deps, sublog, benchmark)
+		if err != nil {
+			sublog.Warn().Err(err).Str("benchmark", benchmark).Msg("failed to find benchmark index")
+			return series
+		}
+		if mi.needEODs(deps, sublog) {
+			fetchMarketIndexEODs(deps, sublog, mi)
+		}
+		dailies, err := mi.getChartEODs(deps, sublog, chartRange)
+		if err != nil {
+			sublog.Warn().Err(err).Str("benchmark", benchmark).Msg("failed to load benchmark EODs")
+		}
+		series = append(series, CompareSeries{mi.MarketIndexName, getMarketCalendar(mi.exchange()).location(), dailies})
+	}
+	return series
+}
+
+func calcCompareStats(name string, dailies []TickerDaily) CompareStats {
+	stats := CompareStats{Name: name}
+
+	closes := make([]float64, 0, len(dailies))
+	for _, daily := range dailies {
+		if daily.ClosePrice > 0 {
+			closes = append(closes, daily.ClosePrice)
+		}
+	}
+	if len(closes) < 2 {
+		return stats
+	}
+
+	stats.TotalReturn = float32((closes[len(closes)-1]/closes[0] - 1) * 100)
+
+	var sum, sumSquares, maxDrawdown float64
+	peak := closes[0]
+	for x := 1; x < len(closes); x++ {
+		change := closes[x]/closes[x-1] - 1
+		sum += change
+		sumSquares += change * change
+
+		if closes[x] > peak {
+			peak = closes[x]
+		}
+		if drawdown := (peak - closes[x]) / peak * 100; drawdown > maxDrawdown {
+			maxDrawdown = drawdown
+		}
+	}
+	stats.MaxDrawdown = float32(maxDrawdown)
+
+	changes := float64(len(closes) - 1)
+	mean := sum / changes
+	variance := sumSquares/changes - mean*mean
+	if variance > 0 {
+		stats.Volatility = float32(math.Sqrt(variance*tradingDaysPerYear) * 100)
+	}
+
+	return stats
+}
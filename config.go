@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// Config holds the settings that differ between production, staging and dev
+// instances. Defaults are production; a JSON file (STOCKWATCH_CONFIG, or
+// stockwatch.json if that exists) overrides them, and then any of the env
+// variables named in the `env` tags override that
+type Config struct {
+	HTTPPort     int    `json:"http_port" env:"STOCKWATCH_HTTP_PORT"`
+	SiteURL      string `json:"site_url" env:"STOCKWATCH_SITE_URL"` // OAuth callbacks, cookie domain, chart assets and CSP reports all hang off this
+	RedisAddress string `json:"redis_address" env:"STOCKWATCH_REDIS_ADDRESS"`
+	Debugging    bool   `json:"debugging" env:"STOCKWATCH_DEBUGGING"` // output DEBUG level logs
+
+	StorageBackend    string `json:"storage_backend" env:"STOCKWATCH_STORAGE_BACKEND"` // "aws" or "local", see setupStorage
+	LocalDataDir      string `json:"local_data_dir" env:"STOCKWATCH_LOCAL_DATA_DIR"`   // sqlite db, sessions, blobs and secrets for the local backend
+	AWSRegion         string `json:"aws_region" env:"STOCKWATCH_AWS_REGION"`
+	PrivateBucketName string `json:"private_bucket_name" env:"STOCKWATCH_PRIVATE_BUCKET_NAME"`
+	TickerQueueName   string `json:"ticker_queue_name" env:"STOCKWATCH_TICKER_QUEUE_NAME"`
+
+	TickerDeadLetterQueueName string `json:"ticker_dead_letter_queue_name" env:"STOCKWATCH_TICKER_DEAD_LETTER_QUEUE_NAME"` // where ticker tasks go once they run out of attempts
+	WorkerConcurrency         int    `json:"worker_concurrency" env:"STOCKWATCH_WORKER_CONCURRENCY"`                       // ticker tasks worked at once
+	WorkerMaxAttempts         int    `json:"worker_max_attempts" env:"STOCKWATCH_WORKER_MAX_ATTEMPTS"`
+
+	MarketDataProvider   string `json:"market_data_provider" env:"STOCKWATCH_MARKET_DATA_PROVIDER"` // see marketDataProviders
+	MarketDataFixtureDir string `json:"market_data_fixture_dir" env:"STOCKWATCH_MARKET_DATA_FIXTURE_DIR"`
+	MarketDataRecord     bool   `json:"market_data_record" env:"STOCKWATCH_MARKET_DATA_RECORD"` // save every yhfinance response as a fixture
+
+	// all in minutes
+	TickerReloadDelayOpen     int `json:"ticker_reload_delay_open" env:"STOCKWATCH_TICKER_RELOAD_DELAY_OPEN"`
+	TickerReloadDelayExtended int `json:"ticker_reload_delay_extended" env:"STOCKWATCH_TICKER_RELOAD_DELAY_EXTENDED"`
+	TickerReloadDelayClosed   int `json:"ticker_reload_delay_closed" env:"STOCKWATCH_TICKER_RELOAD_DELAY_CLOSED"`
+	TickerNewsDelay           int `json:"ticker_news_delay" env:"STOCKWATCH_TICKER_NEWS_DELAY"`
+	TickerFinancialsDelay     int `json:"ticker_financials_delay" env:"STOCKWATCH_TICKER_FINANCIALS_DELAY"`
+
+	AlertCheckInterval int `json:"alert_check_interval" env:"STOCKWATCH_ALERT_CHECK_INTERVAL"` // seconds between alert checks while the market is open
+	MaxRecentCount     int `json:"max_recent_count" env:"STOCKWATCH_MAX_RECENT_COUNT"`         // limit watcher_recents
+}
+
+// object methods -------------------------------------------------------------
+
+// every string, int and bool field with an env tag can be set from the
+// environment, an empty variable counts as unset
+func (c *Config) applyEnv() error {
+	value := reflect.ValueOf(c).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		envName := field.Tag.Get("env")
+		envValue := os.Getenv(envName)
+		if envName == "" || envValue == "" {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.String:
+			value.Field(i).SetString(envValue)
+		case reflect.Int:
+			n, err := strconv.Atoi(envValue)
+			if err != nil {
+				return fmt.Errorf("%s: %w", envName, err)
+			}
+			value.Field(i).SetInt(int64(n))
+		case reflect.Bool:
+			b, err := strconv.ParseBool(envValue)
+			if err != nil {
+				return fmt.Errorf("%s: %w", envName, err)
+			}
+			value.Field(i).SetBool(b)
+		}
+	}
+	return nil
+}
+
+func (c *Config) validate() error {
+	if c.HTTPPort < 1 || c.HTTPPort > 65535 {
+		return fmt.Errorf("http_port %d is out of range", c.HTTPPort)
+	}
+	siteURL, err := url.Parse(c.SiteURL)
+	if err != nil || (siteURL.Scheme != "http" && siteURL.Scheme != "https") || siteURL.Host == "" {
+		return fmt.Errorf("site_url %q must be an absolute http(s) URL", c.SiteURL)
+	}
+	if c.RedisAddress == "" {
+		return errors.New("redis_address is required")
+	}
+
+	switch c.StorageBackend {
+	case "aws":
+		if c.AWSRegion == "" || c.PrivateBucketName == "" {
+			return errors.New("aws_region and private_bucket_name are required for the aws backend")
+		}
+	case "local":
+		if c.LocalDataDir == "" {
+			return errors.New("local_data_dir is required for the local backend")
+		}
+	default:
+		return fmt.Errorf("storage_backend %q must be \"aws\" or \"local\"", c.StorageBackend)
+	}
+	if c.TickerQueueName == "" || c.TickerDeadLetterQueueName == "" {
+		return errors.New("ticker_queue_name and ticker_dead_letter_queue_name are required")
+	}
+	if c.TickerQueueName == c.TickerDeadLetterQueueName {
+		return errors.New("ticker_dead_letter_queue_name must differ from ticker_queue_name")
+	}
+
+	if _, ok := marketDataProviders[c.MarketDataProvider]; !ok {
+		return fmt.Errorf("market_data_provider %q is unknown", c.MarketDataProvider)
+	}
+	if (c.MarketDataProvider == "fixtures" || c.MarketDataRecord) && c.MarketDataFixtureDir == "" {
+		return errors.New("market_data_fixture_dir is required to read or record fixtures")
+	}
+
+	for name, n := range map[string]int{
+		"ticker_reload_delay_open":     c.TickerReloadDelayOpen,
+		"ticker_reload_delay_extended": c.TickerReloadDelayExtended,
+		"ticker_reload_delay_closed":   c.TickerReloadDelayClosed,
+		"ticker_news_delay":            c.TickerNewsDelay,
+		"ticker_financials_delay":      c.TickerFinancialsDelay,
+		"alert_check_interval":         c.AlertCheckInterval,
+		"max_recent_count":             c.MaxRecentCount,
+		"worker_concurrency":           c.WorkerConcurrency,
+		"worker_max_attempts":          c.WorkerMaxAttempts,
+	} {
+		if n < 1 {
+			return fmt.Errorf("%s must be at least 1", name)
+		}
+	}
+	return nil
+}
+
+// an absolute URL on this instance, for callbacks and anything linked from
+// outside the page
+func (c *Config) siteURL(path string) string {
+	return strings.TrimSuffix(c.SiteURL, "/") + path
+}
+
+func (c *Config) siteHost() string {
+	siteURL, _ := url.Parse(c.SiteURL)
+	return siteURL.Hostname()
+}
+
+func (c *Config) siteIsSecure() bool {
+	return strings.HasPrefix(c.SiteURL, "https:")
+}
+
+// misc -----------------------------------------------------------------------
+
+func defaultConfig() Config {
+	return Config{
+		HTTPPort:     3001,
+		SiteURL:      "https://stockwatch.graystorm.com",
+		RedisAddress: "localhost:6379",
+		Debugging:    true,
+
+		StorageBackend:    "aws",
+		LocalDataDir:      "data",
+		AWSRegion:         "us-east-1",
+		PrivateBucketName: "stockwatch-private",
+		TickerQueueName:   "stockwatch-tickers",
+
+		TickerDeadLetterQueueName: "stockwatch-tickers-dlq",
+		WorkerConcurrency:         4,
+		WorkerMaxAttempts:         3,
+
+		MarketDataProvider:   "yhfinance",
+		MarketDataFixtureDir: "fixtures",
+		MarketDataRecord:     false,
+
+		TickerReloadDelayOpen:     1,       //  1 minute
+		TickerReloadDelayExtended: 15,      // 15 minutes, pre-market and after-hours
+		TickerReloadDelayClosed:   60 * 1,  //  1 hour
+		TickerNewsDelay:           60 * 1,  //  1 hour
+		TickerFinancialsDelay:     60 * 24, // 24 hours
+
+		AlertCheckInterval: 60,
+		MaxRecentCount:     6,
+	}
+}
+
+func loadConfig() (*Config, error) {
+	config := defaultConfig()
+
+	configFile := os.Getenv("STOCKWATCH_CONFIG")
+	optional := configFile == ""
+	if optional {
+		configFile = "stockwatch.json"
+	}
+
+	contents, err := os.ReadFile(configFile)
+	if err != nil && !(optional && errors.Is(err, fs.ErrNotExist)) {
+		return nil, err
+	}
+	if err == nil {
+		decoder := json.NewDecoder(bytes.NewReader(contents))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&config); err != nil {
+			return nil, fmt.Errorf("%s: %w", configFile, err)
+		}
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func setupConfig(deps *Dependencies) {
+	sublog := deps.logger
+
+	config, err := loadConfig()
+	if err != nil {
+		sublog.Fatal().Err(err).Msg("invalid configuration")
+	}
+	deps.config = config
+
+	if config.Debugging {
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	}
+	sublog.Info().Str("site", config.SiteURL).Str("storage", config.StorageBackend).Msg("configuration loaded")
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	costBasisFIFO     = "fifo"
+	costBasisLIFO     = "lifo"
+	costBasisAverage  = "average"
+	costBasisSpecific = "specific"
+
+	shareEpsilon = 0.000001 // anything smaller than the modal's step is a rounding leftover
+)
+
+var costBasisMethods = map[string]string{
+	costBasisFIFO:     "First in, first out",
+	costBasisLIFO:     "Last in, first out",
+	costBasisAverage:  "Average cost",
+	costBasisSpecific: "Specific lot",
+}
+
+var errLotNotOpen = errors.New("lot is not open")
+
+// a Lot is a single purchase, with whatever shares of it are still held
+type Lot struct {
+	TransactionId uint64
+	AcquiredDate  time.Time
+	Shares        float64
+	CostPerShare  float64
+}
+
+type RealizedGain struct {
+	TransactionId uint64
+	AcquiredDate  time.Time
+	SoldDate      time.Time
+	Shares        float64
+	Proceeds      float64
+	CostBasis     float64
+	LongTerm      bool
+}
+
+type HoldingPL struct {
+	Method              string
+	OpenLots            []Lot
+	Realized            []RealizedGain
+	Shares              float64
+	CostBasis           float64
+	MarketValue         float64
+	RealizedShortTerm   float32
+	RealizedLongTerm    float32
+	UnrealizedShortTerm float32
+	UnrealizedLongTerm  float32
+	DividendIncome      float32 // every dividend paid on the shares, held now or since sold
+}
+
+// object methods -------------------------------------------------------------
+
+func (l Lot) CostBasis() float64 {
+	return l.Shares * l.CostPerShare
+}
+
+// held for more than one year as of the given date
+func (l Lot) IsLongTerm(asOf time.Time) bool {
+	return asOf.After(l.AcquiredDate.AddDate(1, 0, 0))
+}
+
+func (rg RealizedGain) Gain() float64 {
+	return rg.Proceeds - rg.CostBasis
+}
+
+func (pl HoldingPL) RealizedTotal() float32 {
+	return pl.RealizedShortTerm + pl.RealizedLongTerm
+}
+
+func (pl HoldingPL) UnrealizedTotal() float32 {
+	return pl.UnrealizedShortTerm + pl.UnrealizedLongTerm
+}
+
+func (pl HoldingPL) MethodName() string {
+	return costBasisMethods[pl.Method]
+}
+
+func (t Transaction) TransactionDate() time.Time {
+	dateStr := t.TransactionDateTime
+	if len(dateStr) > 10 {
+		dateStr = dateStr[:10]
+	}
+	date, _ := time.Parse(sqlDateParseType, dateStr)
+	return date
+}
+
+// misc -----------------------------------------------------------------------
+
+func isCostBasisMethod(method string) bool {
+	_, ok := costBasisMethods[method]
+	return ok
+}
+
+// replay a holding's transactions and the ticker's splits in date order and
+// match each sale against open lots using the given method, valuing what
+// remains at currentPrice
+func calcHoldingPL(transactions []Transaction, splits []TickerSplit, method string, currentPrice float64, asOf time.Time) (HoldingPL, error) {
+	if !isCostBasisMethod(method) {
+		method = costBasisFIFO
+	}
+	pl := HoldingPL{Method: method}
+
+	transactions = append([]Transaction{}, transactions...)
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].TransactionDate().Before(transactions[j].TransactionDate())
+	})
+
+	splits = append([]TickerSplit{}, splits...)
+	sort.SliceStable(splits, func(i, j int) bool {
+		return splits[i].SplitDate.Before(splits[j].SplitDate)
+	})
+	nextSplit := 0
+	applySplitsBefore := func(lots []Lot, date time.Time) {
+		for ; nextSplit < len(splits) && !splits[nextSplit].AppliesTo(date); nextSplit++ {
+			factor, err := splits[nextSplit].Factor()
+			if err == nil {
+				splitLots(lots, factor)
+			}
+		}
+	}
+
+	lots := make([]Lot, 0)
+	for _, transaction := range transactions {
+		applySplitsBefore(lots, transaction.TransactionDate())
+		switch transaction.TransactionType {
+		case "bought":
+			lots = append(lots, Lot{transaction.TransactionId, transaction.TransactionDate(), transaction.Shares, transaction.SharePrice})
+			if method == costBasisAverage {
+				averageLots(lots)
+			}
+		case "sold":
+			var realized []RealizedGain
+			var err error
+			lots, realized, err = sellFromLots(lots, transaction, method)
+			if err != nil {
+				return pl, err
+			}
+			pl.Realized = append(pl.Realized, realized...)
+		}
+	}
+
+	applySplitsBefore(lots, asOf.AddDate(0, 0, 1))
+
+	for _, realized := range pl.Realized {
+		if realized.LongTerm {
+			pl.RealizedLongTerm += float32(realized.Gain())
+		} else {
+			pl.RealizedShortTerm += float32(realized.Gain())
+		}
+	}
+
+	for _, lot := range lots {
+		pl.Shares += lot.Shares
+		pl.CostBasis += lot.CostBasis()
+		unrealized := lot.Shares*currentPrice - lot.CostBasis()
+		if lot.IsLongTerm(asOf) {
+			pl.UnrealizedLongTerm += float32(unrealized)
+		} else {
+			pl.UnrealizedShortTerm += float32(unrealized)
+		}
+	}
+	pl.MarketValue = pl.Shares * currentPrice
+	pl.OpenLots = lots
+
+	return pl, nil
+}
+
+// a sale that names a lot has to name one bought, and not yet sold off, by
+// the day of the sale
+func checkSaleLot(transactions []Transaction, splits []TickerSplit, method string, sale Transaction) error {
+	if sale.LotId == 0 {
+		return nil
+	}
+
+	soldDate := sale.TransactionDate()
+	before := make([]Transaction, 0, len(transactions))
+	for _, transaction := range transactions {
+		if !transaction.TransactionDate().After(soldDate) {
+			before = append(before, transaction)
+		}
+	}
+	pl, err := calcHoldingPL(before, splits, method, 0, soldDate)
+	if err != nil {
+		return err
+	}
+	for _, lot := range pl.OpenLots {
+		if lot.TransactionId == sale.LotId {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: lot %d on %s", errLotNotOpen, sale.LotId, soldDate.Format(sqlDateParseType))
+}
+
+// remove shares for a sale from the open lots, returning the lots left over
+// and the realized gain from each lot touched
+func sellFromLots(lots []Lot, sale Transaction, method string) ([]Lot, []RealizedGain, error) {
+	realized := make([]RealizedGain, 0)
+	remaining := sale.Shares
+	soldDate := sale.TransactionDate()
+
+	// naming a lot on the sale overrides FIFO/LIFO, but average cost has no
+	// lots to pick between
+	if sale.LotId != 0 && method != costBasisAverage {
+		method = costBasisSpecific
+	}
+
+	order := make([]int, 0, len(lots))
+	switch method {
+	case costBasisLIFO:
+		for x := len(lots) - 1; x >= 0; x-- {
+			order = append(order, x)
+		}
+	case costBasisSpecific:
+		// the chosen lot first, then fall back to FIFO for anything left
+		for x := range lots {
+			if lots[x].TransactionId == sale.LotId {
+				order = append(order, x)
+			}
+		}
+		for x := range lots {
+			if lots[x].TransactionId != sale.LotId {
+				order = append(order, x)
+			}
+		}
+	default:
+		for x := range lots {
+			order = append(order, x)
+		}
+	}
+
+	for _, x := range order {
+		if remaining <= shareEpsilon {
+			break
+		}
+		if lots[x].Shares <= shareEpsilon {
+			continue
+		}
+		shares := lots[x].Shares
+		if shares > remaining {
+			shares = remaining
+		}
+		realized = append(realized, RealizedGain{
+			TransactionId: lots[x].TransactionId,
+			AcquiredDate:  lots[x].AcquiredDate,
+			SoldDate:      soldDate,
+			Shares:        shares,
+			Proceeds:      shares * sale.SharePrice,
+			CostBasis:     shares * lots[x].CostPerShare,
+			LongTerm:      lots[x].IsLongTerm(soldDate),
+		})
+		lots[x].Shares -= shares
+		remaining -= shares
+	}
+	if remaining > shareEpsilon {
+		return lots, realized, fmt.Errorf("sale of %g shares on %s is %g more than were held", sale.Shares, soldDate.Format(sqlDateParseType), remaining)
+	}
+
+	openLots := make([]Lot, 0, len(lots))
+	for _, lot := range lots {
+		if lot.Shares > shareEpsilon {
+			openLots = append(openLots, lot)
+		}
+	}
+	return openLots, realized, nil
+}
+
+// pool the cost of every open lot so they all carry the same cost per share;
+// each lot keeps its own acquired date for the holding period
+func averageLots(lots []Lot) {
+	shares, cost := 0.0, 0.0
+	for _, lot := range lots {
+		shares += lot.Shares
+		cost += lot.CostBasis()
+	}
+	if shares == 0 {
+		return
+	}
+	for x := range lots {
+		lots[x].CostPerShare = cost / shares
+	}
+}
+
+func getHoldingPL(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, holding Holding, currentPrice float64) (HoldingPL, error) {
+	if holding.HoldingId == 0 {
+		return HoldingPL{Method: watcher.CostBasisMethod}, nil
+	}
+
+	transactions, err := getTransactionsByHolding(deps, sublog, holding.HoldingId)
+	if err != nil {
+		return HoldingPL{}, err
+	}
+
+	splits, err := holding.getSplits(deps, sublog)
+	if err != nil {
+		return HoldingPL{}, err
+	}
+
+	pl, err := calcHoldingPL(transactions, splits, watcher.CostBasisMethod, currentPrice, time.Now())
+	if err != nil {
+		return pl, err
+	}
+
+	dividends, err := holding.getDividends(deps, sublog)
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to get dividends, leaving out dividend income")
+		return pl, nil
+	}
+	for _, payment := range calcDividendPayments(transactions, splits, dividends) {
+		pl.DividendIncome += float32(payment.Total())
+	}
+	return pl, nil
+}
+
+// a change of method changes the cost basis of everything the watcher holds
+func updateWatcherCostBasisMethod(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, method string) error {
+	if !isCostBasisMethod(method) {
+		return fmt.Errorf("unknown cost basis method %q", method)
+	}
+
+	err := deps.watchers.UpdateWatcherCostBasisMethod(watcher.WatcherId, method)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on UPDATE")
+		return err
+	}
+
+	holdings, err := getWatcherHoldings(deps, sublog, watcher)
+	if err != nil {
+		return err
+	}
+	for _, holding := range holdings {
+		err = holding.recalculate(deps, sublog)
+		if err != nil {
+			sublog.Error().Err(err).Uint64("holding_id", holding.HoldingId).Msg("failed to recalculate holding")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func bought(id uint64, date string, shares, price float64) Transaction {
+	return Transaction{TransactionId: id, TransactionType: "bought", TransactionDateTime: date, Shares: shares, SharePrice: price}
+}
+
+func sold(id uint64, date string, shares, price float64, lotId uint64) Transaction {
+	return Transaction{TransactionId: id, TransactionType: "sold", TransactionDateTime: date, Shares: shares, SharePrice: price, LotId: lotId}
+}
+
+func splitOn(date, ratio string) TickerSplit {
+	return TickerSplit{SplitDate: parsePortfolioDate(date), SplitRatio: ratio}
+}
+
+func TestTickerSplitFactor(t *testing.T) {
+	tests := []struct {
+		ratio   string
+		want    float64
+		wantErr bool
+	}{
+		{"4:1", 4, false},
+		{"4/1", 4, false},
+		{"3:2", 1.5, false},
+		{" 3 : 2 ", 1.5, false},
+		{"1:10", 0.1, false},
+		{"4", 0, true},
+		{"4:1:1", 0, true},
+		{"a:1", 0, true},
+		{"4:b", 0, true},
+		{"4:0", 0, true},
+		{"0:1", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ratio, func(t *testing.T) {
+			got, err := TickerSplit{SplitRatio: tt.ratio}.Factor()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Factor(%q) error = %v, want error %t", tt.ratio, err, tt.wantErr)
+			}
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Factor(%q) = %g, want %g", tt.ratio, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcHoldingPL(t *testing.T) {
+	// 10 shares a year apart at 10, 20 and 30, then 15 sold at 40
+	ladder := []Transaction{
+		bought(1, "2022-01-03", 10, 10),
+		bought(2, "2023-01-03", 10, 20),
+		bought(3, "2024-01-02", 10, 30),
+	}
+	ladderSale := func(lotId uint64) []Transaction {
+		return append(append([]Transaction{}, ladder...), sold(4, "2024-03-01", 15, 40, lotId))
+	}
+	// everything is valued as of here
+	asOf := parsePortfolioDate("2024-06-01")
+
+	type lot struct {
+		id     uint64
+		shares float64
+	}
+	tests := []struct {
+		name         string
+		transactions []Transaction
+		splits       []TickerSplit
+		method       string
+		price        float64
+		wantErr      bool
+		wantMethod   string
+		shares       float64
+		costBasis    float64
+		realizedST   float32
+		realizedLT   float32
+		unrealizedST float32
+		unrealizedLT float32
+		openLots     []lot
+	}{
+		// all of lot 1 and half of lot 2, both held over a year
+		{name: "fifo", transactions: ladderSale(0), method: costBasisFIFO, price: 50,
+			shares: 15, costBasis: 400, realizedLT: 400, unrealizedST: 200, unrealizedLT: 150,
+			openLots: []lot{{2, 5}, {3, 10}}},
+		// all of lot 3, short-term, then half of lot 2
+		{name: "lifo", transactions: ladderSale(0), method: costBasisLIFO, price: 50,
+			shares: 15, costBasis: 200, realizedST: 100, realizedLT: 100, unrealizedLT: 550,
+			openLots: []lot{{1, 10}, {2, 5}}},
+		// every lot costs the pooled 20, sold in FIFO order for the holding period
+		{name: "average cost", transactions: ladderSale(0), method: costBasisAverage, price: 50,
+			shares: 15, costBasis: 300, realizedLT: 300, unrealizedST: 300, unrealizedLT: 150,
+			openLots: []lot{{2, 5}, {3, 10}}},
+		// all of lot 2, then FIFO for the other 5
+		{name: "specific lot", transactions: ladderSale(2), method: costBasisFIFO, price: 50,
+			shares: 15, costBasis: 350, realizedLT: 350, unrealizedST: 200, unrealizedLT: 200,
+			openLots: []lot{{1, 5}, {3, 10}}},
+		// naming a lot overrides LIFO, and FIFO covers what the lot doesn't
+		{name: "specific lot under lifo", transactions: ladderSale(1), method: costBasisLIFO, price: 50,
+			shares: 15, costBasis: 400, realizedLT: 400, unrealizedST: 200, unrealizedLT: 150,
+			openLots: []lot{{2, 5}, {3, 10}}},
+		{name: "a named lot means nothing to average cost", transactions: ladderSale(3), method: costBasisAverage, price: 50,
+			shares: 15, costBasis: 300, realizedLT: 300, unrealizedST: 300, unrealizedLT: 150,
+			openLots: []lot{{2, 5}, {3, 10}}},
+		{name: "unknown method is fifo", transactions: ladderSale(0), method: "", price: 50, wantMethod: costBasisFIFO,
+			shares: 15, costBasis: 400, realizedLT: 400, unrealizedST: 200, unrealizedLT: 150,
+			openLots: []lot{{2, 5}, {3, 10}}},
+		{name: "partial lot", transactions: []Transaction{
+			bought(1, "2024-01-02", 10, 10),
+			sold(2, "2024-02-01", 4, 15, 0),
+		}, method: costBasisFIFO, price: 12,
+			shares: 6, costBasis: 60, realizedST: 20, unrealizedST: 12,
+			openLots: []lot{{1, 6}}},
+		// long-term means more than a year: the anniversary itself is still short
+		{name: "sold on the anniversary", transactions: []Transaction{
+			bought(1, "2023-03-01", 10, 10),
+			sold(2, "2024-03-01", 10, 15, 0),
+		}, method: costBasisFIFO, realizedST: 50},
+		{name: "sold the day after the anniversary", transactions: []Transaction{
+			bought(1, "2023-03-01", 10, 10),
+			sold(2, "2024-03-02", 10, 15, 0),
+		}, method: costBasisFIFO, realizedLT: 50},
+		{name: "held past the anniversary", transactions: []Transaction{
+			bought(1, "2023-05-31", 10, 10),
+			bought(2, "2023-06-01", 10, 10),
+		}, method: costBasisFIFO, price: 11, shares: 20, costBasis: 200, unrealizedST: 10, unrealizedLT: 10,
+			openLots: []lot{{1, 10}, {2, 10}}},
+		// the 10 shares became 40 at 25 each before half were sold
+		{name: "sale spanning a split", transactions: []Transaction{
+			bought(1, "2024-01-02", 10, 100),
+			sold(2, "2024-03-01", 20, 30, 0),
+		}, splits: []TickerSplit{splitOn("2024-02-01", "4:1")}, method: costBasisFIFO, price: 30,
+			shares: 20, costBasis: 500, realizedST: 100, unrealizedST: 100,
+			openLots: []lot{{1, 20}}},
+		{name: "sale on the split date is in new shares", transactions: []Transaction{
+			bought(1, "2024-01-02", 10, 100),
+			sold(2, "2024-02-01", 40, 30, 0),
+		}, splits: []TickerSplit{splitOn("2024-02-01", "4:1")}, method: costBasisFIFO, realizedST: 200},
+		{name: "split after the last trade", transactions: []Transaction{
+			bought(1, "2024-01-02", 10, 100),
+		}, splits: []TickerSplit{splitOn("2024-02-01", "2:1")}, method: costBasisFIFO, price: 60,
+			shares: 20, costBasis: 1000, unrealizedST: 200,
+			openLots: []lot{{1, 20}}},
+		{name: "split after asOf is not applied", transactions: []Transaction{
+			bought(1, "2024-01-02", 10, 100),
+		}, splits: []TickerSplit{splitOn("2024-07-01", "2:1")}, method: costBasisFIFO, price: 100,
+			shares: 10, costBasis: 1000,
+			openLots: []lot{{1, 10}}},
+		{name: "reverse split", transactions: []Transaction{
+			bought(1, "2024-01-02", 100, 1),
+			sold(2, "2024-03-01", 5, 12, 0),
+		}, splits: []TickerSplit{splitOn("2024-02-01", "1:10")}, method: costBasisFIFO, price: 12,
+			shares: 5, costBasis: 50, realizedST: 10, unrealizedST: 10,
+			openLots: []lot{{1, 5}}},
+		{name: "sold more than held", transactions: []Transaction{
+			bought(1, "2024-01-02", 10, 10),
+			sold(2, "2024-02-01", 11, 15, 0),
+		}, method: costBasisFIFO, wantErr: true},
+		{name: "sold before it was bought", transactions: []Transaction{
+			sold(2, "2024-01-01", 10, 15, 0),
+			bought(1, "2024-01-02", 10, 10),
+		}, method: costBasisFIFO, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pl, err := calcHoldingPL(tt.transactions, tt.splits, tt.method, tt.price, asOf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("calcHoldingPL() error = %v, want error %t", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if tt.wantMethod != "" && pl.Method != tt.wantMethod {
+				t.Errorf("calcHoldingPL() method = %q, want %q", pl.Method, tt.wantMethod)
+			}
+			near := func(a, b float64) bool { return math.Abs(a-b) < 0.001 }
+			if !near(pl.Shares, tt.shares) || !near(pl.CostBasis, tt.costBasis) {
+				t.Errorf("calcHoldingPL() shares, cost basis = %g, %g, want %g, %g", pl.Shares, pl.CostBasis, tt.shares, tt.costBasis)
+			}
+			if !near(float64(pl.RealizedShortTerm), float64(tt.realizedST)) || !near(float64(pl.RealizedLongTerm), float64(tt.realizedLT)) {
+				t.Errorf("calcHoldingPL() realized = %g short, %g long, want %g, %g", pl.RealizedShortTerm, pl.RealizedLongTerm, tt.realizedST, tt.realizedLT)
+			}
+			if !near(float64(pl.UnrealizedShortTerm), float64(tt.unrealizedST)) || !near(float64(pl.UnrealizedLongTerm), float64(tt.unrealizedLT)) {
+				t.Errorf("calcHoldingPL() unrealized = %g short, %g long, want %g, %g", pl.UnrealizedShortTerm, pl.UnrealizedLongTerm, tt.unrealizedST, tt.unrealizedLT)
+			}
+			if len(pl.OpenLots) != len(tt.openLots) {
+				t.Fatalf("calcHoldingPL() open lots = %+v, want %+v", pl.OpenLots, tt.openLots)
+			}
+			for x, want := range tt.openLots {
+				if pl.OpenLots[x].TransactionId != want.id || !near(pl.OpenLots[x].Shares, want.shares) {
+					t.Errorf("calcHoldingPL() open lots = %+v, want %+v", pl.OpenLots, tt.openLots)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestCheckSaleLot(t *testing.T) {
+	transactions := []Transaction{
+		bought(1, "2024-01-02", 10, 10),
+		bought(2, "2024-02-01", 10, 20),
+		sold(3, "2024-03-01", 10, 30, 0),
+		bought(4, "2024-05-01", 10, 40),
+	}
+	tests := []struct {
+		name string
+		sale Transaction
+		err  error
+	}{
+		{"no lot named", sold(9, "2024-04-01", 5, 30, 0), nil},
+		{"open lot", sold(9, "2024-04-01", 5, 30, 2), nil},
+		{"lot already sold off", sold(9, "2024-04-01", 5, 30, 1), errLotNotOpen},
+		{"lot bought after the sale", sold(9, "2024-04-01", 5, 30, 4), errLotNotOpen},
+		{"lot bought on the sale date", sold(9, "2024-05-01", 5, 30, 4), nil},
+		{"no such lot", sold(9, "2024-04-01", 5, 30, 42), errLotNotOpen},
+		// a backdated sale sees the lot as it was before the later FIFO sale
+		{"open on a backdated sale", sold(9, "2024-02-15", 5, 30, 1), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSaleLot(transactions, nil, costBasisFIFO, tt.sale)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("checkSaleLot() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAverageLots(t *testing.T) {
+	lots := []Lot{
+		{TransactionId: 1, AcquiredDate: parsePortfolioDate("2024-01-02"), Shares: 10, CostPerShare: 10},
+		{TransactionId: 2, AcquiredDate: parsePortfolioDate("2024-02-01"), Shares: 30, CostPerShare: 30},
+	}
+	averageLots(lots)
+	for _, lot := range lots {
+		if math.Abs(lot.CostPerShare-25) > 1e-9 {
+			t.Errorf("averageLots() cost per share = %g, want 25", lot.CostPerShare)
+		}
+	}
+	if !lots[0].AcquiredDate.Equal(parsePortfolioDate("2024-01-02")) || lots[0].Shares != 10 {
+		t.Errorf("averageLots() changed a lot's date or shares: %+v", lots[0])
+	}
+
+	empty := []Lot{}
+	averageLots(empty)
+}
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 )
 
 func desktopHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		watcher := checkAuthState(w, r, deps, *deps.logger)
-		webdata := deps.webdata
+		rc := getRequestContext(r)
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+		webdata := rc.webdata
 
-		sublog := deps.logger.With().Str("watcher", watcher.EId).Logger()
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Logger()
+
+		webdata["IndexQuotes"] = getIndexQuotes(deps, sublog, getBenchmarkIndexes(deps, sublog))
+		webdata["AdvanceDecline"] = getAdvanceDecline(deps, sublog)
 
 		movers := getMovers(deps, sublog)
 		webdata["Movers"] = movers
@@ -21,20 +26,42 @@ func desktopHandler(deps *Dependencies) http.HandlerFunc {
 		webdata["LastCheckedSince"] = lastCheckedSince
 		webdata["UpdatingNewsNow"] = updatingNewsNow
 
-		recents := getWatcherRecents(deps, sublog, watcher)
-		tickerQuotes, err := getRecentsQuotes(deps, sublog, watcher, recents)
+		// the recents tab unless one of the watcher's watchlists is picked
+		watchlists, _ := getWatcherWatchlists(deps, sublog, watcher)
+		webdata["Watchlists"] = watchlists
+
+		var tickerQuotes []TickerQuote
+		var err error
+		if listEId := r.FormValue("list"); listEId != "" {
+			var watchlist Watchlist
+			watchlist, err = getWatcherWatchlist(deps, sublog, watcher, listEId)
+			if err == nil {
+				webdata["Watchlist"] = watchlist
+				tickerQuotes, err = getWatchlistQuotes(deps, sublog, watcher, watchlist)
+			} else if errors.Is(err, errWatchlistNotFound) {
+				rc.messages = append(rc.messages, Message{"Sorry, that watchlist could not be found", "error"})
+				err = nil
+			}
+		} else {
+			recents := getWatcherRecents(deps, sublog, watcher)
+			tickerQuotes, err = getRecentsQuotes(deps, sublog, watcher, recents)
+		}
 		if err != nil {
-			sublog.Error().Err(err).Msg("getRecentsQuotes failed, redirecting to /desktop")
-			deps.messages = append(deps.messages, Message{"Sorry, one or more ticker symbols could not be found", "error"})
+			sublog.Error().Err(err).Msg("getting quotes failed, redirecting to /desktop")
+			rc.messages = append(rc.messages, Message{"Sorry, one or more ticker symbols could not be found", "error"})
 			renderTemplate(w, r, deps, sublog, "desktop")
 			return
 		}
 		webdata["TickerQuotes"] = tickerQuotes
 
+		webdata["QuotesMarketOpen"], webdata["QuotesSession"] = listSession(tickerQuotes)
+
 		webdata["Announcement"] = []string{
 			"2022-04-22 Moving things around alot, especially on the desktop. Trying to find what I like, but email me if you have ideas!",
 		}
 
+		rc.messages = append(rc.messages, deliverWatcherAlerts(deps, sublog, watcher)...)
+
 		renderTemplate(w, r, deps, sublog, "desktop")
 	})
 }
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+const dividendEpsilon = 0.000001 // a restated amount that differs by less is the same amount
+
+// DividendPayment is what a holding was paid by one dividend
+type DividendPayment struct {
+	DividendDate time.Time
+	Shares       float64
+	Amount       float64 // per share
+}
+
+// object methods -------------------------------------------------------------
+
+func (dp DividendPayment) Total() float64 {
+	return dp.Shares * dp.Amount
+}
+
+func (td TickerDividend) date() string {
+	return td.DividendDate.Format(sqlDateParseType)
+}
+
+// misc -----------------------------------------------------------------------
+
+// the shares held going into date: every trade dated before it, restated for
+// each split that had taken effect by then
+func sharesHeldBefore(transactions []Transaction, splits []TickerSplit, date time.Time) float64 {
+	transactions = append([]Transaction{}, transactions...)
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].TransactionDate().Before(transactions[j].TransactionDate())
+	})
+	splits = append([]TickerSplit{}, splits...)
+	sort.SliceStable(splits, func(i, j int) bool { return splits[i].SplitDate.Before(splits[j].SplitDate) })
+
+	day := date.Format(sqlDateParseType)
+	shares := 0.0
+	nextSplit := 0
+	applySplitsBefore := func(date time.Time) {
+		for ; nextSplit < len(splits) && !splits[nextSplit].AppliesTo(date); nextSplit++ {
+			if factor, err := splits[nextSplit].Factor(); err == nil {
+				shares *= factor
+			}
+		}
+	}
+	for _, transaction := range transactions {
+		if transaction.TransactionDate().Format(sqlDateParseType) >= day {
+			break
+		}
+		applySplitsBefore(transaction.TransactionDate())
+		switch transaction.TransactionType {
+		case "bought":
+			shares += transaction.Shares
+		case "sold":
+			shares -= transaction.Shares
+		}
+	}
+	applySplitsBefore(date.AddDate(0, 0, 1))
+	if shares < shareEpsilon {
+		return 0
+	}
+	return shares
+}
+
+// each dividend paid on the shares held going into its ex-dividend date
+func calcDividendPayments(transactions []Transaction, splits []TickerSplit, dividends []TickerDividend) []DividendPayment {
+	payments := make([]DividendPayment, 0)
+	for _, dividend := range dividends {
+		shares := sharesHeldBefore(transactions, splits, dividend.DividendDate)
+		if shares == 0 {
+			continue
+		}
+		payments = append(payments, DividendPayment{dividend.DividendDate, shares, dividend.Amount})
+	}
+	return payments
+}
+
+// the closes as if every dividend were put straight back into the shares on
+// its ex-dividend date, so a chart or a return includes the income. Each bar
+// is scaled by the shares one share on the first day would have grown to;
+// dividends before the first bar don't count
+func reinvestDividends(dailies []TickerDaily, dividends []TickerDividend, location *time.Location) []TickerDaily {
+	if len(dailies) == 0 || len(dividends) == 0 {
+		return dailies
+	}
+
+	dividends = append([]TickerDividend{}, dividends...)
+	sort.SliceStable(dividends, func(i, j int) bool { return dividends[i].DividendDate.Before(dividends[j].DividendDate) })
+
+	first := dailies[0].PriceDatetime.In(location).Format(sqlDateParseType)
+	next := sort.Search(len(dividends), func(i int) bool { return dividends[i].date() > first })
+
+	reinvested := make([]TickerDaily, 0, len(dailies))
+	shares := 1.0
+	for _, daily := range dailies {
+		day := daily.PriceDatetime.In(location).Format(sqlDateParseType)
+		for ; next < len(dividends) && dividends[next].date() <= day; next++ {
+			if daily.ClosePrice > 0 {
+				shares *= 1 + dividends[next].Amount/daily.ClosePrice
+			}
+		}
+		daily.OpenPrice *= shares
+		daily.HighPrice *= shares
+		daily.LowPrice *= shares
+		daily.ClosePrice *= shares
+		reinvested = append(reinvested, daily)
+	}
+	return reinvested
+}
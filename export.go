@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// the rows of an export; CSV gets them as is under the header, JSON as an
+// object per row keyed by the header
+type exportTable struct {
+	Header []string
+	Rows   [][]string
+}
+
+// downloads the watcher's watchlists, holdings or transactions as CSV or
+// JSON; the transactions CSV is in the same columns the import reads
+func exportHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+
+		params := mux.Vars(r)
+		what := params["what"]
+		format := params["format"]
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("export", what).Str("format", format).Logger()
+
+		if watcher.WatcherId == 0 {
+			http.Error(w, "sign in to export your data", http.StatusForbidden)
+			return
+		}
+
+		var table exportTable
+		var err error
+		switch what {
+		case "watchlists":
+			table, err = exportWatchlists(deps, sublog, watcher)
+		case "holdings":
+			table, err = exportHoldings(deps, sublog, watcher)
+		case "transactions":
+			table, err = exportTransactions(deps, sublog, watcher)
+		}
+		if err != nil {
+			sublog.Error().Err(err).Msg("export failed")
+			http.Error(w, "the export failed, please try again", http.StatusInternalServerError)
+			return
+		}
+
+		filename := fmt.Sprintf("stockwatch-%s-%s.%s", what, time.Now().Format(sqlDateParseType), format)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if format == "json" {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(table.objects())
+		} else {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+			table.writeCSV(w)
+		}
+		sublog.Info().Int("rows", len(table.Rows)).Msg("export done")
+	})
+}
+
+// object methods -------------------------------------------------------------
+
+func (t exportTable) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	writer.Write(t.Header)
+	writer.WriteAll(t.Rows)
+	return writer.Error()
+}
+
+func (t exportTable) objects() []map[string]string {
+	objects := make([]map[string]string, 0, len(t.Rows))
+	for _, row := range t.Rows {
+		object := make(map[string]string, len(t.Header))
+		for x, column := range t.Header {
+			object[column] = row[x]
+		}
+		objects = append(objects, object)
+	}
+	return objects
+}
+
+// misc -----------------------------------------------------------------------
+
+func exportWatchlists(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) (exportTable, error) {
+	table := exportTable{Header: []string{"watchlist", "position", "symbol", "note"}}
+
+	watchlists, err := getWatcherWatchlists(deps, sublog, watcher)
+	if err != nil {
+		return table, err
+	}
+	for _, watchlist := range watchlists {
+		entries, err := watchlist.entries(deps, sublog)
+		if err != nil {
+			return table, err
+		}
+		for x, entry := range entries {
+			table.Rows = append(table.Rows, []string{watchlist.WatchlistName, strconv.Itoa(x + 1), entry.TickerSymbol, entry.Note})
+		}
+	}
+	return table, nil
+}
+
+func exportHoldings(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) (exportTable, error) {
+	table := exportTable{Header: []string{"symbol", "shares", "price", "cost_basis"}}
+
+	holdings, err := getWatcherHoldings(deps, sublog, watcher)
+	if err != nil {
+		return table, err
+	}
+	for _, holding := range holdings {
+		if holding.Shares == 0 {
+			continue
+		}
+		ticker, err := deps.tickers.GetTicker(holding.TickerId)
+		if err != nil {
+			return table, err
+		}
+		table.Rows = append(table.Rows, []string{
+			ticker.TickerSymbol,
+			formatExportNumber(holding.Shares),
+			formatExportNumber(holding.AvgCost()),
+			formatExportNumber(holding.CostBasis),
+		})
+	}
+	sort.Slice(table.Rows, func(i, j int) bool { return table.Rows[i][0] < table.Rows[j][0] })
+	return table, nil
+}
+
+func exportTransactions(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) (exportTable, error) {
+	table := exportTable{Header: []string{"symbol", "shares", "price", "date", "type"}}
+
+	holdings, err := getWatcherHoldings(deps, sublog, watcher)
+	if err != nil {
+		return table, err
+	}
+	for _, holding := range holdings {
+		ticker, err := deps.tickers.GetTicker(holding.TickerId)
+		if err != nil {
+			return table, err
+		}
+		transactions, err := getTransactionsByHolding(deps, sublog, holding.HoldingId)
+		if err != nil {
+			return table, err
+		}
+		for _, transaction := range transactions {
+			table.Rows = append(table.Rows, []string{
+				ticker.TickerSymbol,
+				formatExportNumber(transaction.Shares),
+				formatExportNumber(transaction.SharePrice),
+				transaction.TransactionDate().Format(sqlDateParseType),
+				transaction.TransactionType,
+			})
+		}
+	}
+	// oldest first, so a re-import never sells before it buys
+	sort.SliceStable(table.Rows, func(i, j int) bool { return table.Rows[i][3] < table.Rows[j][3] })
+	return table, nil
+}
+
+func formatExportNumber(number float64) string {
+	return strconv.FormatFloat(number, 'f', -1, 64)
+}
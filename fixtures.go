@@ -0,0 +1,265 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/weirdtangent/yhfinance"
+)
+
+// fixtureProvider answers from yhfinance responses saved on disk, so the site
+// can run without RapidAPI. Files live under MarketDataFixtureDir using the
+// same keys as the redis cache:
+//
+//	yhfinance/summary/AAPL.json
+//	yhfinance/quote/AAPL.json
+//	yhfinance/historical/AAPL.json
+//	yhfinance/news/AAPL.json
+//	yhfinance/news/market.json (general market news)
+//	yhfinance/movers/US.json
+//	yhfinance/financials/AAPL.json
+//	yhfinance/autocomplete/apple.json
+//
+// Run with MarketDataRecord on (and the yhfinance provider) to capture them.
+type fixtureProvider struct {
+	dir string
+}
+
+// recordingProvider passes everything through to yhfinance and saves each raw
+// response where fixtureProvider will look for it
+type recordingProvider struct {
+	yhfinanceProvider
+	dir string
+}
+
+// object methods -------------------------------------------------------------
+
+func (fp fixtureProvider) Name() string {
+	return "fixtures"
+}
+
+func (fp fixtureProvider) GetSummary(deps *Dependencies, sublog zerolog.Logger, symbol string) (yhfinance.YHStockSummaryResponse, error) {
+	response, err := fp.read(sublog, "yhfinance/summary/"+symbol)
+	if err != nil {
+		return yhfinance.YHStockSummaryResponse{}, err
+	}
+	return decodeYHSummary(response)
+}
+
+func (fp fixtureProvider) GetQuote(deps *Dependencies, sublog zerolog.Logger, symbol, region string) (Quote, error) {
+	response, err := fp.read(sublog, "yhfinance/quote/"+symbol)
+	if err != nil {
+		return Quote{}, err
+	}
+	quotes, err := decodeYHQuotes(response)
+	if err != nil {
+		return Quote{}, err
+	}
+	if len(quotes) == 0 {
+		return Quote{}, fmt.Errorf("fixture for %s has no quote", symbol)
+	}
+	return quotes[0], nil
+}
+
+// one file per symbol; symbols without a fixture are just left out, the same
+// as yhfinance does for symbols it doesn't know
+func (fp fixtureProvider) GetQuotes(deps *Dependencies, sublog zerolog.Logger, symbols []string) (map[string]Quote, error) {
+	quotes := map[string]Quote{}
+	for _, symbol := range symbols {
+		quote, err := fp.GetQuote(deps, sublog, symbol, "")
+		if err != nil {
+			continue
+		}
+		quotes[quote.Symbol] = quote
+	}
+	return quotes, nil
+}
+
+func (fp fixtureProvider) GetHistorical(deps *Dependencies, sublog zerolog.Logger, symbol string) (HistoricalData, error) {
+	response, err := fp.read(sublog, "yhfinance/historical/"+symbol)
+	if err != nil {
+		return HistoricalData{}, err
+	}
+	return decodeYHHistorical(response)
+}
+
+func (fp fixtureProvider) GetNews(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]NewsItem, error) {
+	response, err := fp.read(sublog, newsFixtureKey(symbol))
+	if err != nil {
+		return nil, err
+	}
+	return decodeYHNews(response)
+}
+
+func (fp fixtureProvider) GetFinancials(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]FinancialsValue, error) {
+	response, err := fp.read(sublog, "yhfinance/financials/"+symbol)
+	if err != nil {
+		return nil, err
+	}
+	return decodeYHFinancials(response)
+}
+
+func (fp fixtureProvider) GetMovers(deps *Dependencies, sublog zerolog.Logger) (map[string][]string, error) {
+	response, err := fp.read(sublog, "yhfinance/movers/US")
+	if err != nil {
+		return nil, err
+	}
+	return decodeYHMovers(response)
+}
+
+func (fp fixtureProvider) Search(deps *Dependencies, sublog zerolog.Logger, searchString string) (yhfinance.YHAutoCompleteResponse, error) {
+	response, err := fp.read(sublog, "yhfinance/autocomplete/"+url.PathEscape(searchString))
+	if err != nil {
+		return yhfinance.YHAutoCompleteResponse{}, err
+	}
+	return decodeYHAutoComplete(response)
+}
+
+func (fp fixtureProvider) read(sublog zerolog.Logger, key string) (string, error) {
+	filename := filepath.Join(fp.dir, key+".json")
+	contents, err := os.ReadFile(filename)
+	if err != nil {
+		sublog.Warn().Err(err).Str("fixture", filename).Msg("no fixture for request")
+		return "", err
+	}
+	sublog.Debug().Str("fixture", filename).Msg("serving fixture")
+	return string(contents), nil
+}
+
+func (rp recordingProvider) Name() string {
+	return "yhfinance (recording)"
+}
+
+func (rp recordingProvider) GetSummary(deps *Dependencies, sublog zerolog.Logger, symbol string) (yhfinance.YHStockSummaryResponse, error) {
+	response, err := yhfinance.GetYHFinanceStockSummary(&sublog, rp.apiKey, rp.apiHost, symbol)
+	if err != nil {
+		return yhfinance.YHStockSummaryResponse{}, err
+	}
+	rp.record(sublog, "yhfinance/summary/"+symbol, response)
+	return decodeYHSummary(response)
+}
+
+func (rp recordingProvider) GetQuote(deps *Dependencies, sublog zerolog.Logger, symbol, region string) (Quote, error) {
+	quotes, err := rp.GetQuotes(deps, sublog, []string{symbol})
+	if err != nil {
+		return Quote{}, err
+	}
+	quote, ok := quotes[symbol]
+	if !ok {
+		return Quote{}, fmt.Errorf("failed to get response data back from yhfinance")
+	}
+	return quote, nil
+}
+
+// a multi-symbol response is split up and saved per symbol, in the same
+// envelope yhfinance uses, so any mix of symbols can be replayed later
+func (rp recordingProvider) GetQuotes(deps *Dependencies, sublog zerolog.Logger, symbols []string) (map[string]Quote, error) {
+	quotes := map[string]Quote{}
+
+	response, err := yhfinance.GetFromYHFinance(&sublog, rp.apiKey, rp.apiHost, "marketQuote", map[string]string{"symbols": strings.Join(symbols, ",")})
+	if err != nil {
+		return quotes, err
+	}
+
+	var envelope struct {
+		QuoteResponse struct {
+			Result []json.RawMessage `json:"result"`
+		} `json:"quoteResponse"`
+	}
+	err = json.Unmarshal([]byte(response), &envelope)
+	if err != nil {
+		return quotes, err
+	}
+	for _, raw := range envelope.QuoteResponse.Result {
+		var quoteSymbol struct {
+			Symbol string `json:"symbol"`
+		}
+		if json.Unmarshal(raw, &quoteSymbol) != nil || quoteSymbol.Symbol == "" {
+			continue
+		}
+		single, _ := json.Marshal(map[string]interface{}{"quoteResponse": map[string]interface{}{"result": []json.RawMessage{raw}}})
+		rp.record(sublog, "yhfinance/quote/"+quoteSymbol.Symbol, string(single))
+	}
+
+	quoteList, _ := decodeYHQuotes(response)
+	for _, quote := range quoteList {
+		quotes[quote.Symbol] = quote
+	}
+	return quotes, nil
+}
+
+func (rp recordingProvider) GetHistorical(deps *Dependencies, sublog zerolog.Logger, symbol string) (HistoricalData, error) {
+	response, err := yhfinance.GetFromYHFinance(&sublog, rp.apiKey, rp.apiHost, "stockHistorical", map[string]string{"symbol": symbol})
+	if err != nil {
+		return HistoricalData{}, err
+	}
+	rp.record(sublog, "yhfinance/historical/"+symbol, response)
+	return decodeYHHistorical(response)
+}
+
+func (rp recordingProvider) GetNews(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]NewsItem, error) {
+	response, err := yhfinance.GetFromYHFinance(&sublog, rp.apiKey, rp.apiHost, "newsList", yhNewsParams(symbol))
+	if err != nil {
+		return nil, err
+	}
+	rp.record(sublog, newsFixtureKey(symbol), response)
+	return decodeYHNews(response)
+}
+
+func (rp recordingProvider) GetFinancials(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]FinancialsValue, error) {
+	response, err := yhfinance.GetFromYHFinance(&sublog, rp.apiKey, rp.apiHost, "stockFinancials", map[string]string{"symbol": symbol, "region": "US"})
+	if err != nil {
+		return nil, err
+	}
+	rp.record(sublog, "yhfinance/financials/"+symbol, response)
+	return decodeYHFinancials(response)
+}
+
+func (rp recordingProvider) GetMovers(deps *Dependencies, sublog zerolog.Logger) (map[string][]string, error) {
+	response, err := yhfinance.GetFromYHFinance(&sublog, rp.apiKey, rp.apiHost, "marketMovers", map[string]string{"region": "US", "lang": "en-US", "count": "10", "start": "0"})
+	if err != nil {
+		return nil, err
+	}
+	rp.record(sublog, "yhfinance/movers/US", response)
+	return decodeYHMovers(response)
+}
+
+func (rp recordingProvider) Search(deps *Dependencies, sublog zerolog.Logger, searchString string) (yhfinance.YHAutoCompleteResponse, error) {
+	response, err := yhfinance.GetFromYHFinance(&sublog, rp.apiKey, rp.apiHost, "autocomplete", map[string]string{"q": searchString, "region": "US"})
+	if err != nil {
+		return yhfinance.YHAutoCompleteResponse{}, err
+	}
+	rp.record(sublog, "yhfinance/autocomplete/"+url.PathEscape(searchString), response)
+	return decodeYHAutoComplete(response)
+}
+
+func (rp recordingProvider) record(sublog zerolog.Logger, key, response string) {
+	filename := filepath.Join(rp.dir, key+".json")
+	err := os.MkdirAll(filepath.Dir(filename), 0755)
+	if err == nil {
+		err = os.WriteFile(filename, []byte(response), 0644)
+	}
+	if err != nil {
+		sublog.Error().Err(err).Str("fixture", filename).Msg("failed to record fixture")
+		return
+	}
+	sublog.Info().Str("fixture", filename).Msg("recorded fixture")
+}
+
+// misc -----------------------------------------------------------------------
+
+func newFixtureProvider(deps *Dependencies) MarketDataProvider {
+	return fixtureProvider{dir: deps.config.MarketDataFixtureDir}
+}
+
+func newsFixtureKey(symbol string) string {
+	if symbol == "" {
+		return "yhfinance/news/market"
+	}
+	return "yhfinance/news/" + symbol
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+type HealthCheck struct {
+	Status       string `json:"status"`
+	Error        string `json:"error,omitempty"`
+	ResponseTime int64  `json:"response_time"` // milliseconds
+}
+
+type HealthStatus struct {
+	Status string                 `json:"status"`
+	Checks map[string]HealthCheck `json:"checks,omitempty"`
+}
+
+// misc -----------------------------------------------------------------------
+
+// liveness: the process is up and serving, nothing more
+func healthzHandler() http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeHealthStatus(w, HealthStatus{Status: "ok"})
+	})
+}
+
+// readiness: every dependency a page needs answers, and we aren't on our way
+// down. Anything else is a 503 so the load balancer stops sending us traffic
+func readyzHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if deps.shuttingDown.Load() {
+			writeHealthStatus(w, HealthStatus{Status: "shutting down"})
+			return
+		}
+
+		sublog := getRequestContext(r).logger
+
+		// every check has to give up when this runs out, or one hung
+		// dependency holds the whole answer past it
+		ctx, cancel := context.WithTimeout(r.Context(), readyCheckTimeout*time.Second)
+		defer cancel()
+
+		checks := map[string]func(context.Context) error{
+			"database": deps.db.PingContext,
+			"redis": func(ctx context.Context) error {
+				conn, err := deps.redisPool.GetContext(ctx)
+				if err != nil {
+					return err
+				}
+				defer conn.Close()
+				_, err = redis.DoContext(conn, ctx, "PING")
+				return err
+			},
+			"queue": func(ctx context.Context) error {
+				return deps.queue.Ping(ctx, deps.config.TickerQueueName)
+			},
+		}
+
+		status := HealthStatus{Status: "ok", Checks: make(map[string]HealthCheck)}
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for name, check := range checks {
+			wg.Add(1)
+			go func(name string, check func(context.Context) error) {
+				defer wg.Done()
+				start := time.Now()
+				result := HealthCheck{Status: "ok"}
+				if err := check(ctx); err != nil {
+					result.Status = "fail"
+					result.Error = err.Error()
+				}
+				result.ResponseTime = time.Since(start).Milliseconds()
+
+				mu.Lock()
+				defer mu.Unlock()
+				status.Checks[name] = result
+				if result.Status != "ok" {
+					status.Status = "fail"
+				}
+			}(name, check)
+		}
+		wg.Wait()
+
+		if status.Status != "ok" {
+			sublog.Warn().Interface("checks", status.Checks).Msg("not ready")
+		}
+		writeHealthStatus(w, status)
+	})
+}
+
+func writeHealthStatus(w http.ResponseWriter, status HealthStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if status.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+type Holding struct {
+	HoldingId      uint64 `db:"holding_id"`
+	EId            string
+	WatcherId      uint64    `db:"watcher_id"`
+	TickerId       uint64    `db:"ticker_id"`
+	Shares         float64   `db:"shares"`
+	CostBasis      float64   `db:"cost_basis"`
+	CreateDatetime time.Time `db:"create_datetime"`
+	UpdateDatetime time.Time `db:"update_datetime"`
+}
+
+// object methods -------------------------------------------------------------
+
+func (h *Holding) getByWatcherTicker(deps *Dependencies) error {
+	db := deps.db
+
+	err := db.QueryRowx("SELECT * FROM holding WHERE watcher_id=? AND ticker_id=?", h.WatcherId, h.TickerId).StructScan(h)
+	return err
+}
+
+func (h *Holding) create(deps *Dependencies, sublog zerolog.Logger) error {
+	return h.insert(deps.db, sublog)
+}
+
+// insert on the connection or inside a transaction
+func (h *Holding) insert(db sqlx.Execer, sublog zerolog.Logger) error {
+	insert := "INSERT INTO holding (watcher_id, ticker_id, shares, cost_basis) VALUES (?, ?, ?, ?)"
+	res, err := db.Exec(insert, h.WatcherId, h.TickerId, h.Shares, h.CostBasis)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on INSERT")
+		return err
+	}
+	holdingId, err := res.LastInsertId()
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on LAST_INSERTID")
+		return err
+	}
+	h.HoldingId = uint64(holdingId)
+	return nil
+}
+
+// two first buys at once both find no holding; the one that loses the race
+// on holding_watcher_ticker picks up the row the other one added
+func (h *Holding) insertOrGet(db sqlx.Ext, sublog zerolog.Logger) error {
+	err := h.insert(db, sublog)
+	if err == nil {
+		return nil
+	}
+	if getErr := sqlx.Get(db, h, "SELECT * FROM holding WHERE watcher_id=? AND ticker_id=?", h.WatcherId, h.TickerId); getErr != nil {
+		return err
+	}
+	return nil
+}
+
+func (h *Holding) update(deps *Dependencies, sublog zerolog.Logger) error {
+	return h.updateIn(deps.db, sublog)
+}
+
+// update on the connection or inside a transaction
+func (h *Holding) updateIn(db sqlx.Execer, sublog zerolog.Logger) error {
+	update := "UPDATE holding SET shares=?, cost_basis=?, update_datetime=CURRENT_TIMESTAMP WHERE holding_id=?"
+	_, err := db.Exec(update, h.Shares, h.CostBasis, h.HoldingId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on UPDATE")
+	}
+	return err
+}
+
+// recalculate replays the holding's transactions (and any splits) through the
+// watcher's cost-basis method and saves the shares and cost basis still held
+func (h *Holding) recalculate(deps *Dependencies, sublog zerolog.Logger) error {
+	return h.recalculateIn(deps, deps.db, sublog)
+}
+
+// recalculate inside a transaction, which has to see the trades it just added
+func (h *Holding) recalculateIn(deps *Dependencies, db sqlx.Ext, sublog zerolog.Logger) error {
+	watcher, err := getWatcherById(deps, h.WatcherId)
+	if err != nil {
+		sublog.Error().Err(err).Uint64("watcher_id", h.WatcherId).Msg("failed to get watcher for holding")
+		return err
+	}
+
+	transactions, err := queryTransactionsByHolding(db, sublog, h.HoldingId)
+	if err != nil {
+		return err
+	}
+
+	splits, err := h.getSplits(deps, sublog)
+	if err != nil {
+		return err
+	}
+
+	pl, err := calcHoldingPL(transactions, splits, watcher.CostBasisMethod, 0, time.Now())
+	if err != nil {
+		return err
+	}
+
+	h.Shares = pl.Shares
+	h.CostBasis = pl.CostBasis
+	return h.updateIn(db, sublog)
+}
+
+func (h Holding) getSplits(deps *Dependencies, sublog zerolog.Logger) ([]TickerSplit, error) {
+	return Ticker{TickerId: h.TickerId}.getSplits(deps, sublog)
+}
+
+func (h Holding) getDividends(deps *Dependencies, sublog zerolog.Logger) ([]TickerDividend, error) {
+	return Ticker{TickerId: h.TickerId}.getDividends(deps, sublog)
+}
+
+func (h Holding) AvgCost() float64 {
+	if h.Shares == 0 {
+		return 0
+	}
+	return h.CostBasis / h.Shares
+}
+
+func (h Holding) MarketValue(price float64) float64 {
+	return h.Shares * price
+}
+
+func (h Holding) GainAmt(price float64) float32 {
+	return float32(h.MarketValue(price) - h.CostBasis)
+}
+
+func (h Holding) GainPct(price float64) float32 {
+	if h.CostBasis == 0 {
+		return 0
+	}
+	return float32((h.MarketValue(price) - h.CostBasis) / h.CostBasis * 100)
+}
+
+// misc -----------------------------------------------------------------------
+
+func getWatcherHolding(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, ticker Ticker) (Holding, error) {
+	holding := Holding{WatcherId: watcher.WatcherId, TickerId: ticker.TickerId}
+	if watcher.WatcherId == 0 {
+		return holding, nil
+	}
+
+	err := holding.getByWatcherTicker(deps)
+	if err != nil && errors.Is(err, sql.ErrNoRows) {
+		return Holding{WatcherId: watcher.WatcherId, TickerId: ticker.TickerId}, nil
+	}
+	return holding, err
+}
+
+func getWatcherHoldings(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) ([]Holding, error) {
+	db := deps.db
+
+	var holding Holding
+	holdings := make([]Holding, 0)
+
+	rows, err := db.Queryx("SELECT * FROM holding WHERE watcher_id=?", watcher.WatcherId)
+	if err != nil {
+		return holdings, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&holding)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+		} else {
+			holdings = append(holdings, holding)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return holdings, err
+	}
+
+	return holdings, nil
+}
@@ -0,0 +1,645 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// ImportRow is one line of an uploaded file, as understood and checked; only
+// rows with no Error that aren't Skipped get imported
+type ImportRow struct {
+	Line         int
+	Symbol       string // as the file has it
+	TickerSymbol string // what it was found as, which a search may have changed
+	Action       string // bought or sold
+	Shares       float64
+	Price        float64
+	Date         string
+	Note         string
+	Error        string
+	Skipped      bool // not an error, just not something we import (dividends, cash...)
+	ticker       Ticker
+}
+
+// the columns of a known export, by what we call them: symbol, shares, price,
+// cost (a total, for exports without a per-share price), date, action and note
+type importFormat struct {
+	Name     string
+	Columns  map[string][]string // the headers each column goes by
+	Required []string            // the columns a header row needs to be this format
+}
+
+const importTargetTransactions = "transactions"
+
+// the broker exports come first, they are more particular about their headers
+var importFormats = []importFormat{
+	{
+		Name:     "Fidelity positions",
+		Columns:  map[string][]string{"symbol": {"Symbol"}, "shares": {"Quantity"}, "price": {"Average Cost Basis"}},
+		Required: []string{"symbol", "shares", "price"},
+	},
+	{
+		Name:     "Fidelity activity",
+		Columns:  map[string][]string{"date": {"Run Date"}, "action": {"Action"}, "symbol": {"Symbol"}, "shares": {"Quantity"}, "price": {"Price ($)"}},
+		Required: []string{"date", "action", "symbol", "shares", "price"},
+	},
+	{
+		Name:     "Schwab positions",
+		Columns:  map[string][]string{"symbol": {"Symbol"}, "shares": {"Quantity", "Qty (Quantity)"}, "cost": {"Cost Basis"}},
+		Required: []string{"symbol", "shares", "cost"},
+	},
+	{
+		Name:     "Schwab transactions",
+		Columns:  map[string][]string{"date": {"Date"}, "action": {"Action"}, "symbol": {"Symbol"}, "shares": {"Quantity"}, "price": {"Price"}},
+		Required: []string{"date", "action", "symbol", "shares", "price"},
+	},
+	{
+		Name:     "Vanguard transactions",
+		Columns:  map[string][]string{"date": {"Trade Date"}, "action": {"Transaction Type"}, "symbol": {"Symbol"}, "shares": {"Shares"}, "price": {"Share Price"}},
+		Required: []string{"date", "action", "symbol", "shares", "price"},
+	},
+	{
+		Name:     "Robinhood activity",
+		Columns:  map[string][]string{"date": {"Activity Date"}, "action": {"Trans Code"}, "symbol": {"Instrument"}, "shares": {"Quantity"}, "price": {"Price"}},
+		Required: []string{"date", "action", "symbol", "shares", "price"},
+	},
+	{
+		Name: "CSV",
+		Columns: map[string][]string{
+			"symbol": {"symbol", "ticker"},
+			"shares": {"shares", "quantity"},
+			"price":  {"price", "share price"},
+			"date":   {"date"},
+			"action": {"type", "action"},
+			"note":   {"note", "notes"},
+		},
+		Required: []string{"symbol"},
+	},
+}
+
+// symbol, shares, price, date, for a file with no header row at all
+var headerlessColumns = map[string]int{"symbol": 0, "shares": 1, "price": 2, "date": 3}
+
+var importDateFormats = []string{sqlDateParseType, "01/02/2006", "1/2/2006", "01/02/06", "1/2/06", "2006/01/02"}
+
+func importHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Logger()
+
+		if watcher.WatcherId == 0 {
+			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
+			return
+		}
+
+		// the form has to be read with a limit before anything asks for a value
+		var formErr error
+		if r.Method == http.MethodPost {
+			r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes*2)
+			formErr = r.ParseMultipartForm(maxImportBytes)
+			if errors.Is(formErr, http.ErrNotMultipart) {
+				formErr = r.ParseForm()
+			}
+		}
+
+		watchlists, _ := getWatcherWatchlists(deps, sublog, watcher)
+		webdata["Watchlists"] = watchlists
+
+		target := r.FormValue("target")
+		if target == "" {
+			target = importTargetTransactions
+		}
+		webdata["ImportTarget"] = target
+		webdata["ImportListName"] = r.FormValue("listname")
+
+		if r.Method != http.MethodPost {
+			renderTemplate(w, r, deps, sublog, "import")
+			return
+		}
+
+		if formErr != nil {
+			sublog.Warn().Err(formErr).Msg("failed to read import form")
+			rc.messages = append(rc.messages, Message{fmt.Sprintf("Sorry, the file could not be read; files can be up to %dKB", maxImportBytes/1024), "error"})
+			renderTemplate(w, r, deps, sublog, "import")
+			return
+		}
+		contents, err := readImportUpload(r)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed to read import")
+			rc.messages = append(rc.messages, Message{"Sorry, " + err.Error(), "error"})
+			renderTemplate(w, r, deps, sublog, "import")
+			return
+		}
+		webdata["ImportContents"] = contents
+
+		format, rows, err := parseImport(strings.NewReader(contents))
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed to parse import")
+			rc.messages = append(rc.messages, Message{"Sorry, " + err.Error(), "error"})
+			renderTemplate(w, r, deps, sublog, "import")
+			return
+		}
+		sublog = sublog.With().Str("format", format).Str("target", target).Int("rows", len(rows)).Logger()
+		webdata["ImportFormat"] = format
+
+		var watchlist Watchlist
+		if target == importTargetTransactions {
+			checkTransactionImport(deps, sublog, watcher, rows)
+		} else {
+			if target != "new" {
+				watchlist, err = getWatcherWatchlist(deps, sublog, watcher, target)
+				if err != nil {
+					rc.messages = append(rc.messages, Message{"Sorry, that watchlist could not be found", "error"})
+					renderTemplate(w, r, deps, sublog, "import")
+					return
+				}
+			}
+			checkWatchlistImport(deps, sublog, rows)
+		}
+		webdata["ImportRows"] = rows
+
+		ready := 0
+		for _, row := range rows {
+			if row.Error == "" && !row.Skipped {
+				ready++
+			}
+		}
+		webdata["ImportReady"] = ready
+
+		// the first post is the preview; it's imported when they confirm it
+		if r.FormValue("confirm") == "" || ready == 0 {
+			renderTemplate(w, r, deps, sublog, "import")
+			return
+		}
+
+		if target == importTargetTransactions {
+			err = applyTransactionImport(deps, sublog, watcher, rows)
+		} else {
+			if target == "new" {
+				watchlist, err = createWatchlist(deps, sublog, watcher, r.FormValue("listname"))
+			}
+			if err == nil {
+				err = applyWatchlistImport(deps, sublog, watchlist, rows)
+			}
+		}
+		if err != nil {
+			sublog.Error().Err(err).Msg("import failed")
+			rc.messages = append(rc.messages, Message{"Sorry, the import did not finish: " + err.Error(), "error"})
+			renderTemplate(w, r, deps, sublog, "import")
+			return
+		}
+		sublog.Info().Int("imported", ready).Msg("import done")
+
+		if target == importTargetTransactions {
+			http.Redirect(w, r, "/desktop", http.StatusFound)
+		} else {
+			http.Redirect(w, r, "/desktop?list="+watchlist.EId, http.StatusFound)
+		}
+	})
+}
+
+// misc -----------------------------------------------------------------------
+
+// an uploaded file wins over pasted text, which is also how the preview
+// hands the contents back when it is confirmed
+func readImportUpload(r *http.Request) (string, error) {
+	file, _, err := r.FormFile("file")
+	if err == nil {
+		defer file.Close()
+		contents, err := io.ReadAll(io.LimitReader(file, maxImportBytes+1))
+		if err != nil {
+			return "", fmt.Errorf("the file could not be read")
+		}
+		if len(contents) > maxImportBytes {
+			return "", fmt.Errorf("the file is larger than %dKB", maxImportBytes/1024)
+		}
+		return string(contents), nil
+	}
+
+	contents := r.FormValue("contents")
+	if strings.TrimSpace(contents) == "" {
+		return "", fmt.Errorf("there was nothing to import")
+	}
+	if len(contents) > maxImportBytes {
+		return "", fmt.Errorf("the file is larger than %dKB", maxImportBytes/1024)
+	}
+	return contents, nil
+}
+
+// find the header row (broker exports often have a few lines above it) and
+// read every row under it; rows without a symbol are totals, cash and the
+// like and are left out
+func parseImport(reader io.Reader) (string, []ImportRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.LazyQuotes = true
+	csvReader.TrimLeadingSpace = true
+
+	records, err := csvReader.ReadAll()
+	if err != nil {
+		return "", []ImportRow{}, fmt.Errorf("the file isn't a CSV file we can read")
+	}
+
+	formatName := ""
+	var columns map[string]int
+	start := 0
+	for x := 0; x < len(records) && x < maxImportHeaderSearch; x++ {
+		if format, found, ok := matchImportFormat(records[x]); ok {
+			formatName, columns, start = format.Name, found, x+1
+			break
+		}
+	}
+	if columns == nil {
+		if len(records) == 0 || len(records[0]) < 2 || parseImportNumber(records[0][1]) == nil {
+			return "", []ImportRow{}, fmt.Errorf("the file's columns weren't recognized; it needs a header row with at least a symbol column")
+		}
+		formatName, columns = "CSV without a header", headerlessColumns
+	}
+
+	rows := make([]ImportRow, 0, len(records)-start)
+	for x := start; x < len(records); x++ {
+		row, ok := parseImportRecord(records[x], columns)
+		if !ok {
+			continue
+		}
+		row.Line = x + 1
+		rows = append(rows, row)
+		if len(rows) > maxImportRows {
+			return formatName, rows, fmt.Errorf("the file has more than %d rows; please split it up", maxImportRows)
+		}
+	}
+	if len(rows) == 0 {
+		return formatName, rows, fmt.Errorf("no rows with a symbol were found")
+	}
+	return formatName, rows, nil
+}
+
+// the first format whose required columns are all in the header, and where
+// each of its columns is
+func matchImportFormat(header []string) (importFormat, map[string]int, bool) {
+	position := make(map[string]int, len(header))
+	for x, name := range header {
+		name = strings.ToLower(strings.Trim(strings.TrimSpace(name), "\ufeff\""))
+		if _, ok := position[name]; !ok {
+			position[name] = x
+		}
+	}
+
+	for _, format := range importFormats {
+		columns := make(map[string]int, len(format.Columns))
+		for column, names := range format.Columns {
+			for _, name := range names {
+				if x, ok := position[strings.ToLower(name)]; ok {
+					columns[column] = x
+					break
+				}
+			}
+		}
+		matched := true
+		for _, column := range format.Required {
+			if _, ok := columns[column]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return format, columns, true
+		}
+	}
+	return importFormat{}, nil, false
+}
+
+func parseImportRecord(record []string, columns map[string]int) (ImportRow, bool) {
+	cell := func(column string) string {
+		x, ok := columns[column]
+		if !ok || x >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[x])
+	}
+
+	// Fidelity marks money market funds with asterisks
+	symbol := strings.ToUpper(strings.TrimRight(cell("symbol"), "*"))
+	if symbol == "" || strings.ContainsAny(symbol, " \t") {
+		return ImportRow{}, false
+	}
+	row := ImportRow{Symbol: symbol, Note: cleanWatchlistNote(cell("note"))}
+
+	if sharesStr := cell("shares"); sharesStr != "" {
+		shares := parseImportNumber(sharesStr)
+		if shares == nil {
+			row.Error = fmt.Sprintf("quantity %q isn't a number", sharesStr)
+			return row, true
+		}
+		row.Shares = math.Abs(*shares)
+	}
+	if priceStr := cell("price"); priceStr != "" {
+		price := parseImportNumber(priceStr)
+		if price == nil {
+			row.Error = fmt.Sprintf("price %q isn't a number", priceStr)
+			return row, true
+		}
+		row.Price = math.Abs(*price)
+	} else if costStr := cell("cost"); costStr != "" && row.Shares > 0 {
+		if cost := parseImportNumber(costStr); cost != nil {
+			row.Price = math.Round(math.Abs(*cost)/row.Shares*10000) / 10000
+		}
+	}
+
+	if dateStr := cell("date"); dateStr != "" {
+		date, err := parseImportDate(dateStr)
+		if err != nil {
+			row.Error = fmt.Sprintf("date %q isn't a date", dateStr)
+			return row, true
+		}
+		row.Date = date
+	}
+
+	// a file without an action column is positions, or plain purchases
+	row.Action = "bought"
+	if _, ok := columns["action"]; ok {
+		action := strings.ToLower(cell("action"))
+		switch {
+		case strings.Contains(action, "buy") || strings.Contains(action, "bought") || action == "":
+			row.Action = "bought"
+		case strings.Contains(action, "sell") || strings.Contains(action, "sold"):
+			row.Action = "sold"
+		default:
+			row.Action = action
+			row.Skipped = true
+		}
+	}
+	return row, true
+}
+
+// "$1,234.50", "(12.00)" and "-3" all read as numbers; "--" and "n/a" don't
+func parseImportNumber(text string) *float64 {
+	text = strings.TrimSpace(text)
+	negative := strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")")
+	text = strings.Trim(text, "()")
+	text = strings.NewReplacer("$", "", ",", "", "+", "").Replace(text)
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+		return nil
+	}
+	if negative {
+		number = -number
+	}
+	return &number
+}
+
+// Schwab adds "as of" dates after the trade date; the trade date is the one
+func parseImportDate(text string) (string, error) {
+	if fields := strings.Fields(text); len(fields) > 0 {
+		text = fields[0]
+	}
+	for _, format := range importDateFormats {
+		if date, err := time.Parse(format, text); err == nil {
+			return date.Format(sqlDateParseType), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", text)
+}
+
+// each symbol is looked up once; one we can't find directly goes through the
+// same search as the jump box, and the row says what it was matched to
+func findImportTickers(deps *Dependencies, sublog zerolog.Logger, rows []ImportRow) {
+	found := map[string]Ticker{}
+	failed := map[string]string{}
+	for x := range rows {
+		row := &rows[x]
+		if row.Error != "" || row.Skipped {
+			continue
+		}
+		if ticker, ok := found[row.Symbol]; ok {
+			row.ticker, row.TickerSymbol = ticker, ticker.TickerSymbol
+			continue
+		}
+		if reason, ok := failed[row.Symbol]; ok {
+			row.Error = reason
+			continue
+		}
+
+		ticker, err := getFreshTicker(deps, sublog, row.Symbol)
+		if err != nil {
+			var result SearchResultTicker
+			result, err = jumpSearch(deps, sublog, row.Symbol)
+			if err == nil && result.TickerSymbol != "" {
+				ticker, err = getFreshTicker(deps, sublog, result.TickerSymbol)
+			}
+		}
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", row.Symbol).Msg("import symbol not found")
+			failed[row.Symbol] = fmt.Sprintf("symbol %s could not be found", row.Symbol)
+			row.Error = failed[row.Symbol]
+			continue
+		}
+		found[row.Symbol] = ticker
+		row.ticker, row.TickerSymbol = ticker, ticker.TickerSymbol
+	}
+}
+
+func checkWatchlistImport(deps *Dependencies, sublog zerolog.Logger, rows []ImportRow) {
+	// a watchlist only wants the symbols, whatever the file says was done
+	for x := range rows {
+		rows[x].Skipped = false
+	}
+	findImportTickers(deps, sublog, rows)
+
+	seen := map[uint64]bool{}
+	for x := range rows {
+		row := &rows[x]
+		if row.Error != "" {
+			continue
+		}
+		if seen[row.ticker.TickerId] {
+			row.Skipped = true
+			continue
+		}
+		seen[row.ticker.TickerId] = true
+	}
+}
+
+// every trade has to make sense on its own and against what is already
+// recorded: a sale can't be for more than was held on its date, and a trade
+// already recorded (same day, shares and price) isn't recorded twice. Two
+// matching rows in the file are two trades, so only stored ones count as
+// duplicates
+func checkTransactionImport(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, rows []ImportRow) {
+	today := time.Now().Format(sqlDateParseType)
+	for x := range rows {
+		row := &rows[x]
+		if row.Error != "" || row.Skipped {
+			continue
+		}
+		// a positions export has no dates, and guessing one would put the
+		// cost basis and every return on the wrong day
+		switch {
+		case row.Date == "":
+			row.Error = "no date given; add a date column with when the shares were bought"
+		case row.Shares <= 0:
+			row.Error = "no quantity given"
+		case row.Date > today:
+			row.Error = "date is in the future"
+		}
+	}
+	findImportTickers(deps, sublog, rows)
+
+	// by ticker, then oldest first with a day's buys ahead of its sales, so
+	// each sale is checked against what was held just before it
+	order := make([]int, 0, len(rows))
+	for x := range rows {
+		if rows[x].Error == "" && !rows[x].Skipped {
+			order = append(order, x)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := rows[order[i]], rows[order[j]]
+		if a.ticker.TickerId != b.ticker.TickerId {
+			return a.ticker.TickerId < b.ticker.TickerId
+		}
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		return a.Action == "bought" && b.Action != "bought"
+	})
+
+	var tickerId uint64
+	var stored, transactions []Transaction
+	var splits []TickerSplit
+	for _, x := range order {
+		row := &rows[x]
+		if row.ticker.TickerId != tickerId {
+			tickerId = row.ticker.TickerId
+			stored, splits = []Transaction{}, []TickerSplit{}
+			holding, err := getWatcherHolding(deps, sublog, watcher, row.ticker)
+			if err == nil && holding.HoldingId != 0 {
+				stored, err = getTransactionsByHolding(deps, sublog, holding.HoldingId)
+			}
+			if err == nil {
+				splits, err = row.ticker.getSplits(deps, sublog)
+			}
+			if err != nil {
+				row.Error = "could not check against your holding"
+				tickerId = 0
+				continue
+			}
+			transactions = append([]Transaction{}, stored...)
+		}
+
+		transaction := row.transaction(watcher)
+		duplicate := false
+		for _, existing := range stored {
+			if existing.TransactionType == transaction.TransactionType && existing.TransactionDate().Equal(transaction.TransactionDate()) &&
+				existing.Shares == transaction.Shares && existing.SharePrice == transaction.SharePrice {
+				duplicate = true
+				break
+			}
+		}
+		if duplicate {
+			row.Error = "already recorded"
+			continue
+		}
+		if row.Action == "sold" {
+			_, err := calcHoldingPL(append(transactions, transaction), splits, watcher.CostBasisMethod, 0, time.Now())
+			if err != nil {
+				row.Error = fmt.Sprintf("you didn't hold %g shares of %s on %s", row.Shares, row.TickerSymbol, row.Date)
+				continue
+			}
+		}
+		transactions = append(transactions, transaction)
+	}
+}
+
+func (row ImportRow) transaction(watcher Watcher) Transaction {
+	return Transaction{
+		WatcherId:           watcher.WatcherId,
+		TransactionType:     row.Action,
+		TransactionDateTime: row.Date,
+		Shares:              row.Shares,
+		SharePrice:          row.Price,
+	}
+}
+
+func applyWatchlistImport(deps *Dependencies, sublog zerolog.Logger, watchlist Watchlist, rows []ImportRow) error {
+	for _, row := range rows {
+		if row.Error != "" || row.Skipped {
+			continue
+		}
+		err := watchlist.addTicker(deps, sublog, row.ticker, row.Note)
+		if err != nil {
+			return fmt.Errorf("%s (line %d): %w", row.TickerSymbol, row.Line, err)
+		}
+	}
+	return nil
+}
+
+// record the trades all or nothing, then bring each holding they touched up
+// to date; the holdings are looked up before the transaction starts
+func applyTransactionImport(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, rows []ImportRow) error {
+	holdings := map[uint64]Holding{}
+	for _, row := range rows {
+		if row.Error != "" || row.Skipped {
+			continue
+		}
+		if _, ok := holdings[row.ticker.TickerId]; ok {
+			continue
+		}
+		holding, err := getWatcherHolding(deps, sublog, watcher, row.ticker)
+		if err != nil {
+			return fmt.Errorf("%s (line %d): %w", row.TickerSymbol, row.Line, err)
+		}
+		holdings[row.ticker.TickerId] = holding
+	}
+
+	tx, err := deps.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, row := range rows {
+		if row.Error != "" || row.Skipped {
+			continue
+		}
+		holding := holdings[row.ticker.TickerId]
+		if holding.HoldingId == 0 {
+			err := holding.insert(tx, sublog)
+			if err != nil {
+				return fmt.Errorf("%s (line %d): %w", row.TickerSymbol, row.Line, err)
+			}
+			holdings[row.ticker.TickerId] = holding
+		}
+
+		transaction := row.transaction(watcher)
+		transaction.HoldingId = holding.HoldingId
+		err := transaction.insert(tx, sublog)
+		if err != nil {
+			return fmt.Errorf("%s (line %d): %w", row.TickerSymbol, row.Line, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, holding := range holdings {
+		err := holding.recalculate(deps, sublog)
+		if err != nil {
+			sublog.Error().Err(err).Uint64("holding_id", holding.HoldingId).Msg("failed to recalculate holding")
+		}
+	}
+	return nil
+}
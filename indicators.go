@@ -0,0 +1,314 @@
+package main
+
+import (
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Indicator is one study asked for on a chart, by name and period, e.g.
+// "sma50" or "rsi14"; a missing period takes the usual default
+type Indicator struct {
+	Kind   string
+	Period int
+}
+
+// IndicatorSeries is one line an indicator draws; values before the
+// indicator has enough history are NaN
+type IndicatorSeries struct {
+	Name   string
+	Values []float64
+	Bar    bool // drawn as bars, like the MACD histogram
+}
+
+const (
+	indicatorSMA  = "sma"
+	indicatorEMA  = "ema"
+	indicatorBB   = "bb"
+	indicatorRSI  = "rsi"
+	indicatorMACD = "macd"
+	indicatorATR  = "atr"
+	indicatorVWAP = "vwap"
+	indicatorOBV  = "obv"
+)
+
+// default period by kind; VWAP and OBV run over the whole chart, MACD is
+// always 12/26/9
+var indicatorPeriods = map[string]int{
+	indicatorSMA:  20,
+	indicatorEMA:  20,
+	indicatorBB:   20,
+	indicatorRSI:  14,
+	indicatorMACD: 0,
+	indicatorATR:  14,
+	indicatorVWAP: 0,
+	indicatorOBV:  0,
+}
+
+// the line chart's moving averages when nothing else is asked for
+var defaultLineIndicators = []Indicator{{indicatorSMA, 20}, {indicatorSMA, 50}, {indicatorSMA, 200}}
+
+// object methods -------------------------------------------------------------
+
+// overlays share the price axis, everything else gets a pane of its own
+func (ind Indicator) isOverlay() bool {
+	switch ind.Kind {
+	case indicatorSMA, indicatorEMA, indicatorBB, indicatorVWAP:
+		return true
+	}
+	return false
+}
+
+func (ind Indicator) Name() string {
+	if ind.Period == 0 {
+		return strings.ToUpper(ind.Kind)
+	}
+	return strings.ToUpper(ind.Kind) + strconv.Itoa(ind.Period)
+}
+
+func (ind Indicator) series(dailies []TickerDaily) []IndicatorSeries {
+	closes := closePrices(dailies)
+
+	switch ind.Kind {
+	case indicatorSMA:
+		return []IndicatorSeries{{Name: ind.Name(), Values: calcSMA(closes, ind.Period)}}
+	case indicatorEMA:
+		return []IndicatorSeries{{Name: ind.Name(), Values: calcEMA(closes, ind.Period)}}
+	case indicatorBB:
+		middle, upper, lower := calcBollinger(closes, ind.Period, 2)
+		return []IndicatorSeries{
+			{Name: ind.Name() + " Mid", Values: middle},
+			{Name: ind.Name() + " Upper", Values: upper},
+			{Name: ind.Name() + " Lower", Values: lower},
+		}
+	case indicatorRSI:
+		return []IndicatorSeries{{Name: ind.Name(), Values: calcRSI(closes, ind.Period)}}
+	case indicatorMACD:
+		macd, signal, histogram := calcMACD(closes, 12, 26, 9)
+		return []IndicatorSeries{
+			{Name: "MACD", Values: macd},
+			{Name: "Signal", Values: signal},
+			{Name: "Histogram", Values: histogram, Bar: true},
+		}
+	case indicatorATR:
+		return []IndicatorSeries{{Name: ind.Name(), Values: calcATR(dailies, ind.Period)}}
+	case indicatorVWAP:
+		return []IndicatorSeries{{Name: ind.Name(), Values: calcVWAP(dailies)}}
+	case indicatorOBV:
+		return []IndicatorSeries{{Name: ind.Name(), Values: calcOBV(dailies)}}
+	}
+	return []IndicatorSeries{}
+}
+
+// misc -----------------------------------------------------------------------
+
+// a comma-separated list like "sma20,ema50,bb,rsi"; anything we don't know
+// is left out, and so are repeats
+func parseIndicators(indicatorStr string) []Indicator {
+	indicators := make([]Indicator, 0)
+	seen := map[Indicator]bool{}
+	for _, name := range strings.Split(strings.ToLower(indicatorStr), ",") {
+		name = strings.TrimSpace(name)
+		kind := strings.TrimRight(name, "0123456789")
+		period, known := indicatorPeriods[kind]
+		if !known {
+			continue
+		}
+		if digits := name[len(kind):]; digits != "" && period > 0 {
+			p, err := strconv.Atoi(digits)
+			if err != nil || p < 2 || p > maxIndicatorPeriod {
+				continue
+			}
+			period = p
+		}
+		indicator := Indicator{kind, period}
+		if !seen[indicator] {
+			seen[indicator] = true
+			indicators = append(indicators, indicator)
+		}
+	}
+	return indicators
+}
+
+func closePrices(dailies []TickerDaily) []float64 {
+	closes := make([]float64, len(dailies))
+	for x := range dailies {
+		closes[x] = dailies[x].ClosePrice
+	}
+	return closes
+}
+
+// a NaN-filled slice, for the stretch before an indicator has enough history
+func nanSlice(length int) []float64 {
+	values := make([]float64, length)
+	for x := range values {
+		values[x] = math.NaN()
+	}
+	return values
+}
+
+func calcSMA(values []float64, period int) []float64 {
+	sma := nanSlice(len(values))
+	var sum float64
+	for x := range values {
+		sum += values[x]
+		if x >= period {
+			sum -= values[x-period]
+		}
+		if x >= period-1 {
+			sma[x] = sum / float64(period)
+		}
+	}
+	return sma
+}
+
+// seeded with the SMA of the first period values; NaNs at the start (as when
+// the EMA of a MACD line is taken) are skipped over
+func calcEMA(values []float64, period int) []float64 {
+	ema := nanSlice(len(values))
+	k := 2 / float64(period+1)
+
+	start := 0
+	for start < len(values) && math.IsNaN(values[start]) {
+		start++
+	}
+	if len(values)-start < period {
+		return ema
+	}
+
+	var sum float64
+	for x := start; x < start+period; x++ {
+		sum += values[x]
+	}
+	ema[start+period-1] = sum / float64(period)
+	for x := start + period; x < len(values); x++ {
+		ema[x] = values[x]*k + ema[x-1]*(1-k)
+	}
+	return ema
+}
+
+// the SMA with bands width standard deviations above and below
+func calcBollinger(values []float64, period int, width float64) ([]float64, []float64, []float64) {
+	middle := calcSMA(values, period)
+	upper := nanSlice(len(values))
+	lower := nanSlice(len(values))
+
+	var sumSquares float64
+	for x := range values {
+		sumSquares += values[x] * values[x]
+		if x >= period {
+			sumSquares -= values[x-period] * values[x-period]
+		}
+		if x >= period-1 {
+			variance := sumSquares/float64(period) - middle[x]*middle[x]
+			deviation := math.Sqrt(math.Max(variance, 0))
+			upper[x] = middle[x] + width*deviation
+			lower[x] = middle[x] - width*deviation
+		}
+	}
+	return middle, upper, lower
+}
+
+// Wilder's RSI, 0 to 100
+func calcRSI(values []float64, period int) []float64 {
+	rsi := nanSlice(len(values))
+	if len(values) <= period {
+		return rsi
+	}
+
+	var gain, loss float64
+	for x := 1; x < len(values); x++ {
+		change := values[x] - values[x-1]
+		up, down := math.Max(change, 0), math.Max(-change, 0)
+		if x <= period {
+			gain += up / float64(period)
+			loss += down / float64(period)
+			if x < period {
+				continue
+			}
+		} else {
+			gain = (gain*float64(period-1) + up) / float64(period)
+			loss = (loss*float64(period-1) + down) / float64(period)
+		}
+		if loss == 0 {
+			rsi[x] = 100
+		} else {
+			rsi[x] = 100 - 100/(1+gain/loss)
+		}
+	}
+	return rsi
+}
+
+// the MACD line, its signal line and the difference between them
+func calcMACD(values []float64, fast, slow, signalPeriod int) ([]float64, []float64, []float64) {
+	fastEMA := calcEMA(values, fast)
+	slowEMA := calcEMA(values, slow)
+
+	macd := nanSlice(len(values))
+	for x := range values {
+		macd[x] = fastEMA[x] - slowEMA[x]
+	}
+	signal := calcEMA(macd, signalPeriod)
+
+	histogram := nanSlice(len(values))
+	for x := range values {
+		histogram[x] = macd[x] - signal[x]
+	}
+	return macd, signal, histogram
+}
+
+// Wilder's average true range
+func calcATR(dailies []TickerDaily, period int) []float64 {
+	atr := nanSlice(len(dailies))
+	if len(dailies) <= period {
+		return atr
+	}
+
+	var sum float64
+	for x := 1; x < len(dailies); x++ {
+		prevClose := dailies[x-1].ClosePrice
+		trueRange := math.Max(dailies[x].HighPrice-dailies[x].LowPrice,
+			math.Max(math.Abs(dailies[x].HighPrice-prevClose), math.Abs(dailies[x].LowPrice-prevClose)))
+		switch {
+		case x < period:
+			sum += trueRange
+		case x == period:
+			atr[x] = (sum + trueRange) / float64(period)
+		default:
+			atr[x] = (atr[x-1]*float64(period-1) + trueRange) / float64(period)
+		}
+	}
+	return atr
+}
+
+// running from the first day on the chart, using each day's typical price
+func calcVWAP(dailies []TickerDaily) []float64 {
+	vwap := nanSlice(len(dailies))
+	var priceVolume, totalVolume float64
+	for x := range dailies {
+		typical := (dailies[x].HighPrice + dailies[x].LowPrice + dailies[x].ClosePrice) / 3
+		priceVolume += typical * float64(dailies[x].Volume)
+		totalVolume += float64(dailies[x].Volume)
+		if totalVolume > 0 {
+			vwap[x] = priceVolume / totalVolume
+		}
+	}
+	return vwap
+}
+
+// on-balance volume, in volumeUnits like the volume pane
+func calcOBV(dailies []TickerDaily) []float64 {
+	obv := make([]float64, len(dailies))
+	for x := 1; x < len(dailies); x++ {
+		volume := float64(dailies[x].Volume) / volumeUnits
+		switch {
+		case dailies[x].ClosePrice > dailies[x-1].ClosePrice:
+			obv[x] = obv[x-1] + volume
+		case dailies[x].ClosePrice < dailies[x-1].ClosePrice:
+			obv[x] = obv[x-1] - volume
+		default:
+			obv[x] = obv[x-1]
+		}
+	}
+	return obv
+}
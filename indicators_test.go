@@ -0,0 +1,161 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+var nan = math.NaN()
+
+// NaN only matches NaN; anything else has to agree to within 0.005
+func equalSeries(got, want []float64) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for x := range want {
+		if math.IsNaN(want[x]) != math.IsNaN(got[x]) {
+			return false
+		}
+		if !math.IsNaN(want[x]) && math.Abs(got[x]-want[x]) > 0.005 {
+			return false
+		}
+	}
+	return true
+}
+
+// the 14-day example from Wilder's book, as worked in most RSI tutorials
+var wilderCloses = []float64{
+	44.34, 44.09, 44.15, 43.61, 44.33, 44.83, 45.10, 45.42, 45.84, 46.08,
+	45.89, 46.03, 45.61, 46.28, 46.28, 46.00, 46.03, 46.41, 46.22, 45.64,
+}
+
+func TestCalcSMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []float64
+	}{
+		{"warm-up then moving", []float64{1, 2, 3, 4, 5}, 3, []float64{nan, nan, 2, 3, 4}},
+		{"period of one", []float64{4, 8, 6}, 1, []float64{4, 8, 6}},
+		{"shorter than period", []float64{1, 2}, 3, []float64{nan, nan}},
+		{"empty", []float64{}, 3, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcSMA(tt.values, tt.period); !equalSeries(got, tt.want) {
+				t.Errorf("calcSMA(%v, %d) = %v, want %v", tt.values, tt.period, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcEMA(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []float64
+	}{
+		// seeded with the SMA of 2, 4, 6, then k = 2/(3+1) = 0.5
+		{"seeded with the SMA", []float64{2, 4, 6, 8, 12}, 3, []float64{nan, nan, 4, 6, 9}},
+		{"leading NaNs skipped", []float64{nan, 2, 4, 6, 8, 12}, 3, []float64{nan, nan, nan, 4, 6, 9}},
+		{"shorter than period", []float64{1, 2}, 3, []float64{nan, nan}},
+		{"all NaN", []float64{nan, nan, nan}, 2, []float64{nan, nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcEMA(tt.values, tt.period); !equalSeries(got, tt.want) {
+				t.Errorf("calcEMA(%v, %d) = %v, want %v", tt.values, tt.period, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcBollinger(t *testing.T) {
+	tests := []struct {
+		name                 string
+		values               []float64
+		period               int
+		width                float64
+		middle, upper, lower []float64
+	}{
+		// mean 5, population standard deviation 2
+		{"textbook deviation", []float64{2, 4, 4, 4, 5, 5, 7, 9}, 8, 2,
+			[]float64{nan, nan, nan, nan, nan, nan, nan, 5},
+			[]float64{nan, nan, nan, nan, nan, nan, nan, 9},
+			[]float64{nan, nan, nan, nan, nan, nan, nan, 1}},
+		{"rolling window", []float64{1, 3, 3, 3}, 2, 1,
+			[]float64{nan, 2, 3, 3},
+			[]float64{nan, 3, 3, 3},
+			[]float64{nan, 1, 3, 3}},
+		{"shorter than period", []float64{1, 2}, 3, 2,
+			[]float64{nan, nan}, []float64{nan, nan}, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middle, upper, lower := calcBollinger(tt.values, tt.period, tt.width)
+			if !equalSeries(middle, tt.middle) || !equalSeries(upper, tt.upper) || !equalSeries(lower, tt.lower) {
+				t.Errorf("calcBollinger(%v, %d, %g) = %v, %v, %v, want %v, %v, %v",
+					tt.values, tt.period, tt.width, middle, upper, lower, tt.middle, tt.upper, tt.lower)
+			}
+		})
+	}
+}
+
+func TestCalcRSI(t *testing.T) {
+	wilderWant := nanSlice(len(wilderCloses))
+	copy(wilderWant[14:], []float64{70.46, 66.25, 66.48, 69.35, 66.29, 57.92})
+
+	tests := []struct {
+		name   string
+		values []float64
+		period int
+		want   []float64
+	}{
+		{"wilder's example", wilderCloses, 14, wilderWant},
+		// first averages 0.5/0.5, then (0.5+1)/2 over (0.5+0)/2
+		{"smoothed averages", []float64{1, 2, 1, 2}, 2, []float64{nan, nan, 50, 75}},
+		{"no losses", []float64{1, 2, 3}, 2, []float64{nan, nan, 100}},
+		{"no gains", []float64{3, 2, 1}, 2, []float64{nan, nan, 0}},
+		{"not past the period", []float64{1, 2}, 2, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := calcRSI(tt.values, tt.period); !equalSeries(got, tt.want) {
+				t.Errorf("calcRSI(%v, %d) = %v, want %v", tt.values, tt.period, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcMACD(t *testing.T) {
+	tests := []struct {
+		name                    string
+		values                  []float64
+		fast, slow, signal      int
+		macd, signalLine, histo []float64
+	}{
+		// EMA2 is 3, 5, 7, 31/3 and EMA3 is 4, 6, 9; the signal is the EMA2 of
+		// the MACD line from where it starts
+		{"small periods", []float64{2, 4, 6, 8, 12}, 2, 3, 2,
+			[]float64{nan, nan, 1, 1, 4.0 / 3},
+			[]float64{nan, nan, nan, 1, 11.0 / 9},
+			[]float64{nan, nan, nan, 0, 1.0 / 9}},
+		{"flat prices", []float64{5, 5, 5, 5, 5}, 2, 3, 2,
+			[]float64{nan, nan, 0, 0, 0},
+			[]float64{nan, nan, nan, 0, 0},
+			[]float64{nan, nan, nan, 0, 0}},
+		{"shorter than the slow period", []float64{1, 2}, 2, 3, 2,
+			[]float64{nan, nan}, []float64{nan, nan}, []float64{nan, nan}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			macd, signal, histogram := calcMACD(tt.values, tt.fast, tt.slow, tt.signal)
+			if !equalSeries(macd, tt.macd) || !equalSeries(signal, tt.signalLine) || !equalSeries(histogram, tt.histo) {
+				t.Errorf("calcMACD(%v, %d, %d, %d) = %v, %v, %v, want %v, %v, %v",
+					tt.values, tt.fast, tt.slow, tt.signal, macd, signal, histogram, tt.macd, tt.signalLine, tt.histo)
+			}
+		})
+	}
+}
@@ -6,11 +6,7 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
 	"time"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
 )
 
 func pingHandler() http.HandlerFunc {
@@ -20,9 +16,7 @@ func pingHandler() http.HandlerFunc {
 
 func JSONReportHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		sublog := deps.logger
-		awssess := deps.awssess
-		s3svc := s3.New(awssess)
+		sublog := getRequestContext(r).logger
 
 		currentMonth := time.Now().Format("2006-01")
 
@@ -33,15 +27,9 @@ func JSONReportHandler(deps *Dependencies) http.HandlerFunc {
 		io.WriteString(sha1Hash, cspReport)
 		logKey := fmt.Sprintf("csp-violations/%s/%x", currentMonth, string(sha1Hash.Sum(nil)))
 
-		inputPutObj := &s3.PutObjectInput{
-			Body:   aws.ReadSeekCloser(strings.NewReader(cspReport)),
-			Bucket: aws.String(awsPrivateBucketName),
-			Key:    aws.String(logKey),
-		}
-
-		_, err := s3svc.PutObject(inputPutObj)
+		err := deps.blobs.Put(logKey, b)
 		if err != nil {
-			sublog.Warn().Err(err).Str("bucket", awsPrivateBucketName).Str("key", logKey).Msg("failed to upload to S3 bucket")
+			sublog.Warn().Err(err).Str("key", logKey).Msg("failed to save CSP report")
 		}
 	})
 }
@@ -20,12 +20,17 @@ type LastDone struct {
 
 // object methods -------------------------------------------------------------
 func (ld *LastDone) getByActivity(deps *Dependencies) error {
-	db := deps.db
-
-	err := db.QueryRowx("SELECT * FROM lastdone WHERE activity=? AND unique_key=?", ld.Activity, ld.UniqueKey).StructScan(ld)
+	lastdone, err := deps.lastdone.GetLastDone(ld.Activity, ld.UniqueKey)
+	if err == nil {
+		*ld = lastdone
+	}
 	return err
 }
 
+func (ld *LastDone) createOrUpdate(deps *Dependencies) error {
+	return deps.lastdone.SaveLastDone(*ld)
+}
+
 // misc -----------------------------------------------------------------------
 func getLastDoneInfo(deps *Dependencies, sublog zerolog.Logger, task string, key string) (sql.NullTime, string, bool) {
 	sublog = sublog.With().Str("task", task).Logger()
@@ -48,7 +53,7 @@ func getLastDoneInfo(deps *Dependencies, sublog zerolog.Logger, task string, key
 		lastSuccessSince = fmt.Sprintf("%.0f min ago", time.Since(lastSuccessDatetime.Time).Minutes())
 	}
 	if lastdone.LastStatus == "success" {
-		if lastdone.LastDoneDatetime.Time.Add(time.Minute * minTickerNewsDelay).Before(time.Now()) {
+		if lastdone.LastDoneDatetime.Time.Add(time.Minute * time.Duration(deps.config.TickerNewsDelay)).Before(time.Now()) {
 			return lastSuccessDatetime, lastSuccessSince, runningTaskNow
 		} else {
 			return lastSuccessDatetime, lastSuccessSince, false
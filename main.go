@@ -1,10 +1,12 @@
 package main
 
-const (
-	httpPort = 3001
+import (
+	"context"
+	"os"
+)
 
-	awsRegion            = "us-east-1"
-	awsPrivateBucketName = "stockwatch-private"
+const (
+	// deployment settings (port, site URL, storage, delays...) live in Config
 
 	skipRedisChecks     = false // always skip the redis cache info
 	skipLocalTickerInfo = false // always fetch ticker info from yhfinance
@@ -16,26 +18,77 @@ const (
 
 	zoneDir = "/usr/share/zoneinfo/"
 
-	minTickerReloadDelayOpen   = 1       //  1 minute
-	minTickerReloadDelayClosed = 60 * 1  //  1 hour
-	minTickerNewsDelay         = 60 * 1  //  1 hour
-	minTickerFinancialsDelay   = 60 * 24 // 24 hours
+	alertQuoteBatchSize  = 50 // symbols per multi-quote call
+	defaultAlertCooldown = 60 // minutes before a triggered alert can re-arm
+	alertAvgVolumeDays   = 50 // trading days in the average a volume alert compares against
+
+	volumeUnits         = 1_000_000 // factor to reduce volume counts by when graphing
+	intradayVolumeUnits = 1_000     // the same, for the much smaller volume between intraday samples
+
+	shutdownDrainDelay = 10 // seconds /readyz says "shutting down" before we stop taking connections
+	shutdownTimeout    = 30 // seconds to let in-flight requests finish on SIGTERM
+	readyCheckTimeout  = 5  // seconds for all of the /readyz dependency checks
+
+	workerRetryBackoff = 2       // seconds before the first retry of a ticker task, doubling after that
+	workerIdleWait     = 1       // seconds to wait before polling an empty local queue again
+	maxFavIconBytes    = 100_000 // anything bigger isn't a favicon
+	taskDedupeWindow   = 300     // seconds a queued task swallows repeats of itself
+
+	schedulerInterval = 60 // seconds between checks for due jobs
+	jobLockTimeout    = 60 // minutes before a job that never finished can run again
+	jobRetryDelay     = 15 // minutes before a failed job is retried
 
-	volumeUnits    = 1_000_000 // factor to reduce volume counts by when graphing
-	maxRecentCount = 6         // limit watcher_recents
-	debugging      = true      // output DEBUG level logs
+	calendarSearchDays = 14 // days to look ahead or back for a session before giving up
+	eodSettleDelay     = 30 // minutes after the close before the day's EODs are final
 
+	maxCompareSymbols  = 8    // tickers on one comparison chart, not counting the benchmark
+	tradingDaysPerYear = 252  // for annualizing volatility
+	maxIndicatorPeriod = 200  // longest lookback a chart indicator can ask for
+	maxDailyBarDays    = 400  // longest range charted in daily bars unless asked for
+	maxWeeklyBarDays   = 1830 // the same for weekly bars, monthly past that
+	minZoomSliderBars  = 60   // fewer bars than this on a chart get no zoom slider
+
+	maxWatchlists          = 20  // named lists per watcher, the recents aren't counted
+	maxWatchlistSymbols    = 100 // tickers on one watchlist
+	maxWatchlistNameLength = 40
+	maxWatchlistNoteLength = 200
+	watchlistShareTokenLen = 16 // random bytes in a share link, before base64url encoding
+
+	maxImportBytes        = 1 << 20 // largest file we'll take for an import
+	maxImportRows         = 1000    // rows in one import
+	maxImportHeaderSearch = 10      // lines to look through for the header row of a broker export
+
+	maxXIRRIterations = 100    // steps to look for a money-weighted return before giving up
+	xirrTolerance     = 0.0001 // dollars of net present value close enough to zero
 )
 
 func main() {
 	deps := &Dependencies{}
 
 	setupLogging(deps)
-	setupAWS(deps)
-	setupSecrets(deps)
+	setupConfig(deps)
+	setupStorage(deps)
+	setupRedis(deps)
+	setupTaskQueue(deps)
+	setupMarketData(deps)
+
+	// `stockwatch worker` only works the ticker queue, no web server
+	if len(os.Args) > 1 && os.Args[1] == "worker" {
+		startWorker(deps)
+		return
+	}
+
 	setupSessionStore(deps)
 	setupOAuth(deps)
 	setupTemplates(deps)
 
+	// nothing outside this process can see the local queue, so work it here,
+	// and with no worker service the scheduler and alerts have to live here too
+	if deps.config.StorageBackend == "local" {
+		go runWorker(deps, context.Background())
+		go runScheduler(deps, context.Background())
+		go runAlertEvaluator(deps, context.Background())
+	}
+
 	startServer(deps)
 }
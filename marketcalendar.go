@@ -0,0 +1,271 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// MarketCalendar is when one exchange trades: its regular session in its own
+// time zone, plus the holidays and early closes that come out of the
+// exchange's published calendar. Times are "1504" strings, like the rest of
+// our market-hours code
+type MarketCalendar struct {
+	TZ          string
+	PreOpen     string // extended-hours trading before Open, if the exchange has it
+	Open        string
+	Close       string
+	PostClose   string          // extended-hours trading after Close, if the exchange has it
+	EarlyClose  string          // close on EarlyCloses days
+	EarlyPost   string          // end of extended hours on EarlyCloses days
+	LunchStart  string          // a midday break, if the exchange takes one
+	LunchEnd    string          //
+	Holidays    map[string]bool // "2006-01-02", no trading at all
+	EarlyCloses map[string]bool // "2006-01-02", trading ends at EarlyClose
+}
+
+// the hand-maintained holiday tables only run a year or so ahead; add the
+// next year's dates when the exchanges publish them
+var nyseCalendar = MarketCalendar{
+	TZ:         "America/New_York",
+	PreOpen:    "0400",
+	Open:       "0930",
+	Close:      "1600",
+	PostClose:  "2000",
+	EarlyClose: "1300",
+	EarlyPost:  "1700",
+	Holidays: dateSet(
+		"2025-01-01", "2025-01-09", "2025-01-20", "2025-02-17", "2025-04-18", "2025-05-26", "2025-06-19", "2025-07-04", "2025-09-01", "2025-11-27", "2025-12-25",
+		"2026-01-01", "2026-01-19", "2026-02-16", "2026-04-03", "2026-05-25", "2026-06-19", "2026-07-03", "2026-09-07", "2026-11-26", "2026-12-25",
+		"2027-01-01", "2027-01-18", "2027-02-15", "2027-03-26", "2027-05-31", "2027-06-18", "2027-07-05", "2027-09-06", "2027-11-25", "2027-12-24",
+	),
+	EarlyCloses: dateSet(
+		"2025-07-03", "2025-11-28", "2025-12-24",
+		"2026-11-27", "2026-12-24",
+		"2027-11-26",
+	),
+}
+
+var lseCalendar = MarketCalendar{
+	TZ:         "Europe/London",
+	Open:       "0800",
+	Close:      "1630",
+	EarlyClose: "1230",
+	Holidays: dateSet(
+		"2025-01-01", "2025-04-18", "2025-04-21", "2025-05-05", "2025-05-26", "2025-08-25", "2025-12-25", "2025-12-26",
+		"2026-01-01", "2026-04-03", "2026-04-06", "2026-05-04", "2026-05-25", "2026-08-31", "2026-12-25", "2026-12-28",
+		"2027-01-01", "2027-03-26", "2027-03-29", "2027-05-03", "2027-05-31", "2027-08-30", "2027-12-27", "2027-12-28",
+	),
+	EarlyCloses: dateSet(
+		"2025-12-24", "2025-12-31",
+		"2026-12-24", "2026-12-31",
+		"2027-12-24", "2027-12-31",
+	),
+}
+
+var tseCalendar = MarketCalendar{
+	TZ:         "Asia/Tokyo",
+	Open:       "0900",
+	Close:      "1530",
+	LunchStart: "1130",
+	LunchEnd:   "1230",
+	Holidays: dateSet(
+		"2025-01-01", "2025-01-02", "2025-01-03", "2025-01-13", "2025-02-11", "2025-02-24", "2025-03-20", "2025-04-29", "2025-05-05", "2025-05-06",
+		"2025-07-21", "2025-08-11", "2025-09-15", "2025-09-23", "2025-10-13", "2025-11-03", "2025-11-24", "2025-12-31",
+		"2026-01-01", "2026-01-02", "2026-01-12", "2026-02-11", "2026-02-23", "2026-03-20", "2026-04-29", "2026-05-04", "2026-05-05", "2026-05-06",
+		"2026-07-20", "2026-08-11", "2026-09-21", "2026-09-22", "2026-09-23", "2026-10-12", "2026-11-03", "2026-11-23", "2026-12-31",
+		"2027-01-01", "2027-01-11", "2027-02-11", "2027-02-23", "2027-03-22", "2027-04-29", "2027-05-03", "2027-05-04", "2027-05-05",
+		"2027-07-19", "2027-08-11", "2027-09-20", "2027-09-23", "2027-10-11", "2027-11-03", "2027-11-23", "2027-12-31",
+	),
+}
+
+// calendars by exchange or operating MIC; every US venue follows the NYSE
+// holiday schedule
+var marketCalendars = map[string]MarketCalendar{
+	"XNYS": nyseCalendar,
+	"XASE": nyseCalendar,
+	"ARCX": nyseCalendar,
+	"XNAS": nyseCalendar,
+	"XNGS": nyseCalendar,
+	"XNMS": nyseCalendar,
+	"XNCM": nyseCalendar,
+	"BATS": nyseCalendar,
+	"XLON": lseCalendar,
+	"XJPX": tseCalendar,
+	"XTKS": tseCalendar,
+}
+
+// where a MarketCalendar puts a moment in time
+const (
+	sessionPre     = "pre"
+	sessionRegular = "regular"
+	sessionPost    = "post"
+	sessionClosed  = "closed"
+)
+
+// the market the header's TRADING/CLOSED badge and the scheduled jobs follow
+var homeExchange = Exchange{ExchangeMic: "XNYS", OperatingMic: "XNYS", ExchangeTZ: "America/New_York"}
+
+// *time.Location by MarketCalendar.TZ, so the zoneinfo is only read once
+var calendarLocations sync.Map
+
+// object methods -------------------------------------------------------------
+
+func (c MarketCalendar) location() *time.Location {
+	if location, ok := calendarLocations.Load(c.TZ); ok {
+		return location.(*time.Location)
+	}
+	location, err := time.LoadLocation(c.TZ)
+	if err != nil {
+		location = time.UTC
+	}
+	calendarLocations.Store(c.TZ, location)
+	return location
+}
+
+// hhmm on the same calendar day as day, in the exchange's time zone
+func (c MarketCalendar) at(day time.Time, hhmm string) time.Time {
+	clock, _ := time.Parse("1504", hhmm)
+	return time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, day.Location())
+}
+
+func (c MarketCalendar) isTradingDay(day time.Time) bool {
+	day = day.In(c.location())
+	weekday := day.Weekday()
+	return weekday != time.Saturday && weekday != time.Sunday && !c.Holidays[day.Format(sqlDateParseType)]
+}
+
+// each [open, close) stretch of trading on the exchange's calendar day that
+// contains day; none on a weekend or holiday
+func (c MarketCalendar) sessions(day time.Time) [][2]time.Time {
+	day = day.In(c.location())
+	if !c.isTradingDay(day) {
+		return nil
+	}
+
+	closeStr := c.Close
+	if c.EarlyCloses[day.Format(sqlDateParseType)] && c.EarlyClose != "" {
+		closeStr = c.EarlyClose
+	}
+	open, closeAt := c.at(day, c.Open), c.at(day, closeStr)
+
+	if c.LunchStart != "" && c.LunchStart < closeStr {
+		return [][2]time.Time{{open, c.at(day, c.LunchStart)}, {c.at(day, c.LunchEnd), closeAt}}
+	}
+	return [][2]time.Time{{open, closeAt}}
+}
+
+// the sessions of the trading day t falls on or, before that day's open or on
+// a day off, those of the last trading day
+func (c MarketCalendar) latestSessions(t time.Time) [][2]time.Time {
+	sessions := c.sessions(t)
+	if len(sessions) == 0 || t.Before(sessions[0][0]) {
+		sessions = c.sessions(c.lastClose(t))
+	}
+	return sessions
+}
+
+func (c MarketCalendar) isOpen(t time.Time) bool {
+	for _, session := range c.sessions(t) {
+		if !t.Before(session[0]) && t.Before(session[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// which session t falls in; a lunch break is closed, and so is the gap between
+// the close and the end of a shorter extended session
+func (c MarketCalendar) session(t time.Time) string {
+	sessions := c.sessions(t)
+	if len(sessions) == 0 {
+		return sessionClosed
+	}
+	if c.isOpen(t) {
+		return sessionRegular
+	}
+
+	day := t.In(c.location())
+	if c.PreOpen != "" && !t.Before(c.at(day, c.PreOpen)) && t.Before(sessions[0][0]) {
+		return sessionPre
+	}
+	postClose := c.PostClose
+	if c.EarlyCloses[day.Format(sqlDateParseType)] && c.EarlyPost != "" {
+		postClose = c.EarlyPost
+	}
+	if postClose != "" && !t.Before(sessions[len(sessions)-1][1]) && t.Before(c.at(day, postClose)) {
+		return sessionPost
+	}
+	return sessionClosed
+}
+
+// when trading next starts at or after t, including coming back from lunch
+func (c MarketCalendar) nextOpen(t time.Time) time.Time {
+	day := t.In(c.location())
+	for i := 0; i < calendarSearchDays; i++ {
+		for _, session := range c.sessions(day) {
+			if !session[0].Before(t) {
+				return session[0]
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, day.Location())
+	}
+	return time.Time{}
+}
+
+// the end of the last full trading day at or before t; a lunch break
+// doesn't count as a close
+func (c MarketCalendar) lastClose(t time.Time) time.Time {
+	day := t.In(c.location())
+	for i := 0; i < calendarSearchDays; i++ {
+		sessions := c.sessions(day)
+		if len(sessions) > 0 {
+			closeAt := sessions[len(sessions)-1][1]
+			if !closeAt.After(t) {
+				return closeAt
+			}
+		}
+		day = time.Date(day.Year(), day.Month(), day.Day()-1, 12, 0, 0, 0, day.Location())
+	}
+	return time.Time{}
+}
+
+// misc -----------------------------------------------------------------------
+
+// an exchange we have no calendar for still gets its own time zone and
+// weekdays, with US-style hours; with no time zone either it gets NYSE
+func getMarketCalendar(exchange Exchange) MarketCalendar {
+	if calendar, ok := marketCalendars[exchange.ExchangeMic]; ok {
+		return calendar
+	}
+	if calendar, ok := marketCalendars[exchange.OperatingMic]; ok {
+		return calendar
+	}
+	if exchange.ExchangeTZ != "" {
+		return MarketCalendar{TZ: exchange.ExchangeTZ, Open: nyseCalendar.Open, Close: nyseCalendar.Close}
+	}
+	return nyseCalendar
+}
+
+func isMarketOpen(exchange Exchange) bool {
+	return getMarketCalendar(exchange).isOpen(time.Now())
+}
+
+func marketSession(exchange Exchange) string {
+	return getMarketCalendar(exchange).session(time.Now())
+}
+
+func nextOpen(exchange Exchange) time.Time {
+	return getMarketCalendar(exchange).nextOpen(time.Now())
+}
+
+func lastClose(exchange Exchange) time.Time {
+	return getMarketCalendar(exchange).lastClose(time.Now())
+}
+
+func dateSet(dates ...string) map[string]bool {
+	set := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		set[date] = true
+	}
+	return set
+}
@@ -0,0 +1,571 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/weirdtangent/yhfinance"
+)
+
+// MarketDataProvider is where quotes, company info, price history and search
+// come from. Responses use the yhfinance shapes the rest of the code already
+// knows, except price history which is vendor-neutral and quotes, which carry
+// extended-hours trading on top of the yhfinance quote
+type MarketDataProvider interface {
+	Name() string
+	GetSummary(deps *Dependencies, sublog zerolog.Logger, symbol string) (yhfinance.YHStockSummaryResponse, error)
+	GetQuote(deps *Dependencies, sublog zerolog.Logger, symbol, region string) (Quote, error)
+	GetQuotes(deps *Dependencies, sublog zerolog.Logger, symbols []string) (map[string]Quote, error)
+	GetHistorical(deps *Dependencies, sublog zerolog.Logger, symbol string) (HistoricalData, error)
+	GetNews(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]NewsItem, error) // an empty symbol is general market news
+	GetFinancials(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]FinancialsValue, error)
+	GetMovers(deps *Dependencies, sublog zerolog.Logger) (map[string][]string, error) // "gainer", "loser" and "active" symbols, best first
+	Search(deps *Dependencies, sublog zerolog.Logger, searchString string) (yhfinance.YHAutoCompleteResponse, error)
+}
+
+// Quote is a yhfinance quote plus the pre-market and after-hours trading the
+// yhfinance package doesn't model; a zero price means there was none
+type Quote struct {
+	yhfinance.YHQuote
+	PreMarketPrice      float64
+	PreMarketChange     float64
+	PreMarketChangePct  float64
+	PreMarketTime       time.Time
+	PostMarketPrice     float64
+	PostMarketChange    float64
+	PostMarketChangePct float64
+	PostMarketTime      time.Time
+}
+
+// ExtendedHours is the latest pre-market or after-hours trade, for whichever
+// of those sessions the exchange is in
+type ExtendedHours struct {
+	Session   string
+	Price     float64
+	ChangeAmt float32
+	ChangePct float32
+	Time      time.Time
+}
+
+type HistoricalPrice struct {
+	Date   time.Time
+	Open   float64
+	High   float64
+	Low    float64
+	Close  float64
+	Volume int64
+}
+
+type HistoricalSplit struct {
+	Date       time.Time
+	SplitRatio string
+}
+
+type HistoricalDividend struct {
+	Date   time.Time
+	Amount float64
+}
+
+type HistoricalData struct {
+	Prices    []HistoricalPrice
+	Splits    []HistoricalSplit
+	Dividends []HistoricalDividend
+}
+
+type NewsItem struct {
+	ExternalId string
+	Publisher  string
+	Title      string
+	Summary    string
+	URL        string
+	ImageURL   string
+	Published  time.Time
+	Symbols    []string
+}
+
+// one value from one period of a company's statements, shaped the way the
+// financials table and charts want it: e.g. the Quarterly bar "Revenue" for
+// the quarter ending 2022-03-31
+type FinancialsValue struct {
+	Term         string // "Quarterly" or "Annual"
+	ChartType    string // "bar" or "line"
+	IsPercentage bool
+	Name         string
+	Date         time.Time
+	Value        float64
+}
+
+// providers by the name used for Config.MarketDataProvider
+var marketDataProviders = map[string]func(deps *Dependencies) MarketDataProvider{
+	"yhfinance": newYHFinanceProvider,
+	"fixtures":  newFixtureProvider,
+}
+
+// object methods -------------------------------------------------------------
+
+// the extended-hours trade for session, if session is pre or post and there
+// has been one
+func (q Quote) extendedHours(session string) ExtendedHours {
+	switch {
+	case session == sessionPre && q.PreMarketPrice > 0:
+		return ExtendedHours{session, q.PreMarketPrice, float32(q.PreMarketChange), float32(q.PreMarketChangePct), q.PreMarketTime}
+	case session == sessionPost && q.PostMarketPrice > 0:
+		return ExtendedHours{session, q.PostMarketPrice, float32(q.PostMarketChange), float32(q.PostMarketChangePct), q.PostMarketTime}
+	}
+	return ExtendedHours{Session: session}
+}
+
+// misc -----------------------------------------------------------------------
+
+func setupMarketData(deps *Dependencies) {
+	sublog := deps.logger
+	providerName := deps.config.MarketDataProvider
+
+	newProvider, ok := marketDataProviders[providerName]
+	if !ok {
+		sublog.Fatal().Str("provider", providerName).Msg("unknown market data provider")
+	}
+	deps.marketData = newProvider(deps)
+	sublog.Info().Str("provider", providerName).Msg("market data provider selected")
+}
+
+// fetch ticker info (and possibly new exchange) from the market data provider
+func fetchTickerInfo(deps *Dependencies, sublog zerolog.Logger, symbol string) (Ticker, error) {
+	marketData := deps.marketData
+	sublog = sublog.With().Str("symbol", symbol).Str("provider", marketData.Name()).Logger()
+
+	summaryResponse, err := marketData.GetSummary(deps, sublog, symbol)
+	if err != nil {
+		return Ticker{}, err
+	}
+
+	// can't create exchange - all we get is ExchangeCode and I can't find a
+	// table to translate those to Exchange MIC or Acronym... so I have to
+	// link them manually for now
+	exchange := Exchange{ExchangeCode: summaryResponse.Price.ExchangeCode}
+	err = exchange.getByCode(deps, sublog)
+	if err != nil {
+		sublog.Error().Err(err).Str("ticker", summaryResponse.QuoteType.Symbol).Str("exchange_code", summaryResponse.Price.ExchangeCode).Msg("failed to find exchange_code matched to exchange mic record")
+		return Ticker{}, err
+	}
+
+	// create/update ticker
+	ticker := Ticker{
+		0,
+		"",
+		summaryResponse.QuoteType.Symbol,
+		summaryResponse.Price.QuoteType,
+		summaryResponse.QuoteType.Market,
+		exchange.ExchangeId,
+		summaryResponse.QuoteType.ShortName,
+		summaryResponse.QuoteType.LongName,
+		summaryResponse.SummaryProfile.Address1,
+		summaryResponse.SummaryProfile.City,
+		summaryResponse.SummaryProfile.State,
+		summaryResponse.SummaryProfile.Zip,
+		summaryResponse.SummaryProfile.Country,
+		summaryResponse.SummaryProfile.Website,
+		summaryResponse.SummaryProfile.Phone,
+		summaryResponse.SummaryProfile.Sector,
+		summaryResponse.SummaryProfile.Industry,
+		summaryResponse.Price.RegularMarketPrice.Raw,
+		summaryResponse.Price.RegularMarketPreviousClose.Raw,
+		summaryResponse.Price.RegularMarketVolume.Raw,
+		time.Unix(summaryResponse.Price.RegularMarketTime, 0),
+		"",
+		time.Now(),
+		"",
+		time.Now(),
+		time.Now(),
+	}
+	err = ticker.createOrUpdate(deps, sublog)
+	if err != nil {
+		sublog.Error().Err(err).Str("symbol", ticker.TickerSymbol).Msg("failed to create or update ticker")
+		return Ticker{}, err
+	}
+	if ticker.TickerSymbol == "" || ticker.TickerId == 0 {
+		sublog.Fatal().Interface("ticker", ticker).Str("quotetype_symbol", summaryResponse.QuoteType.Symbol).Msg("ticker object is not saved")
+	}
+
+	tickerDescription := TickerDescription{0, "", ticker.TickerId, summaryResponse.SummaryProfile.LongBusinessSummary, time.Now(), time.Now()}
+	if len(summaryResponse.SummaryProfile.LongBusinessSummary) > 0 {
+		tickerDescription.BusinessSummary = summaryResponse.SummaryProfile.LongBusinessSummary
+	} else if len(summaryResponse.AssetProfile.LongBusinessSummary) > 0 {
+		tickerDescription.BusinessSummary = summaryResponse.AssetProfile.LongBusinessSummary
+	}
+	err = tickerDescription.createOrUpdate(deps, sublog)
+	if err != nil {
+		sublog.Error().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to create ticker description")
+	}
+
+	// create upgrade/downgrade recommendations
+	for _, updown := range summaryResponse.UpgradeDowngradeHistory.Histories {
+		updownDate := time.Unix(updown.GradeDate, 0)
+		UpDown := TickerUpDown{0, "", ticker.TickerId, updown.Action, updown.FromGrade, updown.ToGrade, sql.NullTime{Valid: true, Time: updownDate}, updown.Firm, "", time.Now(), time.Now()}
+		UpDown.createIfNew(deps, sublog)
+	}
+
+	// create/update ticker_attributes
+	ticker.createOrUpdateAttribute(deps, sublog, "sector", "", summaryResponse.SummaryProfile.Sector)
+	ticker.createOrUpdateAttribute(deps, sublog, "industry", "", summaryResponse.SummaryProfile.Industry)
+	ticker.createOrUpdateAttribute(deps, sublog, "short_ratio", "", summaryResponse.DefaultKeyStatistics.ShortRatio.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "last_split_date", "", summaryResponse.DefaultKeyStatistics.LastSplitDate.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "last_dividend_date", "", summaryResponse.DefaultKeyStatistics.LastDividendDate.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "shares_short", "", summaryResponse.DefaultKeyStatistics.SharesShort.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "float_shares", "", summaryResponse.DefaultKeyStatistics.FloatShares.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "forward_eps", "", summaryResponse.DefaultKeyStatistics.ForwardEPS.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "enterprize_to_revenue", "", summaryResponse.DefaultKeyStatistics.EnterprizeToRevenue.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "enterprize_to_ebita", "", summaryResponse.DefaultKeyStatistics.EnterprizeToEbita.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "held_percent_insiders", "", summaryResponse.DefaultKeyStatistics.HeldPercentInsiders.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "shares_outstanding", "", summaryResponse.DefaultKeyStatistics.SharesOutstanding.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "fund_inception_date", "", summaryResponse.DefaultKeyStatistics.FundInceptionDate.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "total_assets", "", summaryResponse.DefaultKeyStatistics.TotalAssets.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "fund_family", "", summaryResponse.DefaultKeyStatistics.FundFamily)
+	ticker.createOrUpdateAttribute(deps, sublog, "price_hint", "", summaryResponse.DefaultKeyStatistics.PriceHint.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "ytd_return", "", summaryResponse.DefaultKeyStatistics.YtdReturn.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "three_year_avg_return", "", summaryResponse.DefaultKeyStatistics.ThreeYearAverageReturn.Fmt)
+	ticker.createOrUpdateAttribute(deps, sublog, "five_year_avg_return", "", summaryResponse.DefaultKeyStatistics.FiveYearAverageReturn.Fmt)
+
+	return ticker, nil
+}
+
+// load ticker up-to-date quote
+func fetchTickerQuote(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) (Quote, error) {
+	marketData := deps.marketData
+
+	return marketData.GetQuote(deps, sublog, ticker.TickerSymbol, ticker.TickerMarket)
+}
+
+func loadMultiTickerQuotes(deps *Dependencies, sublog zerolog.Logger, symbols []string) (map[string]Quote, error) {
+	marketData := deps.marketData
+
+	start := time.Now()
+	sublog.Info().Str("symbols", strings.Join(symbols, ",")).Str("provider", marketData.Name()).Msg("getting multi-symbol quote")
+	quotes, err := marketData.GetQuotes(deps, sublog, symbols)
+	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: multi-symbol quote")
+	if err != nil {
+		sublog.Warn().Err(err).Str("symbols", strings.Join(symbols, ",")).Msg("failed to retrieve quote")
+	}
+	return quotes, err
+}
+
+// load ticker historical prices
+func fetchTickerEODs(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) error {
+	marketData := deps.marketData
+
+	historical, err := marketData.GetHistorical(deps, sublog, ticker.TickerSymbol)
+	if err != nil {
+		sublog.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to retrieve historical prices")
+		return err
+	}
+
+	// the splits go in first: the provider's prices are already restated in
+	// today's shares, and we store them as they traded
+	knownSplits, _ := ticker.getSplits(deps, sublog)
+	var lastErr error
+	for _, split := range historical.Splits {
+		tickerSplit := TickerSplit{0, "", ticker.TickerId, split.Date, split.SplitRatio, time.Now(), time.Now()}
+		err = tickerSplit.createIfNew(deps, sublog)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		sublog.Warn().Err(lastErr).Str("ticker", ticker.TickerSymbol).Msg("failed to load at least one historical split")
+	}
+	splits, err := ticker.getSplits(deps, sublog)
+	if err != nil {
+		sublog.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to get splits, not loading historical prices")
+		return err
+	}
+	if len(splits) != len(knownSplits) {
+		recalculateTickerHoldings(deps, sublog, ticker)
+	}
+
+	lastErr = nil
+	for _, price := range historical.Prices {
+		tickerDaily := TickerDaily{0, "", ticker.TickerId, price.Date, price.Open, price.High, price.Low, price.Close, price.Volume, time.Now(), time.Now()}
+		tickerDaily = unadjustDailyForSplits(sublog, tickerDaily, splits)
+		err = tickerDaily.createOrUpdate(deps, sublog)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		sublog.Warn().Err(lastErr).Str("ticker", ticker.TickerSymbol).Msg("failed to load at least one historical price")
+	}
+
+	// the provider restates dividends in today's shares too; what a holding
+	// was paid is its shares then times the amount per share then
+	lastErr = nil
+	for _, dividend := range historical.Dividends {
+		amount := dividend.Amount * splitFactorSince(sublog, splits, dividend.Date)
+		tickerDividend := TickerDividend{0, "", ticker.TickerId, dividend.Date, amount, time.Now(), time.Now()}
+		err = tickerDividend.createOrUpdate(deps, sublog)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	if lastErr != nil {
+		sublog.Warn().Err(lastErr).Str("ticker", ticker.TickerSymbol).Msg("failed to load at least one historical dividend")
+	}
+
+	return nil
+}
+
+// load recent news articles that mention the ticker
+func fetchTickerNews(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) error {
+	marketData := deps.marketData
+
+	news, err := marketData.GetNews(deps, sublog, ticker.TickerSymbol)
+	if err != nil {
+		sublog.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to retrieve news")
+		return err
+	}
+
+	// we asked for this ticker's news, so it is about this ticker even if the
+	// provider didn't tag it
+	for i := range news {
+		if !slices.Contains(news[i].Symbols, ticker.TickerSymbol) {
+			news[i].Symbols = append(news[i].Symbols, ticker.TickerSymbol)
+		}
+	}
+
+	return saveNewsItems(deps, sublog.With().Str("ticker", ticker.TickerSymbol).Logger(), news)
+}
+
+// general market news, shown on the desktop
+func fetchMarketNews(deps *Dependencies, sublog zerolog.Logger) error {
+	marketData := deps.marketData
+
+	news, err := marketData.GetNews(deps, sublog, "")
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to retrieve market news")
+		return err
+	}
+
+	return saveNewsItems(deps, sublog, news)
+}
+
+func saveNewsItems(deps *Dependencies, sublog zerolog.Logger, news []NewsItem) error {
+	var lastErr error
+	saved := 0
+	for _, item := range news {
+		isNew, err := saveNewsItem(deps, sublog, item)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if isNew {
+			saved++
+		}
+	}
+	if lastErr != nil {
+		sublog.Warn().Err(lastErr).Msg("failed to save at least one news article")
+	}
+	sublog.Info().Int("articles", len(news)).Int("new_articles", saved).Msg("loaded news")
+
+	return lastErr
+}
+
+// load quarterly and annual financials, replacing what we had for the ticker
+func fetchTickerFinancials(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) error {
+	marketData := deps.marketData
+
+	values, err := marketData.GetFinancials(deps, sublog, ticker.TickerSymbol)
+	if err != nil {
+		sublog.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to retrieve financials")
+		return err
+	}
+
+	financials := make([]Financials, 0, len(values))
+	for _, value := range values {
+		financials = append(financials, Financials{
+			TickerId:      ticker.TickerId,
+			FormTermName:  value.Term,
+			ChartName:     value.Name,
+			ChartDatetime: sql.NullTime{Valid: true, Time: value.Date},
+			ChartType:     value.ChartType,
+			IsPercentage:  value.IsPercentage,
+			ChartValue:    value.Value,
+		})
+	}
+
+	return deps.tickers.ReplaceTickerFinancials(ticker.TickerId, financials)
+}
+
+// replace the day's movers with the provider's current lists. Tickers we
+// haven't seen before are looked up first, they need a ticker_id
+func fetchMovers(deps *Dependencies, sublog zerolog.Logger) error {
+	marketData := deps.marketData
+
+	lists, err := marketData.GetMovers(deps, sublog)
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to retrieve movers")
+		return err
+	}
+
+	symbols := make([]string, 0)
+	for _, list := range lists {
+		for _, symbol := range list {
+			if !slices.Contains(symbols, symbol) {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+	quotes, err := marketData.GetQuotes(deps, sublog, symbols)
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to retrieve quotes for movers")
+		return err
+	}
+
+	sourceId, err := getOrCreateSource(deps, marketData.Name())
+	if err != nil {
+		return err
+	}
+
+	movers := make([]Mover, 0, len(symbols))
+	for moverType, list := range lists {
+		for _, symbol := range list {
+			quote, ok := quotes[symbol]
+			if !ok {
+				sublog.Warn().Str("symbol", symbol).Msg("no quote for mover, skipping")
+				continue
+			}
+			ticker, err := getTickerBySymbol(deps, sublog, symbol)
+			if errors.Is(err, sql.ErrNoRows) {
+				ticker, err = fetchTickerInfo(deps, sublog, symbol)
+			}
+			if err != nil {
+				sublog.Warn().Err(err).Str("symbol", symbol).Msg("failed to load ticker for mover, skipping")
+				continue
+			}
+
+			change := quote.QuotePrice - quote.QuotePrevClose
+			var changePct float64
+			if quote.QuotePrevClose != 0 {
+				changePct = change / quote.QuotePrevClose * 100
+			}
+			movers = append(movers, Mover{
+				SourceId:       sourceId,
+				TickerId:       ticker.TickerId,
+				MoverType:      moverType,
+				LastPrice:      quote.QuotePrice,
+				PriceChange:    float32(change),
+				PriceChangePct: float32(changePct),
+				Volume:         quote.QuoteVolume,
+			})
+		}
+	}
+
+	moverDate := time.Now().In(getMarketCalendar(homeExchange).location())
+	sublog.Info().Int("movers", len(movers)).Str("mover_date", moverDate.Format(sqlDateParseType)).Msg("loaded movers")
+
+	return deps.movers.ReplaceMovers(moverDate, movers)
+}
+
+// search for ticker and return highest scored quote symbol
+func jumpSearch(deps *Dependencies, sublog zerolog.Logger, searchString string) (SearchResultTicker, error) {
+	var searchResult SearchResultTicker
+
+	searchResults, err := listSearch(deps, sublog, searchString, "ticker")
+	if err != nil {
+		return searchResult, err
+	}
+	if len(searchResults) == 0 {
+		return searchResult, fmt.Errorf("sorry, the search returned zero results")
+	}
+
+	var highestScore float64 = 0
+	for _, result := range searchResults {
+		if result.ResultType == "ticker" && result.Ticker.SearchScore > highestScore {
+			searchResult = result.Ticker
+			highestScore = result.Ticker.SearchScore
+		}
+	}
+	if searchResult.TickerSymbol == "" {
+		return searchResult, fmt.Errorf("sorry, the search returned zero results")
+	}
+
+	return searchResult, nil
+}
+
+// search for ticker or news
+func listSearch(deps *Dependencies, sublog zerolog.Logger, searchString string, resultTypes string) ([]SearchResult, error) {
+	marketData := deps.marketData
+
+	start := time.Now()
+	searchResults := make([]SearchResult, 100)
+	searchResponse, err := marketData.Search(deps, sublog, searchString)
+	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Str("provider", marketData.Name()).Msg("timer: search")
+	if err != nil {
+		return searchResults, err
+	}
+
+	newsCount := 0
+	tickerCount := 0
+	if resultTypes == "ticker" && len(searchResponse.Quotes) == 0 {
+		return searchResults, fmt.Errorf("sorry, the search returned zero results")
+	}
+	if resultTypes == "news" && len(searchResponse.News) == 0 {
+		return searchResults, fmt.Errorf("sorry, the search returned zero results")
+	}
+	if resultTypes == "both" && len(searchResponse.Quotes)+len(searchResponse.News) == 0 {
+		return searchResults, fmt.Errorf("sorry, the search returned zero results")
+	}
+
+	if resultTypes == "news" || resultTypes == "both" {
+		for _, newsResult := range searchResponse.News {
+			newsCount++
+			searchResults = append(searchResults, SearchResult{
+				ResultType: "news",
+				News: SearchResultNews{
+					Publisher:   newsResult.Publisher,
+					Title:       newsResult.Title,
+					Type:        newsResult.Type,
+					URL:         newsResult.URL,
+					PublishDate: time.Unix(newsResult.PublishTime, 0).Format("Jan 2 15:04 MST 2006"),
+				},
+				Ticker: SearchResultTicker{},
+			})
+		}
+	}
+
+	if resultTypes == "ticker" || resultTypes == "both" {
+		for _, quoteResult := range searchResponse.Quotes {
+			if quoteResult.Type == "Option" {
+				continue
+			}
+			exchange := Exchange{ExchangeCode: quoteResult.ExchangeCode}
+			err := exchange.getByCode(deps, sublog)
+			if err != nil {
+				sublog.Error().Err(err).Str("symbol", quoteResult.Symbol).Str("exchange_code", quoteResult.ExchangeCode).Msg("skipping {symbol} with unknown {exchange_code}")
+				continue
+			}
+			if exchange.ExchangeId > 0 {
+				tickerCount++
+				searchResults = append(searchResults, SearchResult{
+					ResultType: "ticker",
+					News:       SearchResultNews{},
+					Ticker: SearchResultTicker{
+						TickerSymbol: quoteResult.Symbol,
+						ExchangeMic:  exchange.ExchangeMic,
+						Type:         quoteResult.Type,
+						ShortName:    quoteResult.ShortName,
+						LongName:     quoteResult.LongName,
+						SearchScore:  quoteResult.Score,
+					},
+				})
+			}
+		}
+	}
+
+	sublog.Info().Str("search_string", searchString).Int("news_count", newsCount).Int("ticker_count", tickerCount).Msg("Search results")
+
+	return searchResults, nil
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
 	"sort"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -46,7 +49,7 @@ type MarketIndexByPriceDate MarketIndexDailies
 type MarketIndexIntraday struct {
 	MarketIndexIntradayId uint64 `db:"intraday_id"`
 	EId                   string
-	TickerId              uint64    `db:"ticker_id"`
+	MarketIndexId         uint64    `db:"marketindex_id"`
 	PriceDate             string    `db:"price_date"`
 	LastPrice             float64   `db:"last_price"`
 	Volume                int64     `db:"volume"`
@@ -60,56 +63,200 @@ type MarketIndexIntradays struct {
 
 type ByMarketIndexPriceTime MarketIndexIntradays
 
+// IndexQuote is an index's latest level for the desktop and /index pages
+type IndexQuote struct {
+	MarketIndex MarketIndex
+	Quote       Quote
+	Session     string
+	ChangeAmt   float32
+	ChangePct   float32
+}
+
+// AdvanceDecline is how many of the tickers we track are up, down or flat on
+// the home market's latest session; it is our own breadth, not the
+// exchange-wide figure
+type AdvanceDecline struct {
+	Advancing int
+	Declining int
+	Unchanged int
+}
+
+// the indexes on the desktop, and the benchmarks tickers are compared to
+var benchmarkIndexes = []MarketIndex{
+	{MarketIndexSymbol: "^GSPC", MarketIndexMic: "XNYS", MarketIndexName: "S&P 500"},
+	{MarketIndexSymbol: "^DJI", MarketIndexMic: "XNYS", MarketIndexName: "Dow Jones Industrial Average"},
+	{MarketIndexSymbol: "^IXIC", MarketIndexMic: "XNAS", MarketIndexName: "Nasdaq Composite"},
+}
+
+// benchmark index symbol by exchange or operating MIC; anything else is
+// compared to the S&P 500
+var exchangeBenchmarks = map[string]string{
+	"XNAS": "^IXIC",
+	"XNGS": "^IXIC",
+	"XNMS": "^IXIC",
+	"XNCM": "^IXIC",
+}
+
 // object methods -------------------------------------------------------------
 
-func (mi MarketIndex) LoadDailies(deps *Dependencies, sublog zerolog.Logger, days int) ([]MarketIndexDaily, error) {
-	db := deps.db
+// the symbol without yhfinance's leading caret, for URLs
+func (mi MarketIndex) Slug() string {
+	return strings.TrimPrefix(mi.MarketIndexSymbol, "^")
+}
 
-	daily := MarketIndexDaily{}
-	dailies := make([]MarketIndexDaily, 0, days)
+func (mi *MarketIndex) getBySymbol(deps *Dependencies, sublog zerolog.Logger) error {
+	marketIndex, err := deps.marketIndexes.GetMarketIndexBySymbol(mi.MarketIndexSymbol)
+	if err == nil {
+		*mi = marketIndex
+	}
+	return err
+}
 
-	rows, err := db.Queryx(
-		`SELECT * FROM (
-       SELECT * FROM marketindex_daily WHERE marketindex_id=? AND volume > 0
-         ORDER BY price_datetime DESC LIMIT ?) DT1
-     ORDER BY price_date`,
-		mi.MarketIndexId, days)
+func (mi *MarketIndex) create(deps *Dependencies, sublog zerolog.Logger) error {
+	mi.HasIntraday = true
+	mi.HasEOD = true
+	err := deps.marketIndexes.CreateMarketIndex(*mi)
 	if err != nil {
-		return dailies, err
+		sublog.Warn().Err(err).Str("symbol", mi.MarketIndexSymbol).Msg("failed on INSERT")
+		return err
 	}
-	defer rows.Close()
+	return mi.getBySymbol(deps, sublog)
+}
 
-	for rows.Next() {
-		err = rows.StructScan(&daily)
-		if err != nil {
-			sublog.Warn().Err(err).Msg("failed reading result rows")
-		} else {
-			dailies = append(dailies, daily)
-		}
+// the calendar an index keeps is the one of the exchange it is computed on
+func (mi MarketIndex) exchange() Exchange {
+	return Exchange{ExchangeMic: mi.MarketIndexMic, ExchangeAcronym: "index"}
+}
+
+// same rule as tickers: we want the EOD for the exchange's last full session
+func (mi MarketIndex) needEODs(deps *Dependencies, sublog zerolog.Logger) bool {
+	closed := lastClose(mi.exchange())
+	if closed.IsZero() {
+		return false
+	}
+	_, err := getMarketIndexDaily(deps, sublog, mi.MarketIndexId, closed.Format(sqlDateParseType))
+	return err != nil
+}
+
+// a sample of the live level for the intraday chart; a refresh that gets the
+// same quote time again adds nothing
+func (mi MarketIndex) saveIntraday(deps *Dependencies, sublog zerolog.Logger, quote Quote) {
+	if quote.QuoteTime == 0 || quote.QuotePrice == 0 {
+		return
+	}
+	intraday := MarketIndexIntraday{
+		MarketIndexId: mi.MarketIndexId,
+		PriceDate:     time.Unix(quote.QuoteTime, 0).UTC().Format(sqlDatetimeSearchType),
+		LastPrice:     quote.QuotePrice,
+		Volume:        quote.QuoteVolume,
 	}
-	if err := rows.Err(); err != nil {
+	err := deps.marketIndexes.CreateMarketIndexIntraday(intraday)
+	if err != nil {
+		sublog.Warn().Err(err).Str("symbol", mi.MarketIndexSymbol).Msg("failed to save intraday sample")
+	}
+}
+
+// some indexes report no volume at all, so a day with none is still kept;
+// skipping it would leave needEODs asking for the same day forever
+func (mid *MarketIndexDaily) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error {
+	err := deps.marketIndexes.SaveMarketIndexDaily(*mid)
+	if err != nil {
+		sublog.Warn().Err(err).Str("price_date", mid.PriceDate).Msg("failed to save marketindex daily")
+	}
+	return err
+}
+
+// the dailies as TickerDaily, so the ticker charts can draw them; the bar's
+// time is the start of its trading day on the index's exchange
+func (mid MarketIndexDaily) asTickerDaily(location *time.Location) TickerDaily {
+	priceDate := mid.PriceDate
+	if len(priceDate) > 10 {
+		priceDate = priceDate[0:10]
+	}
+	priceDatetime, _ := time.ParseInLocation(sqlDateParseType, priceDate, location)
+	return TickerDaily{
+		TickerId:      mid.MarketIndexId,
+		PriceDatetime: priceDatetime,
+		OpenPrice:     mid.OpenPrice,
+		HighPrice:     mid.HighPrice,
+		LowPrice:      mid.LowPrice,
+		ClosePrice:    mid.ClosePrice,
+		Volume:        mid.Volume,
+	}
+}
+
+// the range's EODs in the range's interval
+func (mi MarketIndex) getChartEODs(deps *Dependencies, sublog zerolog.Logger, chartRange ChartRange) ([]TickerDaily, error) {
+	location := getMarketCalendar(mi.exchange()).location()
+	dailies, err := mi.getEODsBetween(deps, sublog, chartRange.fromDate(), chartRange.beforeDate(), location)
+	if err != nil {
 		return dailies, err
 	}
+	return resampleDailies(dailies, chartRange.interval(), location), nil
+}
 
-	return dailies, nil
+// the EODs from fromDate up to (not including) beforeDate, shaped for the
+// ticker charts
+func (mi MarketIndex) getEODsBetween(deps *Dependencies, sublog zerolog.Logger, fromDate, beforeDate string, location *time.Location) ([]TickerDaily, error) {
+	marketIndexDailies, err := deps.marketIndexes.GetMarketIndexDailies(sublog, mi.MarketIndexId, fromDate, beforeDate)
+	dailies := make([]TickerDaily, 0, len(marketIndexDailies))
+	for _, daily := range marketIndexDailies {
+		dailies = append(dailies, daily.asTickerDaily(location))
+	}
+	return dailies, err
+}
+
+// the samples of the exchange's current or most recent session, shaped for
+// the ticker intraday chart
+func (mi MarketIndex) getSessionIntradays(deps *Dependencies, sublog zerolog.Logger) ([]TickerIntraday, error) {
+	intradays := make([]TickerIntraday, 0)
+	sessions := getMarketCalendar(mi.exchange()).latestSessions(time.Now())
+	if len(sessions) == 0 {
+		return intradays, nil
+	}
+
+	from := sessions[0][0].UTC().Format(sqlDatetimeSearchType)
+	to := sessions[len(sessions)-1][1].UTC().Format(sqlDatetimeSearchType)
+	marketIndexIntradays, err := deps.marketIndexes.GetMarketIndexIntradays(sublog, mi.MarketIndexId, from, to)
+	for _, intraday := range marketIndexIntradays {
+		intradays = append(intradays, TickerIntraday{TickerId: mi.MarketIndexId, PriceDate: intraday.PriceDate, LastPrice: intraday.LastPrice, Volume: intraday.Volume})
+	}
+	return intradays, err
+}
+
+// the close before the session the intraday chart shows
+func (mi MarketIndex) priorClose(deps *Dependencies, sublog zerolog.Logger) float64 {
+	sessions := getMarketCalendar(mi.exchange()).latestSessions(time.Now())
+	if len(sessions) == 0 {
+		return 0
+	}
+	sessionDate := sessions[0][0].Format(sqlDateParseType)
+
+	closePrice, err := deps.marketIndexes.GetPriorMarketIndexClose(mi.MarketIndexId, sessionDate)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		sublog.Warn().Err(err).Str("symbol", mi.MarketIndexSymbol).Msg("failed to get prior close")
+	}
+	return closePrice
+}
+
+// a stand-in Ticker so the index can be drawn by the ticker charts
+func (mi MarketIndex) asTicker() Ticker {
+	return Ticker{TickerId: mi.MarketIndexId, TickerSymbol: mi.MarketIndexSymbol, TickerName: mi.MarketIndexName}
+}
+
+func (mi MarketIndex) LoadDailies(deps *Dependencies, sublog zerolog.Logger, days int) ([]MarketIndexDaily, error) {
+	return deps.marketIndexes.GetLastMarketIndexDailies(sublog, mi.MarketIndexId, days)
 }
 
 func (mi MarketIndex) LoadMarketIndexIntraday(deps *Dependencies, intradate string) ([]MarketIndexIntraday, error) {
-	db := deps.db
 	sublog := deps.logger
 
-	var marketindex_intraday MarketIndexIntraday
 	marketindex_intradays := make([]MarketIndexIntraday, 0)
 
-	rows, err := db.Queryx(
-		`SELECT * FROM marketindex_intraday
-                 WHERE marketindex_id=? AND price_dateitime LIKE ? AND volume > 0
-                 ORDER BY price_date`,
-		mi.MarketIndexId, intradate+"%")
+	intradays, err := deps.marketIndexes.GetMarketIndexIntradays(*sublog, mi.MarketIndexId, intradate+" 00:00:00", intradate+" 23:59:59")
 	if err != nil {
 		return marketindex_intradays, err
 	}
-	defer rows.Close()
 
 	// add pre-closing price
 	priorBusinessDay, err := mytime.PriorBusinessDayStr(intradate + " 21:05:00")
@@ -124,17 +271,11 @@ func (mi MarketIndex) LoadMarketIndexIntraday(deps *Dependencies, intradate stri
 	}
 
 	// add these marketindex intraday prices
-	for rows.Next() {
-		err = rows.StructScan(&marketindex_intraday)
-		if err != nil {
-			sublog.Warn().Err(err).Msg("failed reading result rows")
-		} else {
+	for _, marketindex_intraday := range intradays {
+		if marketindex_intraday.Volume > 0 {
 			marketindex_intradays = append(marketindex_intradays, marketindex_intraday)
 		}
 	}
-	if err := rows.Err(); err != nil {
-		return marketindex_intradays, err
-	}
 
 	// add post-opening price
 	nextBusinessDay, err := mytime.NextBusinessDayStr(intradate + " 13:55:00")
@@ -194,14 +335,125 @@ func (i MarketIndexIntradays) Count() int {
 // misc -----------------------------------------------------------------------
 
 func getMarketIndexDaily(deps *Dependencies, sublog zerolog.Logger, marketindex_id uint64, daily_date string) (*MarketIndexDaily, error) {
-	db := deps.db
-
-	marketindexdaily := MarketIndexDaily{}
 	if len(daily_date) > 10 {
 		daily_date = daily_date[0:10]
 	}
-	err := db.QueryRowx(
-		`SELECT * FROM marketindex_daily WHERE marketindex_id=? AND price_date=?`,
-		marketindex_id, daily_date).StructScan(&marketindexdaily)
+	marketindexdaily, err := deps.marketIndexes.GetMarketIndexDaily(marketindex_id, daily_date)
 	return &marketindexdaily, err
 }
+
+// the index by symbol, with or without the caret; a benchmark index is added
+// the first time it is asked for
+func getMarketIndex(deps *Dependencies, sublog zerolog.Logger, symbol string) (MarketIndex, error) {
+	symbol = strings.ToUpper(symbol)
+	if !strings.HasPrefix(symbol, "^") {
+		symbol = "^" + symbol
+	}
+
+	mi := MarketIndex{MarketIndexSymbol: symbol}
+	err := mi.getBySymbol(deps, sublog)
+	if errors.Is(err, sql.ErrNoRows) {
+		for _, benchmark := range benchmarkIndexes {
+			if benchmark.MarketIndexSymbol == symbol {
+				mi = benchmark
+				err = mi.create(deps, sublog)
+				break
+			}
+		}
+	}
+	return mi, err
+}
+
+func getBenchmarkIndexes(deps *Dependencies, sublog zerolog.Logger) []MarketIndex {
+	indexes := make([]MarketIndex, 0, len(benchmarkIndexes))
+	for _, benchmark := range benchmarkIndexes {
+		mi, err := getMarketIndex(deps, sublog, benchmark.MarketIndexSymbol)
+		if err != nil {
+			sublog.Warn().Err(err).Str("symbol", benchmark.MarketIndexSymbol).Msg("failed to get benchmark index")
+			continue
+		}
+		indexes = append(indexes, mi)
+	}
+	return indexes
+}
+
+func getBenchmarkIndex(deps *Dependencies, sublog zerolog.Logger, exchange Exchange) (MarketIndex, error) {
+	symbol, ok := exchangeBenchmarks[exchange.ExchangeMic]
+	if !ok {
+		symbol, ok = exchangeBenchmarks[exchange.OperatingMic]
+	}
+	if !ok {
+		symbol = benchmarkIndexes[0].MarketIndexSymbol
+	}
+	return getMarketIndex(deps, sublog, symbol)
+}
+
+// price history for an index comes from the same provider call as a ticker's
+func fetchMarketIndexEODs(deps *Dependencies, sublog zerolog.Logger, mi MarketIndex) error {
+	marketData := deps.marketData
+
+	historical, err := marketData.GetHistorical(deps, sublog, mi.MarketIndexSymbol)
+	if err != nil {
+		sublog.Warn().Err(err).Str("symbol", mi.MarketIndexSymbol).Msg("failed to retrieve historical prices")
+		return err
+	}
+
+	location := getMarketCalendar(mi.exchange()).location()
+	var lastErr error
+	for _, price := range historical.Prices {
+		daily := MarketIndexDaily{
+			MarketIndexId: mi.MarketIndexId,
+			PriceDate:     price.Date.In(location).Format(sqlDateParseType),
+			OpenPrice:     price.Open,
+			HighPrice:     price.High,
+			LowPrice:      price.Low,
+			ClosePrice:    price.Close,
+			Volume:        price.Volume,
+		}
+		err := daily.createOrUpdate(deps, sublog)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// the latest level of each index, sampled for the intraday chart while its
+// exchange is open
+func getIndexQuotes(deps *Dependencies, sublog zerolog.Logger, indexes []MarketIndex) []IndexQuote {
+	symbols := make([]string, 0, len(indexes))
+	for _, mi := range indexes {
+		symbols = append(symbols, mi.MarketIndexSymbol)
+	}
+	quotes, _ := loadMultiTickerQuotes(deps, sublog, symbols)
+
+	indexQuotes := make([]IndexQuote, 0, len(indexes))
+	for _, mi := range indexes {
+		indexQuote := IndexQuote{MarketIndex: mi, Session: marketSession(mi.exchange())}
+		if quote, ok := quotes[mi.MarketIndexSymbol]; ok {
+			indexQuote.Quote = quote
+			if quote.QuotePrice > 0 && quote.QuotePrevClose > 0 {
+				indexQuote.ChangeAmt = float32(quote.QuotePrice - quote.QuotePrevClose)
+				indexQuote.ChangePct = float32((quote.QuotePrice - quote.QuotePrevClose) / quote.QuotePrevClose * 100)
+			}
+			if indexQuote.Session == sessionRegular {
+				mi.saveIntraday(deps, sublog, quote)
+			}
+		}
+		indexQuotes = append(indexQuotes, indexQuote)
+	}
+	return indexQuotes
+}
+
+func getAdvanceDecline(deps *Dependencies, sublog zerolog.Logger) AdvanceDecline {
+	sessions := getMarketCalendar(homeExchange).latestSessions(time.Now())
+	if len(sessions) == 0 {
+		return AdvanceDecline{}
+	}
+
+	advancing, declining, unchanged, err := deps.tickers.CountTickerMoves(sessions[0][0])
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to count advancing and declining tickers")
+	}
+	return AdvanceDecline{advancing, declining, unchanged}
+}
@@ -0,0 +1,29 @@
+-- Aurora (MySQL) DDL for the holding and transaction tables; apply once, in
+-- file order, before deploying the code that uses them. schema_sqlite.sql is
+-- the local backend's copy of the same tables
+
+CREATE TABLE IF NOT EXISTS holding (
+  holding_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  watcher_id BIGINT UNSIGNED NOT NULL,
+  ticker_id BIGINT UNSIGNED NOT NULL,
+  shares DOUBLE NOT NULL DEFAULT 0,
+  cost_basis DOUBLE NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (holding_id),
+  UNIQUE KEY holding_watcher_ticker (watcher_id, ticker_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS `transaction` (
+  transaction_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  holding_id BIGINT UNSIGNED NOT NULL,
+  watcher_id BIGINT UNSIGNED NOT NULL,
+  transaction_type VARCHAR(16) NOT NULL,
+  transaction_datetime DATETIME NOT NULL,
+  shares DOUBLE NOT NULL DEFAULT 0,
+  share_price DOUBLE NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (transaction_id),
+  KEY transaction_holding (holding_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
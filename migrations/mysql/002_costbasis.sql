@@ -0,0 +1,5 @@
+-- Aurora (MySQL) DDL for cost-basis methods and specific-lot sales
+
+ALTER TABLE watcher ADD COLUMN costbasis_method VARCHAR(16) NOT NULL DEFAULT 'fifo';
+
+ALTER TABLE `transaction` ADD COLUMN lot_id BIGINT UNSIGNED NOT NULL DEFAULT 0 AFTER share_price;
@@ -0,0 +1,31 @@
+-- Aurora (MySQL) DDL for price alerts and their trigger history
+
+CREATE TABLE IF NOT EXISTS alert (
+  alert_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  watcher_id BIGINT UNSIGNED NOT NULL,
+  ticker_id BIGINT UNSIGNED NOT NULL,
+  alert_type VARCHAR(32) NOT NULL,
+  alert_value DOUBLE NOT NULL DEFAULT 0,
+  alert_status VARCHAR(16) NOT NULL DEFAULT 'armed',
+  cooldown_minutes INT NOT NULL DEFAULT 60,
+  last_triggered_datetime DATETIME NULL,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (alert_id),
+  KEY alert_watcher (watcher_id),
+  KEY alert_ticker (ticker_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS alert_trigger (
+  alert_trigger_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  alert_id BIGINT UNSIGNED NOT NULL,
+  watcher_id BIGINT UNSIGNED NOT NULL,
+  ticker_id BIGINT UNSIGNED NOT NULL,
+  trigger_price DOUBLE NOT NULL DEFAULT 0,
+  trigger_message VARCHAR(255) NOT NULL DEFAULT '',
+  delivered TINYINT(1) NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (alert_trigger_id),
+  KEY alert_trigger_watcher (watcher_id, delivered)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
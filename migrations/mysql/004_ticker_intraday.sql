@@ -0,0 +1,15 @@
+-- Aurora (MySQL) DDL for the live-quote samples behind the intraday chart.
+-- CreateTickerIntraday's ON DUPLICATE KEY UPDATE relies on the unique key;
+-- without it every refresh would add another row
+
+CREATE TABLE IF NOT EXISTS ticker_intraday (
+  intraday_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  ticker_id BIGINT UNSIGNED NOT NULL,
+  price_date VARCHAR(19) NOT NULL,
+  last_price DOUBLE NOT NULL DEFAULT 0,
+  volume BIGINT NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (intraday_id),
+  UNIQUE KEY ticker_intraday_date (ticker_id, price_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
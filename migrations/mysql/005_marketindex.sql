@@ -0,0 +1,24 @@
+-- Aurora (MySQL) DDL for the market index dashboard. marketindex is new;
+-- marketindex_daily and marketindex_intraday already exist, and get the
+-- unique keys SaveMarketIndexDaily and CreateMarketIndexIntraday upsert on.
+-- Clear out any duplicate (index, date) rows before adding the keys
+
+CREATE TABLE IF NOT EXISTS marketindex (
+  marketindex_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  marketindex_symbol VARCHAR(16) NOT NULL DEFAULT '',
+  marketindex_mic VARCHAR(8) NOT NULL DEFAULT '',
+  marketindex_name VARCHAR(128) NOT NULL DEFAULT '',
+  country_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  marketindex_has_intraday TINYINT(1) NOT NULL DEFAULT 0,
+  marketindex_has_eod TINYINT(1) NOT NULL DEFAULT 0,
+  currency_id BIGINT UNSIGNED NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (marketindex_id),
+  UNIQUE KEY marketindex_symbol (marketindex_symbol)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+ALTER TABLE marketindex_daily ADD UNIQUE KEY marketindex_daily_date (marketindex_id, price_date);
+
+ALTER TABLE marketindex_intraday CHANGE ticker_id marketindex_id BIGINT UNSIGNED NOT NULL;
+ALTER TABLE marketindex_intraday ADD UNIQUE KEY marketindex_intraday_date (marketindex_id, price_date);
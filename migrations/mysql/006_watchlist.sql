@@ -0,0 +1,25 @@
+-- Aurora (MySQL) DDL for named watchlists and the tickers on them
+
+CREATE TABLE IF NOT EXISTS watchlist (
+  watchlist_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  watcher_id BIGINT UNSIGNED NOT NULL,
+  watchlist_name VARCHAR(64) NOT NULL,
+  share_token VARCHAR(32) NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (watchlist_id),
+  UNIQUE KEY watchlist_name (watcher_id, watchlist_name),
+  KEY watchlist_share (share_token)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+CREATE TABLE IF NOT EXISTS watchlist_ticker (
+  watchlist_ticker_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  watchlist_id BIGINT UNSIGNED NOT NULL,
+  ticker_id BIGINT UNSIGNED NOT NULL,
+  sort_order INT NOT NULL DEFAULT 0,
+  note VARCHAR(255) NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (watchlist_ticker_id),
+  UNIQUE KEY watchlist_ticker_ticker (watchlist_id, ticker_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
@@ -0,0 +1,13 @@
+-- Aurora (MySQL) DDL for ticker dividends, per share as paid on the
+-- ex-dividend date
+
+CREATE TABLE IF NOT EXISTS ticker_dividend (
+  ticker_dividend_id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+  ticker_id BIGINT UNSIGNED NOT NULL,
+  dividend_date DATE NOT NULL,
+  amount DOUBLE NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (ticker_dividend_id),
+  UNIQUE KEY ticker_dividend_date (ticker_id, dividend_date)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
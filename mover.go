@@ -80,8 +80,6 @@ func (m Movers) SortActives() *[]WebMover {
 // misc -----------------------------------------------------------------------
 
 func getMovers(deps *Dependencies, sublog zerolog.Logger) Movers {
-	db := deps.db
-
 	movers := Movers{}
 	gainers := make([]WebMover, 0)
 	losers := make([]WebMover, 0)
@@ -94,20 +92,13 @@ func getMovers(deps *Dependencies, sublog zerolog.Logger) Movers {
 	}
 	sublog = sublog.With().Str("mover_date", latestMoverDate.Format("2006-01-02")).Logger()
 
-	rows, err := db.Queryx(`SELECT * FROM mover WHERE mover_date=?`, latestMoverDate.Format("2006-01-02"))
+	found, err := deps.movers.GetMovers(sublog, latestMoverDate)
 	if err != nil {
 		sublog.Error().Err(err).Msg("failed to load movers")
 		return movers
 	}
 
-	defer rows.Close()
-	mover := Mover{}
-	for rows.Next() {
-		err = rows.StructScan(&mover)
-		if err != nil {
-			sublog.Warn().Err(err).Msg("failed reading row")
-			continue
-		}
+	for _, mover := range found {
 		if mover.Volume > 1_000_000 {
 			mover.VolumeStr = fmt.Sprintf("%.2fM", float32(mover.Volume)/1_000_000)
 		} else if mover.Volume > 1_000 {
@@ -134,19 +125,11 @@ func getMovers(deps *Dependencies, sublog zerolog.Logger) Movers {
 			}
 		}
 	}
-	if err := rows.Err(); err != nil {
-		sublog.Warn().Err(err).Msg("failed reading rows")
-		return movers
-	}
 
 	movers = Movers{gainers, losers, actives, latestMoverDate}
 	return movers
 }
 
 func getLatestMoversDate(deps *Dependencies) (time.Time, error) {
-	db := deps.db
-
-	maxMoverDate := time.Time{}
-	err := db.QueryRowx(`SELECT MAX(mover_date) FROM mover`).Scan(&maxMoverDate)
-	return maxMoverDate, err
+	return deps.movers.GetLatestMoverDate()
 }
@@ -26,7 +26,7 @@ func (o *OAuth) create(deps *Dependencies) error {
 	sublog := deps.logger.With().Str("provider", o.OAuthIssuer).Logger()
 
 	_, err := db.Exec(
-		"INSERT INTO oauth SET oauth_issuer=?, oauth_sub=?, oauth_issued=?, oauth_expires=?",
+		"INSERT INTO oauth (oauth_issuer, oauth_sub, oauth_issued, oauth_expires) VALUES (?, ?, ?, ?)",
 		o.OAuthIssuer, o.OAuthSub, o.OAuthIssued, o.OAuthExpires)
 	if err != nil {
 		sublog.Error().Err(err).Msg("failed on insert")
@@ -46,7 +46,7 @@ func (o *OAuth) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error
 	}
 
 	_, err = db.Exec(
-		"UPDATE oauth SET oauth_issued=?, oauth_expires=?, update_datetime=now() WHERE oauth_id=?",
+		"UPDATE oauth SET oauth_issued=?, oauth_expires=?, update_datetime=CURRENT_TIMESTAMP WHERE oauth_id=?",
 		o.OAuthIssued, o.OAuthExpires,
 		o.OAuthId,
 	)
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// PortfolioDay is everything the watcher held valued at one day's closes,
+// with the money put in (+) or taken out (-) by that day's transactions
+type PortfolioDay struct {
+	Date      string
+	Value     float64
+	CashFlow  float64
+	Dividends float64 // paid out on the day's ex-dividend dates, for total return
+	Invested  float64 // net of every cash flow so far
+	Growth    float64 // what 1 invested at the start would be worth, by time-weighted return
+}
+
+// PortfolioStats sums up the portfolio over the range shown; the returns are
+// percents
+type PortfolioStats struct {
+	StartValue     float64
+	EndValue       float64
+	NetFlows       float64
+	Dividends      float64
+	Gain           float64
+	TWR            float32 // time-weighted: the holdings' own performance, whatever was added or taken out
+	XIRR           float32 // money-weighted: what the watcher's own dollars earned
+	XIRRAnnualized bool    // per year, when the range is at least that long
+	HasXIRR        bool
+}
+
+// HoldingIncome is every dividend one holding has been paid
+type HoldingIncome struct {
+	TickerSymbol string
+	Payments     int
+	Total        float64
+}
+
+// AllocationSlice is one sector or industry's share of the market value held
+type AllocationSlice struct {
+	Name    string
+	Value   float64
+	Percent float32
+}
+
+// a holding with what it takes to value it on any day: its trades, the
+// ticker's splits, and the ticker's unadjusted closes by exchange date
+type portfolioPosition struct {
+	holding      Holding
+	ticker       Ticker
+	transactions []Transaction
+	splits       []TickerSplit
+	dividends    []TickerDividend
+	closes       map[string]float64
+}
+
+type cashFlow struct {
+	Date   time.Time
+	Amount float64
+}
+
+var errNoXIRR = errors.New("cash flows have no rate of return")
+
+func portfolioHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+
+		benchmark := r.FormValue("benchmark")
+		if benchmark == "" {
+			benchmark = benchmarkIndexes[0].Slug()
+		}
+		timespan, err := strconv.Atoi(r.FormValue("timespan"))
+		if err != nil || timespan <= 0 {
+			timespan = 365
+		}
+		totalReturn := r.FormValue("totalreturn") == "1"
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("benchmark", benchmark).Int("timespan", timespan).Logger()
+
+		webdata["PortfolioBenchmark"] = strings.TrimPrefix(strings.ToUpper(benchmark), "^")
+		webdata["Indexes"] = benchmarkIndexes
+		webdata["timespan"] = timespan
+		webdata["TotalReturn"] = totalReturn
+
+		if watcher.WatcherId == 0 {
+			rc.messages = append(rc.messages, Message{"Sorry, you need to be signed in to see your portfolio", "error"})
+			renderTemplate(w, r, deps, sublog, "portfolio")
+			return
+		}
+
+		positions, err := loadPortfolioPositions(deps, sublog, watcher)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to load portfolio")
+			rc.messages = append(rc.messages, Message{"Sorry, there was a problem loading your portfolio", "error"})
+			renderTemplate(w, r, deps, sublog, "portfolio")
+			return
+		}
+
+		chartRange := ChartRange{Days: timespan, Interval: intervalDaily}
+		days := calcPortfolioDays(positions, totalReturn)
+		inRange := portfolioDaysSince(days, chartRange.fromDate())
+		if len(inRange) == 0 {
+			rc.messages = append(rc.messages, Message{"Record a purchase, or import your transactions, to see how your portfolio is doing", "info"})
+			renderTemplate(w, r, deps, sublog, "portfolio")
+			return
+		}
+
+		stats := calcPortfolioStats(days, chartRange.fromDate())
+		compare := []CompareSeries{{"Portfolio", time.UTC, portfolioGrowthDailies(inRange)}}
+		if mi, err := getMarketIndex(deps, sublog, benchmark); err != nil {
+			sublog.Warn().Err(err).Msg("failed to find benchmark index")
+		} else {
+			if mi.needEODs(deps, sublog) {
+				fetchMarketIndexEODs(deps, sublog, mi)
+			}
+			// from the portfolio's first day shown, so both start together
+			indexRange := ChartRange{From: inRange[0].Date, Interval: intervalDaily}
+			indexDailies, err := mi.getChartEODs(deps, sublog, indexRange)
+			if err != nil {
+				sublog.Warn().Err(err).Msg("failed to load benchmark EODs")
+			}
+			compare = append(compare, CompareSeries{mi.MarketIndexName, getMarketCalendar(mi.exchange()).location(), indexDailies})
+		}
+		compareStats := make([]CompareStats, 0, len(compare))
+		for _, s := range compare {
+			compareStats = append(compareStats, calcCompareStats(s.Name, s.Dailies))
+		}
+
+		sectors, industries := calcPortfolioAllocations(positions)
+		income := calcPortfolioIncome(positions)
+
+		webdata["PortfolioStats"] = stats
+		webdata["CompareStats"] = compareStats
+		webdata["Sectors"] = sectors
+		webdata["Industries"] = industries
+		webdata["Income"] = income
+		webdata["ValueChart"] = chartHandlerPortfolioValue(deps, sublog, rc.nonce, inRange, chartRange)
+		webdata["ReturnChart"] = chartHandlerCompare(deps, sublog, rc.nonce, "Portfolio (time-weighted) vs benchmark", compare, chartRange)
+		webdata["SectorChart"] = chartHandlerAllocation(deps, sublog, rc.nonce, "By Sector", sectors)
+		webdata["IndustryChart"] = chartHandlerAllocation(deps, sublog, rc.nonce, "By Industry", industries)
+
+		renderTemplate(w, r, deps, sublog, "portfolio")
+	})
+}
+
+// object methods -------------------------------------------------------------
+
+// the exchange dates of the position's closes, in order
+func (p portfolioPosition) dates() []string {
+	dates := make([]string, 0, len(p.closes))
+	for date := range p.closes {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+// shares held now at the latest price we have for them
+func (p portfolioPosition) marketValue() float64 {
+	price := p.ticker.MarketPrice
+	if price <= 0 {
+		dates := p.dates()
+		if len(dates) > 0 {
+			price = p.closes[dates[len(dates)-1]]
+		}
+	}
+	return p.holding.Shares * price
+}
+
+// misc -----------------------------------------------------------------------
+
+// every holding the watcher has ever had, even ones since sold, since they
+// are part of the portfolio's history
+func loadPortfolioPositions(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) ([]portfolioPosition, error) {
+	holdings, err := getWatcherHoldings(deps, sublog, watcher)
+	if err != nil {
+		return []portfolioPosition{}, err
+	}
+
+	positions := make([]portfolioPosition, 0, len(holdings))
+	for _, holding := range holdings {
+		transactions, err := getTransactionsByHolding(deps, sublog, holding.HoldingId)
+		if err != nil {
+			return positions, err
+		}
+		if len(transactions) == 0 {
+			continue
+		}
+		ticker, err := deps.tickers.GetTicker(holding.TickerId)
+		if err != nil {
+			return positions, err
+		}
+		splits, err := ticker.getSplits(deps, sublog)
+		if err != nil {
+			return positions, err
+		}
+		dividends, err := ticker.getDividends(deps, sublog)
+		if err != nil {
+			return positions, err
+		}
+
+		location := time.UTC
+		if exchange, err := getExchangeById(deps, sublog, ticker.ExchangeId); err == nil {
+			location = getMarketCalendar(exchange).location()
+		}
+		dailies, err := deps.tickers.GetTickerDailies(sublog, ticker.TickerId, transactions[0].TransactionDate().Format(sqlDateParseType), "")
+		if err != nil {
+			return positions, err
+		}
+		closes := make(map[string]float64, len(dailies))
+		for _, daily := range dailies {
+			if daily.ClosePrice > 0 {
+				closes[daily.PriceDatetime.In(location).Format(sqlDateParseType)] = daily.ClosePrice
+			}
+		}
+
+		positions = append(positions, portfolioPosition{holding, ticker, transactions, splits, dividends, closes})
+	}
+	return positions, nil
+}
+
+// the portfolio on every day any of its tickers closed, from the first
+// purchase on; shares follow the trades and splits as they happened, so they
+// are valued at the unadjusted closes. A ticker with no close on a day keeps
+// its last one. For total return, dividends are paid out to the watcher on
+// their ex-dividend dates and count toward the day's return
+func calcPortfolioDays(positions []portfolioPosition, totalReturn bool) []PortfolioDay {
+	first := ""
+	dateSet := map[string]bool{}
+	for _, position := range positions {
+		for _, transaction := range position.transactions {
+			date := transaction.TransactionDate().Format(sqlDateParseType)
+			if first == "" || date < first {
+				first = date
+			}
+		}
+		for date := range position.closes {
+			dateSet[date] = true
+		}
+	}
+	dates := make([]string, 0, len(dateSet))
+	for date := range dateSet {
+		if date >= first {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+
+	type state struct {
+		transactions []Transaction
+		splits       []TickerSplit
+		dividends    []TickerDividend
+		shares       float64
+		lastClose    float64
+	}
+	states := make([]state, len(positions))
+	for x, position := range positions {
+		transactions := append([]Transaction{}, position.transactions...)
+		sort.SliceStable(transactions, func(i, j int) bool {
+			return transactions[i].TransactionDate().Before(transactions[j].TransactionDate())
+		})
+		splits := append([]TickerSplit{}, position.splits...)
+		sort.SliceStable(splits, func(i, j int) bool { return splits[i].SplitDate.Before(splits[j].SplitDate) })
+		dividends := []TickerDividend{}
+		if totalReturn {
+			dividends = append(dividends, position.dividends...)
+			sort.SliceStable(dividends, func(i, j int) bool { return dividends[i].DividendDate.Before(dividends[j].DividendDate) })
+		}
+		states[x] = state{transactions: transactions, splits: splits, dividends: dividends}
+	}
+
+	days := make([]PortfolioDay, 0, len(dates))
+	invested, growth := 0.0, 1.0
+	for _, date := range dates {
+		day := PortfolioDay{Date: date}
+		for x := range states {
+			s := &states[x]
+			// splits, dividends and trades dated on or before today, in date
+			// order; on the same day a split takes effect first, and a trade
+			// on the ex-dividend date doesn't change who gets the dividend
+			for {
+				next, nextDate := "", ""
+				if len(s.splits) > 0 {
+					next, nextDate = "split", s.splits[0].SplitDate.Format(sqlDateParseType)
+				}
+				if len(s.dividends) > 0 && (next == "" || s.dividends[0].date() < nextDate) {
+					next, nextDate = "dividend", s.dividends[0].date()
+				}
+				if len(s.transactions) > 0 {
+					if tradeDate := s.transactions[0].TransactionDate().Format(sqlDateParseType); next == "" || tradeDate < nextDate {
+						next, nextDate = "trade", tradeDate
+					}
+				}
+				if next == "" || nextDate > date {
+					break
+				}
+
+				switch next {
+				case "split":
+					if factor, err := s.splits[0].Factor(); err == nil {
+						s.shares *= factor
+					}
+					s.splits = s.splits[1:]
+				case "dividend":
+					if s.shares > shareEpsilon {
+						day.Dividends += s.shares * s.dividends[0].Amount
+					}
+					s.dividends = s.dividends[1:]
+				case "trade":
+					transaction := s.transactions[0]
+					switch transaction.TransactionType {
+					case "bought":
+						s.shares += transaction.Shares
+						day.CashFlow += transaction.Shares * transaction.SharePrice
+					case "sold":
+						s.shares -= transaction.Shares
+						day.CashFlow -= transaction.Shares * transaction.SharePrice
+					}
+					s.transactions = s.transactions[1:]
+				}
+			}
+			if closePrice, ok := positions[x].closes[date]; ok {
+				s.lastClose = closePrice
+			}
+			if s.shares > shareEpsilon {
+				day.Value += s.shares * s.lastClose
+			}
+		}
+
+		var prior float64
+		if len(days) > 0 {
+			prior = days[len(days)-1].Value
+		}
+		growth *= 1 + portfolioDayReturn(prior, day.Value+day.Dividends, day.CashFlow)
+		invested += day.CashFlow
+		day.Invested = invested
+		day.Growth = growth
+		days = append(days, day)
+	}
+	return days
+}
+
+// the day's return with buys counted at the start of the day and sales at
+// the end, so neither a first purchase nor selling everything divides by
+// nothing
+func portfolioDayReturn(prior, value, cashFlow float64) float64 {
+	start := prior
+	if cashFlow > 0 {
+		start += cashFlow
+	}
+	if start <= 0 {
+		return 0
+	}
+	return (value - cashFlow - prior) / start
+}
+
+func portfolioDaysSince(days []PortfolioDay, fromDate string) []PortfolioDay {
+	x := sort.Search(len(days), func(i int) bool { return days[i].Date >= fromDate })
+	return days[x:]
+}
+
+// the returns are measured from the close before the range, or from nothing
+// when the range starts before the first purchase
+func calcPortfolioStats(days []PortfolioDay, fromDate string) PortfolioStats {
+	stats := PortfolioStats{}
+	start := sort.Search(len(days), func(i int) bool { return days[i].Date >= fromDate })
+	if start == len(days) {
+		return stats
+	}
+
+	growth := 1.0
+	flows := make([]cashFlow, 0)
+	if start > 0 {
+		before := days[start-1]
+		stats.StartValue = before.Value
+		growth = before.Growth
+		if before.Value > 0 {
+			flows = append(flows, cashFlow{parsePortfolioDate(before.Date), -before.Value})
+		}
+	}
+	for _, day := range days[start:] {
+		stats.NetFlows += day.CashFlow
+		stats.Dividends += day.Dividends
+		if day.CashFlow != 0 || day.Dividends != 0 {
+			flows = append(flows, cashFlow{parsePortfolioDate(day.Date), day.Dividends - day.CashFlow})
+		}
+	}
+	last := days[len(days)-1]
+	stats.EndValue = last.Value
+	stats.Gain = stats.EndValue - stats.StartValue - stats.NetFlows + stats.Dividends
+	stats.TWR = float32((last.Growth/growth - 1) * 100)
+
+	if last.Value > 0 {
+		flows = append(flows, cashFlow{parsePortfolioDate(last.Date), last.Value})
+	}
+	if rate, err := calcXIRR(flows); err == nil {
+		// a few weeks' return compounded out to a year is a silly number, so
+		// less than a year gets the return over the range instead
+		years := flows[len(flows)-1].Date.Sub(flows[0].Date).Hours() / 24 / 365
+		if years < 1 {
+			rate = math.Pow(1+rate, years) - 1
+		}
+		stats.XIRR = float32(rate * 100)
+		stats.HasXIRR = true
+		stats.XIRRAnnualized = years >= 1
+	}
+	return stats
+}
+
+// the annual rate that brings the dated cash flows to a net present value of
+// zero: Newton's method, falling back to bisection when it wanders off
+func calcXIRR(flows []cashFlow) (float64, error) {
+	if len(flows) < 2 {
+		return 0, errNoXIRR
+	}
+	hasIn, hasOut := false, false
+	for _, flow := range flows {
+		hasIn = hasIn || flow.Amount < 0
+		hasOut = hasOut || flow.Amount > 0
+	}
+	if !hasIn || !hasOut {
+		return 0, errNoXIRR
+	}
+
+	first := flows[0].Date
+	for _, flow := range flows {
+		if flow.Date.Before(first) {
+			first = flow.Date
+		}
+	}
+	npv := func(rate float64) (float64, float64) {
+		value, derivative := 0.0, 0.0
+		for _, flow := range flows {
+			years := flow.Date.Sub(first).Hours() / 24 / 365
+			discount := math.Pow(1+rate, years)
+			value += flow.Amount / discount
+			derivative -= years * flow.Amount / (discount * (1 + rate))
+		}
+		return value, derivative
+	}
+
+	rate := 0.1
+	for x := 0; x < maxXIRRIterations; x++ {
+		value, derivative := npv(rate)
+		if math.Abs(value) < xirrTolerance {
+			return rate, nil
+		}
+		if derivative == 0 {
+			break
+		}
+		next := rate - value/derivative
+		if next <= -1 || math.IsNaN(next) || math.IsInf(next, 0) {
+			break
+		}
+		rate = next
+	}
+
+	low, high := -0.9999, 100.0
+	lowValue, _ := npv(low)
+	highValue, _ := npv(high)
+	if lowValue*highValue > 0 {
+		return 0, errNoXIRR
+	}
+	for x := 0; x < maxXIRRIterations*2; x++ {
+		rate = (low + high) / 2
+		value, _ := npv(rate)
+		if math.Abs(value) < xirrTolerance {
+			break
+		}
+		if value*lowValue > 0 {
+			low, lowValue = rate, value
+		} else {
+			high = rate
+		}
+	}
+	return rate, nil
+}
+
+// the time-weighted growth as a price series, so it can be charted and
+// measured the same way as the benchmark
+func portfolioGrowthDailies(days []PortfolioDay) []TickerDaily {
+	dailies := make([]TickerDaily, 0, len(days))
+	for _, day := range days {
+		dailies = append(dailies, TickerDaily{PriceDatetime: parsePortfolioDate(day.Date), ClosePrice: day.Growth})
+	}
+	return dailies
+}
+
+// what is held today, by the tickers' sectors and industries
+func calcPortfolioAllocations(positions []portfolioPosition) ([]AllocationSlice, []AllocationSlice) {
+	sectors := map[string]float64{}
+	industries := map[string]float64{}
+	for _, position := range positions {
+		value := position.marketValue()
+		if value <= 0 {
+			continue
+		}
+		sectors[allocationName(position.ticker.Sector)] += value
+		industries[allocationName(position.ticker.Industry)] += value
+	}
+	return calcAllocation(sectors), calcAllocation(industries)
+}
+
+// the dividends each holding has been paid, whatever the range; holdings
+// that never got one are left out
+func calcPortfolioIncome(positions []portfolioPosition) []HoldingIncome {
+	income := make([]HoldingIncome, 0, len(positions))
+	for _, position := range positions {
+		payments := calcDividendPayments(position.transactions, position.splits, position.dividends)
+		if len(payments) == 0 {
+			continue
+		}
+		holdingIncome := HoldingIncome{TickerSymbol: position.ticker.TickerSymbol, Payments: len(payments)}
+		for _, payment := range payments {
+			holdingIncome.Total += payment.Total()
+		}
+		income = append(income, holdingIncome)
+	}
+	sort.Slice(income, func(i, j int) bool { return income[i].TickerSymbol < income[j].TickerSymbol })
+	return income
+}
+
+// largest first
+func calcAllocation(values map[string]float64) []AllocationSlice {
+	total := 0.0
+	for _, value := range values {
+		total += value
+	}
+	slices := make([]AllocationSlice, 0, len(values))
+	if total == 0 {
+		return slices
+	}
+	for name, value := range values {
+		slices = append(slices, AllocationSlice{name, value, float32(value / total * 100)})
+	}
+	sort.Slice(slices, func(i, j int) bool {
+		if slices[i].Value == slices[j].Value {
+			return slices[i].Name < slices[j].Name
+		}
+		return slices[i].Value > slices[j].Value
+	})
+	return slices
+}
+
+func allocationName(name string) string {
+	if name == "" {
+		return "Unknown"
+	}
+	return name
+}
+
+func parsePortfolioDate(date string) time.Time {
+	parsed, _ := time.Parse(sqlDateParseType, date)
+	return parsed
+}
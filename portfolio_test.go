@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestCalcXIRR(t *testing.T) {
+	date := parsePortfolioDate
+	tests := []struct {
+		name  string
+		flows []cashFlow
+		want  float64
+		err   error
+	}{
+		{"one year at 10%", []cashFlow{
+			{date("2021-01-01"), -1000},
+			{date("2022-01-01"), 1100},
+		}, 0.10, nil},
+		{"two deposits at 10%", []cashFlow{
+			{date("2021-01-01"), -1000},
+			{date("2022-01-01"), -1000},
+			{date("2023-01-01"), 2310},
+		}, 0.10, nil},
+		{"half lost", []cashFlow{
+			{date("2021-01-01"), -1000},
+			{date("2022-01-01"), 500},
+		}, -0.50, nil},
+		// the worked example from the spreadsheet XIRR docs
+		{"irregular dates", []cashFlow{
+			{date("2008-01-01"), -10000},
+			{date("2008-03-01"), 2750},
+			{date("2008-10-30"), 4250},
+			{date("2009-02-15"), 3250},
+			{date("2009-04-01"), 2750},
+		}, 0.373363, nil},
+		{"out of order", []cashFlow{
+			{date("2022-01-01"), 1100},
+			{date("2021-01-01"), -1000},
+		}, 0.10, nil},
+		// the net present value stays below zero at every rate
+		{"no root", []cashFlow{
+			{date("2021-01-01"), -1000},
+			{date("2022-01-01"), 10},
+			{date("2023-01-01"), -1000},
+		}, 0, errNoXIRR},
+		{"only money in", []cashFlow{
+			{date("2021-01-01"), -1000},
+			{date("2022-01-01"), -500},
+		}, 0, errNoXIRR},
+		{"only money out", []cashFlow{
+			{date("2021-01-01"), 1000},
+			{date("2022-01-01"), 500},
+		}, 0, errNoXIRR},
+		{"single flow", []cashFlow{{date("2021-01-01"), -1000}}, 0, errNoXIRR},
+		{"no flows", []cashFlow{}, 0, errNoXIRR},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := calcXIRR(tt.flows)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("calcXIRR() error = %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("calcXIRR() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPortfolioDayReturn(t *testing.T) {
+	tests := []struct {
+		name                   string
+		prior, value, cashFlow float64
+		want                   float64
+	}{
+		{"first purchase", 0, 1000, 1000, 0},
+		{"first purchase rises", 0, 1050, 1000, 0.05},
+		{"no trades", 1000, 1100, 0, 0.10},
+		{"buy counted at the open", 1000, 2100, 1000, 0.05},
+		{"sale counted at the close", 1000, 500, -550, 0.05},
+		{"sold everything", 1000, 0, -1050, 0.05},
+		{"nothing held", 0, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := portfolioDayReturn(tt.prior, tt.value, tt.cashFlow); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("portfolioDayReturn(%g, %g, %g) = %g, want %g", tt.prior, tt.value, tt.cashFlow, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalcPortfolioStats(t *testing.T) {
+	// flat until a second 1000 goes in halfway, then up 10%: the holdings made
+	// 10%, but more of the money was there for the gain than wasn't
+	deposits := []PortfolioDay{
+		{Date: "2021-01-01", Value: 1000, CashFlow: 1000, Invested: 1000, Growth: 1},
+		{Date: "2021-07-02", Value: 1000, Invested: 1000, Growth: 1},
+		{Date: "2021-07-03", Value: 2000, CashFlow: 1000, Invested: 2000, Growth: 1},
+		{Date: "2022-01-01", Value: 2200, Invested: 2000, Growth: 1.1},
+	}
+	steady := []PortfolioDay{
+		{Date: "2021-01-01", Value: 1000, CashFlow: 1000, Invested: 1000, Growth: 1},
+		{Date: "2021-07-02", Value: 1050, Invested: 1000, Growth: 1.05},
+		{Date: "2022-01-01", Value: 1100, Invested: 1000, Growth: 1.1},
+	}
+
+	tests := []struct {
+		name     string
+		days     []PortfolioDay
+		fromDate string
+		want     PortfolioStats
+	}{
+		{"from the first purchase", steady, "2021-01-01",
+			PortfolioStats{StartValue: 0, EndValue: 1100, NetFlows: 1000, Gain: 100, TWR: 10, XIRR: 10, XIRRAnnualized: true, HasXIRR: true}},
+		{"from before the first purchase", steady, "2020-06-01",
+			PortfolioStats{StartValue: 0, EndValue: 1100, NetFlows: 1000, Gain: 100, TWR: 10, XIRR: 10, XIRRAnnualized: true, HasXIRR: true}},
+		// measured from the close before, a year before the end
+		{"from mid-range", steady, "2021-07-02",
+			PortfolioStats{StartValue: 1000, EndValue: 1100, NetFlows: 0, Gain: 100, TWR: 10, XIRR: 10, XIRRAnnualized: true, HasXIRR: true}},
+		{"from the last day", steady, "2022-01-01",
+			PortfolioStats{StartValue: 1050, EndValue: 1100, NetFlows: 0, Gain: 50, TWR: 4.7619, XIRR: 4.7619, HasXIRR: true}},
+		{"time-weighted ignores the deposit", deposits, "2021-01-01",
+			PortfolioStats{StartValue: 0, EndValue: 2200, NetFlows: 2000, Gain: 200, TWR: 10, XIRR: 13.49, XIRRAnnualized: true, HasXIRR: true}},
+		{"after the last day", steady, "2022-06-01", PortfolioStats{}},
+		{"no days", []PortfolioDay{}, "2021-01-01", PortfolioStats{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := calcPortfolioStats(tt.days, tt.fromDate)
+			near := func(a, b float64) bool { return math.Abs(a-b) < 0.01 }
+			if !near(got.StartValue, tt.want.StartValue) || !near(got.EndValue, tt.want.EndValue) ||
+				!near(got.NetFlows, tt.want.NetFlows) || !near(got.Gain, tt.want.Gain) ||
+				!near(float64(got.TWR), float64(tt.want.TWR)) {
+				t.Errorf("calcPortfolioStats() = %+v, want %+v", got, tt.want)
+			}
+			if got.HasXIRR != tt.want.HasXIRR || got.XIRRAnnualized != tt.want.XIRRAnnualized ||
+				!near(float64(got.XIRR), float64(tt.want.XIRR)) {
+				t.Errorf("calcPortfolioStats() XIRR = %f (annualized %t, has %t), want %f (annualized %t, has %t)",
+					got.XIRR, got.XIRRAnnualized, got.HasXIRR, tt.want.XIRR, tt.want.XIRRAnnualized, tt.want.HasXIRR)
+			}
+		})
+	}
+}
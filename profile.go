@@ -19,6 +19,7 @@ type Profile struct {
 	Nickname       string
 	Timezone       string
 	AvatarURL      string
+	CostBasis      string
 	CreateDatetime time.Time
 	Emails         []ProfileEmail
 }
@@ -29,15 +30,16 @@ type Profile struct {
 
 func profileHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		webdata := deps.webdata
+		rc := getRequestContext(r)
+		webdata := rc.webdata
 
-		watcher := checkAuthState(w, r, deps, *deps.logger)
+		watcher := checkAuthState(w, r, deps, *rc.logger)
 		if watcher.WatcherId == 0 {
 			http.Redirect(w, r, "/", http.StatusTemporaryRedirect)
 			return
 		}
 
-		sublog := deps.logger.With().Str("watcher", watcher.EId).Logger()
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Logger()
 
 		params := mux.Vars(r)
 		status := params["status"]
@@ -65,40 +67,34 @@ func profileHandler(deps *Dependencies) http.HandlerFunc {
 		})
 
 		webdata["timezones"] = timezones
+		webdata["costBasisMethods"] = costBasisMethods
 
 		renderTemplate(w, r, deps, sublog, "profile")
 	})
 }
 
 func getProfile(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) (*Profile, error) {
-	db := deps.db
-
 	profile := Profile{}
 
 	profile.Name = watcher.WatcherName
 	profile.Nickname = watcher.WatcherNickname
 	profile.Timezone = watcher.WatcherTimezone
 	profile.AvatarURL = watcher.WatcherPicURL
+	profile.CostBasis = watcher.CostBasisMethod
+	if !isCostBasisMethod(profile.CostBasis) {
+		profile.CostBasis = costBasisFIFO
+	}
 	profile.CreateDatetime = watcher.CreateDatetime
 
-	rows, err := db.Queryx("SELECT * FROM watcher_email WHERE watcher_id=? ORDER BY email_is_primary DESC, email_address", watcher.WatcherId)
+	watcherEmails, err := deps.watchers.GetWatcherEmails(sublog, watcher.WatcherId)
 	if err != nil {
 		sublog.Fatal().Err(err).Msg("failed on SELECT")
 	}
-	defer rows.Close()
 
-	watcherEmail := WatcherEmail{}
 	emails := make([]ProfileEmail, 0)
-	for rows.Next() {
-		err = rows.StructScan(&watcherEmail)
-		if err != nil {
-			sublog.Fatal().Err(err).Msg("failed reading result rows")
-		}
+	for _, watcherEmail := range watcherEmails {
 		emails = append(emails, ProfileEmail{watcherEmail.EmailAddress, watcherEmail.IsPrimary})
 	}
-	if err := rows.Err(); err != nil {
-		sublog.Fatal().Err(err).Msg("failed reading result rows")
-	}
 
 	profile.Emails = emails
 	return &profile, nil
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/sqs"
+)
+
+type QueueMessage struct {
+	Body       string
+	Attributes map[string]string
+	receipt    string
+}
+
+// work handed off to the background workers, SQS in production or a plain
+// in-process list when running locally
+type Queue interface {
+	Send(queueName, body string, attributes map[string]string) error
+	Receive(queueName string, max int) ([]QueueMessage, error)
+	Delete(queueName string, message QueueMessage) error
+	Ping(ctx context.Context, queueName string) error
+}
+
+type sqsQueue struct {
+	awssess *session.Session
+}
+
+// localQueue loses anything still queued when the process exits, which is
+// fine for the refresh tasks it carries
+type localQueue struct {
+	mu       sync.Mutex
+	messages map[string][]QueueMessage
+}
+
+// object methods -------------------------------------------------------------
+
+func (q sqsQueue) queueURL(awssvc *sqs.SQS, queueName string) (*string, error) {
+	urlResult, err := awssvc.GetQueueUrl(&sqs.GetQueueUrlInput{
+		QueueName: &queueName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return urlResult.QueueUrl, nil
+}
+
+func (q sqsQueue) Send(queueName, body string, attributes map[string]string) error {
+	awssvc := sqs.New(q.awssess)
+
+	queueURL, err := q.queueURL(awssvc, queueName)
+	if err != nil {
+		return err
+	}
+
+	messageAttributes := map[string]*sqs.MessageAttributeValue{}
+	for name, value := range attributes {
+		messageAttributes[name] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	_, err = awssvc.SendMessage(&sqs.SendMessageInput{
+		MessageBody:       aws.String(body),
+		MessageAttributes: messageAttributes,
+		QueueUrl:          queueURL,
+	})
+	return err
+}
+
+func (q sqsQueue) Receive(queueName string, max int) ([]QueueMessage, error) {
+	awssvc := sqs.New(q.awssess)
+	messages := make([]QueueMessage, 0)
+
+	queueURL, err := q.queueURL(awssvc, queueName)
+	if err != nil {
+		return messages, err
+	}
+
+	result, err := awssvc.ReceiveMessage(&sqs.ReceiveMessageInput{
+		QueueUrl:              queueURL,
+		MaxNumberOfMessages:   aws.Int64(int64(max)),
+		MessageAttributeNames: []*string{aws.String(sqs.QueueAttributeNameAll)},
+		WaitTimeSeconds:       aws.Int64(20),
+	})
+	if err != nil {
+		return messages, err
+	}
+
+	for _, message := range result.Messages {
+		attributes := map[string]string{}
+		for name, value := range message.MessageAttributes {
+			attributes[name] = aws.StringValue(value.StringValue)
+		}
+		messages = append(messages, QueueMessage{aws.StringValue(message.Body), attributes, aws.StringValue(message.ReceiptHandle)})
+	}
+	return messages, nil
+}
+
+func (q sqsQueue) Delete(queueName string, message QueueMessage) error {
+	awssvc := sqs.New(q.awssess)
+
+	queueURL, err := q.queueURL(awssvc, queueName)
+	if err != nil {
+		return err
+	}
+
+	_, err = awssvc.DeleteMessage(&sqs.DeleteMessageInput{
+		QueueUrl:      queueURL,
+		ReceiptHandle: aws.String(message.receipt),
+	})
+	return err
+}
+
+// the queue exists and we're allowed to see it
+func (q sqsQueue) Ping(ctx context.Context, queueName string) error {
+	_, err := sqs.New(q.awssess).GetQueueUrlWithContext(ctx, &sqs.GetQueueUrlInput{
+		QueueName: &queueName,
+	})
+	return err
+}
+
+func (q *localQueue) Send(queueName, body string, attributes map[string]string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.messages[queueName] = append(q.messages[queueName], QueueMessage{Body: body, Attributes: attributes})
+	return nil
+}
+
+// messages are taken off the queue as they are received, so Delete has
+// nothing left to do
+func (q *localQueue) Receive(queueName string, max int) ([]QueueMessage, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	queued := q.messages[queueName]
+	if max > len(queued) {
+		max = len(queued)
+	}
+	messages := append([]QueueMessage{}, queued[:max]...)
+	q.messages[queueName] = queued[max:]
+	return messages, nil
+}
+
+func (q *localQueue) Delete(queueName string, message QueueMessage) error {
+	return nil
+}
+
+func (q *localQueue) Ping(ctx context.Context, queueName string) error {
+	return nil
+}
+
+// misc -----------------------------------------------------------------------
+
+func newLocalQueue() *localQueue {
+	return &localQueue{messages: map[string][]QueueMessage{}}
+}
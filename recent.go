@@ -19,21 +19,16 @@ type Recent struct {
 // object methods -------------------------------------------------------------
 
 func (r *Recent) createOrUpdate(deps *Dependencies) error {
-	db := deps.db
-
 	if r.TickerId == 0 {
 		return nil
 	}
 
-	var insert_or_update = "INSERT INTO recent (ticker_id, ms_performance_id) VALUES(?, ?) ON DUPLICATE KEY UPDATE ms_performance_id=?, lastseen_datetime=now()"
-	_, err := db.Exec(insert_or_update, r.TickerId, r.MSPerformanceId, r.MSPerformanceId)
-	return err
+	return deps.recents.SaveRecent(*r)
 }
 
 // misc -----------------------------------------------------------------------
 
 func getWatcherRecents(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) []WatcherRecent {
-	db := deps.db
 	sublog = sublog.With().Str("watcherEid", encryptId(deps, *deps.logger, "watcher", watcher.WatcherId)).Logger()
 
 	watcherRecents := make([]WatcherRecent, 0, 30)
@@ -41,36 +36,15 @@ func getWatcherRecents(deps *Dependencies, sublog zerolog.Logger, watcher Watche
 		return watcherRecents
 	}
 
-	rows, err := db.Queryx(`
-	  SELECT watcher_recent.*, ticker.ticker_symbol
-	  FROM watcher_recent
-	  LEFT JOIN ticker USING (ticker_id)
-	  WHERE watcher_id=?
-	  ORDER BY watcher_recent.update_datetime DESC`, watcher.WatcherId)
+	watcherRecents, err := deps.recents.GetWatcherRecents(sublog, watcher.WatcherId)
 	if err != nil {
 		sublog.Error().Err(err).Msg("error with query")
 		return []WatcherRecent{}
 	}
-
-	defer rows.Close()
-	var watcherRecent WatcherRecent
-	for rows.Next() {
-		err = rows.StructScan(&watcherRecent)
-		if err != nil {
-			sublog.Error().Err(err).Msg("error reading row")
-			continue
-		}
-		watcherRecents = append(watcherRecents, watcherRecent)
-	}
-	if err := rows.Err(); err != nil {
-		sublog.Error().Err(err).Msg("error reading rows")
-	}
 	return watcherRecents
 }
 
 func addTickerToWatcherRecents(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, ticker Ticker) ([]WatcherRecent, error) {
-	db := deps.db
-
 	if watcher.WatcherId == 0 {
 		return []WatcherRecent{}, fmt.Errorf("not adding recents for watcherId 0")
 	}
@@ -83,13 +57,13 @@ func addTickerToWatcherRecents(deps *Dependencies, sublog zerolog.Logger, watche
 		}
 
 		// if at max already, need to delete an unlocked one before allowing another
-		var count int32
-		err = db.QueryRowx("SELECT count(*) FROM watcher_recent WHERE watcher_id=?", watcher.WatcherId).Scan(&count)
+		var count int
+		count, err = deps.recents.CountWatcherRecents(watcher.WatcherId)
 		if err != nil {
 			return getWatcherRecents(deps, sublog, watcher), err
 		} else {
-			if count >= maxRecentCount {
-				_, err := db.Exec("DELETE FROM watcher_recent WHERE watcher_id=? AND locked=false ORDER BY update_datetime LIMIT ?", watcher.WatcherId, count-maxRecentCount)
+			if count >= deps.config.MaxRecentCount {
+				err := deps.recents.TrimWatcherRecents(watcher.WatcherId, count-deps.config.MaxRecentCount)
 				if err != nil && errors.Is(err, sql.ErrNoRows) {
 					return getWatcherRecents(deps, sublog, watcher), nil
 				}
@@ -113,24 +87,17 @@ func addTickerToWatcherRecents(deps *Dependencies, sublog zerolog.Logger, watche
 }
 
 func isWatcherRecent(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, ticker Ticker) (bool, error) {
-	db := deps.db
-
-	count := 0
-	err := db.QueryRowx(`SELECT COUNT(*) FROM watcher_recent WHERE watcher_id=? and ticker_id=?`, watcher.WatcherId, ticker.TickerId).Scan(&count)
-	return count == 1, err
+	_, err := deps.recents.GetWatcherRecent(watcher.WatcherId, ticker.TickerId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
 }
 
 func getWatcherRecent(deps *Dependencies, watcher Watcher, ticker Ticker) (WatcherRecent, error) {
-	db := deps.db
-
-	recent := WatcherRecent{}
-	err := db.QueryRowx(`SELECT * FROM watcher_recent WHERE watcher_id=? and ticker_id=?`, watcher.WatcherId, ticker.TickerId).StructScan(&recent)
-	return recent, err
+	return deps.recents.GetWatcherRecent(watcher.WatcherId, ticker.TickerId)
 }
 
 func removeFromWatcherRecents(deps *Dependencies, watcher Watcher, ticker Ticker) error {
-	db := deps.db
-
-	_, err := db.Exec("DELETE FROM watcher_recent WHERE watcher_id=? AND ticker_id=? AND locked=false", watcher.WatcherId, ticker.TickerId)
-	return err
+	return deps.recents.RemoveWatcherRecent(watcher.WatcherId, ticker.TickerId)
 }
@@ -10,9 +10,10 @@ import (
 
 func staticPageHandler(deps *Dependencies, tmplname string) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		watcher := checkAuthState(w, r, deps, *deps.logger)
-		sublog := deps.logger.With().Str("watcher", watcher.EId).Logger()
-		webdata := deps.webdata
+		rc := getRequestContext(r)
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Logger()
+		webdata := rc.webdata
 
 		if tmplname == "home" || tmplname == "terms" || tmplname == "privacy" {
 			webdata["hideRecents"] = true
@@ -21,7 +22,7 @@ func staticPageHandler(deps *Dependencies, tmplname string) http.HandlerFunc {
 			webdata["about"], webdata["commits"], _ = getGithubCommits(deps, sublog)
 		}
 
-		renderTemplate(w, r, deps, *deps.logger, tmplname)
+		renderTemplate(w, r, deps, *rc.logger, tmplname)
 	})
 }
 
@@ -29,14 +30,15 @@ func staticPageHandler(deps *Dependencies, tmplname string) http.HandlerFunc {
 // It writes into a bytes.Buffer before writing to the http.ResponseWriter to catch
 // any errors resulting from populating the template.
 func renderTemplate(w http.ResponseWriter, r *http.Request, deps *Dependencies, sublog zerolog.Logger, tmplname string) error {
+	rc := getRequestContext(r)
 	tmpl := deps.templates
-	config := deps.config
-	webdata := deps.webdata
+	config := rc.config
+	webdata := rc.webdata
 
 	config["template_name"] = tmplname
 	webdata["config"] = config
-	webdata["messages"] = deps.messages
-	webdata["nonce"] = deps.nonce
+	webdata["messages"] = rc.messages
+	webdata["nonce"] = rc.nonce
 
 	// Create a buffer to temporarily write to and check if any errors were encountered.
 	buf := deps.bufpool.Get()
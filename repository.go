@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// storage for the core tables sits behind these, so the same handlers can run
+// on Aurora (MySQL) in production or a SQLite file on a laptop; see
+// Config.StorageBackend and sqlRepository
+
+type TickerRepository interface {
+	GetTicker(tickerId uint64) (Ticker, error)
+	GetTickerBySymbol(symbol string) (Ticker, error)
+	CreateTicker(ticker Ticker) (uint64, error)
+	UpdateTicker(ticker Ticker) error
+	UpdateTickerQuote(ticker Ticker) error
+	UpdateTickerFavIcon(ticker Ticker) error
+	CountTickerMoves(since time.Time) (advancing, declining, unchanged int, err error)
+
+	GetTickerAttribute(tickerId uint64, attributeName string) (TickerAttribute, error)
+	GetTickerAttributes(sublog zerolog.Logger, tickerId uint64) ([]TickerAttribute, error)
+	CreateTickerAttribute(attribute TickerAttribute) error
+	UpdateTickerAttribute(attribute TickerAttribute) error
+
+	GetTickerDailyId(tickerId uint64, priceDatetime time.Time) uint64
+	CountTickerDailies(tickerId uint64, fromDatetime, toDatetime string) (int, error)
+	GetTickerDailies(sublog zerolog.Logger, tickerId uint64, fromDate, beforeDate string) ([]TickerDaily, error)
+	DeleteTickerDailies(tickerId uint64) error
+	GetLastTickerDaily(tickerId uint64) (TickerDaily, error)
+	CreateTickerDaily(daily TickerDaily) error
+	UpdateTickerDaily(daily TickerDaily) error
+
+	GetTickerIntradays(sublog zerolog.Logger, tickerId uint64, fromDatetime, toDatetime string) ([]TickerIntraday, error)
+	CreateTickerIntraday(intraday TickerIntraday) error
+
+	GetTickerDescription(tickerId uint64) (TickerDescription, error)
+	CreateTickerDescription(description TickerDescription) error
+	UpdateTickerDescription(description TickerDescription) error
+
+	GetTickerUpDown(tickerId uint64, upDownDate sql.NullTime, upDownFirm string) (TickerUpDown, error)
+	GetTickerUpDowns(sublog zerolog.Logger, tickerId uint64, since time.Time) ([]TickerUpDown, error)
+	CreateTickerUpDown(upDown TickerUpDown) error
+
+	GetTickerSplit(tickerId uint64, splitDate time.Time) (TickerSplit, error)
+	GetTickerSplits(sublog zerolog.Logger, tickerId uint64) ([]TickerSplit, error)
+	GetSplitTickerIds() ([]uint64, error)
+	CreateTickerSplit(split TickerSplit) error
+
+	GetTickerDividend(tickerId uint64, dividendDate time.Time) (TickerDividend, error)
+	GetTickerDividends(sublog zerolog.Logger, tickerId uint64) ([]TickerDividend, error)
+	CreateTickerDividend(dividend TickerDividend) error
+	UpdateTickerDividend(dividend TickerDividend) error
+
+	ReplaceTickerFinancials(tickerId uint64, financials []Financials) error
+}
+
+type MarketIndexRepository interface {
+	GetMarketIndexBySymbol(symbol string) (MarketIndex, error)
+	CreateMarketIndex(marketIndex MarketIndex) error
+
+	GetMarketIndexDaily(marketIndexId uint64, priceDate string) (MarketIndexDaily, error)
+	GetMarketIndexDailies(sublog zerolog.Logger, marketIndexId uint64, fromDate, beforeDate string) ([]MarketIndexDaily, error)
+	GetLastMarketIndexDailies(sublog zerolog.Logger, marketIndexId uint64, count int) ([]MarketIndexDaily, error)
+	GetPriorMarketIndexClose(marketIndexId uint64, beforeDate string) (float64, error)
+	SaveMarketIndexDaily(daily MarketIndexDaily) error
+
+	GetMarketIndexIntradays(sublog zerolog.Logger, marketIndexId uint64, fromDatetime, toDatetime string) ([]MarketIndexIntraday, error)
+	CreateMarketIndexIntraday(intraday MarketIndexIntraday) error
+}
+
+type WatcherRepository interface {
+	GetWatcher(watcherId uint64) (Watcher, error)
+	GetWatcherIdBySession(session string) (uint64, error)
+	GetWatcherIdByEmail(email string) (uint64, error)
+	CountWatchersWithNickname(watcherId uint64, nickname string) (int, error)
+	CreateWatcher(watcher Watcher) error
+	UpdateWatcher(watcher Watcher) error
+	UpdateWatcherOAuth(watcher Watcher) error
+	UpdateWatcherCostBasisMethod(watcherId uint64, method string) error
+	ClearWatcherSession(session string) error
+	GetWatcherEmails(sublog zerolog.Logger, watcherId uint64) ([]WatcherEmail, error)
+	AddWatcherEmail(watcherId uint64, email string, isPrimary bool) error
+}
+
+type RecentRepository interface {
+	GetWatcherRecents(sublog zerolog.Logger, watcherId uint64) ([]WatcherRecent, error)
+	GetWatcherRecent(watcherId, tickerId uint64) (WatcherRecent, error)
+	CountWatcherRecents(watcherId uint64) (int, error)
+	CreateWatcherRecent(recent WatcherRecent) error
+	TouchWatcherRecent(recent WatcherRecent) error
+	LockWatcherRecent(watcherId, tickerId uint64, locked bool) error
+	TrimWatcherRecents(watcherId uint64, count int) error
+	RemoveWatcherRecent(watcherId, tickerId uint64) error
+	SaveRecent(recent Recent) error
+	GetRecentTickerIds() ([]uint64, error)
+}
+
+type WatchlistRepository interface {
+	GetWatcherWatchlists(sublog zerolog.Logger, watcherId uint64) ([]Watchlist, error)
+	GetWatchlistByShareToken(shareToken string) (Watchlist, error)
+	CreateWatchlist(watchlist Watchlist) (uint64, error)
+	UpdateWatchlist(watchlist Watchlist) error
+	DeleteWatchlist(watchlistId uint64) error
+	GetWatchlistTickers(sublog zerolog.Logger, watchlistId uint64) ([]WatchlistTicker, error)
+	AddWatchlistTicker(entry WatchlistTicker) error
+	UpdateWatchlistTicker(entry WatchlistTicker) error
+	RemoveWatchlistTicker(watchlistId, tickerId uint64) error
+}
+
+type ArticleRepository interface {
+	GetTickerArticles(sublog zerolog.Logger, tickerId uint64, fromDate string, max int) ([]WebArticle, error)
+	GetRecentArticles(sublog zerolog.Logger, fromDate string, max int) ([]WebArticle, error)
+	GetArticleId(sourceId uint64, externalId string) (uint64, error)
+	CreateArticle(article Article) (uint64, error)
+	CreateArticleTicker(articleTicker ArticleTicker) error
+	GetSourceId(sourceName string) (uint64, error)
+	CreateSource(source Source) (uint64, error)
+}
+
+type MoverRepository interface {
+	GetLatestMoverDate() (time.Time, error)
+	GetMovers(sublog zerolog.Logger, moverDate time.Time) ([]Mover, error)
+	ReplaceMovers(moverDate time.Time, movers []Mover) error
+}
+
+type LastDoneRepository interface {
+	GetLastDone(activity, uniqueKey string) (LastDone, error)
+	SaveLastDone(lastdone LastDone) error
+}
@@ -0,0 +1,991 @@
+package main
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
+)
+
+const (
+	mysqlDialect  = "mysql"
+	sqliteDialect = "sqlite3"
+)
+
+// sqlRepository implements every repository on a sqlx connection. The SQL is
+// kept to what MySQL and SQLite both understand; the few spots where they
+// differ (upserts, GROUP_CONCAT) switch on the dialect
+type sqlRepository struct {
+	db      *sqlx.DB
+	dialect string
+}
+
+// object methods -------------------------------------------------------------
+
+// tickers --------------------------------------------------------------------
+
+func (r sqlRepository) GetTicker(tickerId uint64) (Ticker, error) {
+	var ticker Ticker
+	err := r.db.QueryRowx("SELECT * FROM ticker WHERE ticker_id=?", tickerId).StructScan(&ticker)
+	return ticker, err
+}
+
+func (r sqlRepository) GetTickerBySymbol(symbol string) (Ticker, error) {
+	var ticker Ticker
+	err := r.db.QueryRowx("SELECT * FROM ticker WHERE ticker_symbol=?", symbol).StructScan(&ticker)
+	return ticker, err
+}
+
+func (r sqlRepository) CreateTicker(t Ticker) (uint64, error) {
+	insert := `INSERT INTO ticker (ticker_symbol, ticker_type, ticker_market, exchange_id, ticker_name, company_name, address, city, state, zip, country, website, phone, sector, industry, market_price, market_prev_close, market_volume, fetch_datetime)
+	           VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.Exec(insert, t.TickerSymbol, t.TickerType, t.TickerMarket, t.ExchangeId, t.TickerName, t.CompanyName, t.Address, t.City, t.State, t.Zip, t.Country, t.Website, t.Phone, t.Sector, t.Industry, t.MarketPrice, t.MarketPrevClose, t.MarketVolume, t.FetchDatetime)
+	if err != nil {
+		return 0, err
+	}
+	tickerId, err := res.LastInsertId()
+	return uint64(tickerId), err
+}
+
+func (r sqlRepository) UpdateTicker(t Ticker) error {
+	update := "UPDATE ticker SET ticker_type=?, ticker_market=?, exchange_id=?, ticker_name=?, company_name=?, address=?, city=?, state=?, zip=?, country=?, website=?, phone=?, sector=?, industry=?, market_price=?, market_prev_close=?, market_volume=?, market_price_datetime=?, favicon_s3key=?, fetch_datetime=CURRENT_TIMESTAMP WHERE ticker_id=?"
+	_, err := r.db.Exec(update, t.TickerType, t.TickerMarket, t.ExchangeId, t.TickerName, t.CompanyName, t.Address, t.City, t.State, t.Zip, t.Country, t.Website, t.Phone, t.Sector, t.Industry, t.MarketPrice, t.MarketPrevClose, t.MarketVolume, t.MarketPriceDatetime, t.FavIconS3Key, t.TickerId)
+	return err
+}
+
+// unlike UpdateTicker this leaves fetch_datetime alone, the info isn't any fresher
+func (r sqlRepository) UpdateTickerFavIcon(t Ticker) error {
+	update := "UPDATE ticker SET favicon_s3key=? WHERE ticker_id=?"
+	_, err := r.db.Exec(update, t.FavIconS3Key, t.TickerId)
+	return err
+}
+
+func (r sqlRepository) UpdateTickerQuote(t Ticker) error {
+	update := "UPDATE ticker SET market_price=?, market_volume=?, market_prev_close=?, market_price_datetime=? WHERE ticker_id=?"
+	_, err := r.db.Exec(update, t.MarketPrice, t.MarketVolume, t.MarketPrevClose, t.MarketPriceDatetime, t.TickerId)
+	return err
+}
+
+func (r sqlRepository) GetTickerAttribute(tickerId uint64, attributeName string) (TickerAttribute, error) {
+	var attribute TickerAttribute
+	err := r.db.QueryRowx("SELECT * FROM ticker_attribute WHERE ticker_id=? AND attribute_name=?", tickerId, attributeName).StructScan(&attribute)
+	return attribute, err
+}
+
+func (r sqlRepository) GetTickerAttributes(sublog zerolog.Logger, tickerId uint64) ([]TickerAttribute, error) {
+	var attribute TickerAttribute
+	attributes := make([]TickerAttribute, 0)
+
+	rows, err := r.db.Queryx(
+		`SELECT ticker_attribute.*, definition.definition FROM ticker_attribute LEFT JOIN definition ON (REPLACE(attribute_name, '_', ' ') = term) WHERE ticker_id=?`, tickerId)
+	if err != nil {
+		return attributes, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&attribute)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			attributes = append(attributes, attribute)
+		}
+	}
+	return attributes, rows.Err()
+}
+
+func (r sqlRepository) CreateTickerAttribute(a TickerAttribute) error {
+	insert := "INSERT INTO ticker_attribute (ticker_id, attribute_name, attribute_comment, attribute_value) VALUES (?, ?, ?, ?)"
+	_, err := r.db.Exec(insert, a.TickerId, a.AttributeName, a.AttributeComment, a.AttributeValue)
+	return err
+}
+
+func (r sqlRepository) UpdateTickerAttribute(a TickerAttribute) error {
+	update := "UPDATE ticker_attribute SET attribute_value=? WHERE ticker_id=? AND attribute_name=? AND attribute_comment=?"
+	_, err := r.db.Exec(update, a.AttributeValue, a.TickerId, a.AttributeName, a.AttributeComment)
+	return err
+}
+
+func (r sqlRepository) GetTickerDailyId(tickerId uint64, priceDatetime time.Time) uint64 {
+	var tickerDailyId uint64
+	r.db.QueryRowx("SELECT ticker_daily_id FROM ticker_daily WHERE ticker_id=? AND price_datetime=?", tickerId, priceDatetime).Scan(&tickerDailyId)
+	return tickerDailyId
+}
+
+func (r sqlRepository) CountTickerDailies(tickerId uint64, fromDatetime, toDatetime string) (int, error) {
+	var count int
+	err := r.db.QueryRowx("SELECT COUNT(*) FROM ticker_daily WHERE ticker_id=? AND price_datetime BETWEEN ? AND ?", tickerId, fromDatetime, toDatetime).Scan(&count)
+	return count, err
+}
+
+// an empty beforeDate leaves the range open-ended
+func (r sqlRepository) GetTickerDailies(sublog zerolog.Logger, tickerId uint64, fromDate, beforeDate string) ([]TickerDaily, error) {
+	var daily TickerDaily
+	dailies := make([]TickerDaily, 0)
+
+	query := "SELECT * FROM ticker_daily WHERE ticker_id=? AND volume > 0 AND price_datetime > ?"
+	args := []interface{}{tickerId, fromDate}
+	if beforeDate != "" {
+		query += " AND price_datetime < ?"
+		args = append(args, beforeDate)
+	}
+	rows, err := r.db.Queryx(query+" ORDER BY price_datetime", args...)
+	if err != nil {
+		return dailies, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&daily)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			dailies = append(dailies, daily)
+		}
+	}
+	return dailies, rows.Err()
+}
+
+func (r sqlRepository) DeleteTickerDailies(tickerId uint64) error {
+	_, err := r.db.Exec("DELETE FROM ticker_daily WHERE ticker_id=?", tickerId)
+	return err
+}
+
+func (r sqlRepository) GetLastTickerDaily(tickerId uint64) (TickerDaily, error) {
+	var daily TickerDaily
+	err := r.db.QueryRowx("SELECT * FROM ticker_daily WHERE ticker_id=? ORDER BY price_datetime DESC LIMIT 1", tickerId).StructScan(&daily)
+	return daily, err
+}
+
+func (r sqlRepository) CreateTickerDaily(td TickerDaily) error {
+	insert := "INSERT INTO ticker_daily (ticker_id, price_datetime, open_price, high_price, low_price, close_price, volume) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	_, err := r.db.Exec(insert, td.TickerId, td.PriceDatetime, td.OpenPrice, td.HighPrice, td.LowPrice, td.ClosePrice, td.Volume)
+	return err
+}
+
+func (r sqlRepository) UpdateTickerDaily(td TickerDaily) error {
+	update := "UPDATE ticker_daily SET open_price=?, high_price=?, low_price=?, close_price=?, volume=?, update_datetime=CURRENT_TIMESTAMP WHERE ticker_id=? AND price_datetime=?"
+	_, err := r.db.Exec(update, td.OpenPrice, td.HighPrice, td.LowPrice, td.ClosePrice, td.Volume, td.TickerId, td.PriceDatetime)
+	return err
+}
+
+// only tickers quoted since the given time count, so a ticker nobody has
+// looked at today doesn't report yesterday's move
+func (r sqlRepository) CountTickerMoves(since time.Time) (int, int, int, error) {
+	var advancing, declining, unchanged int
+	err := r.db.QueryRowx(
+		`SELECT COALESCE(SUM(CASE WHEN market_price > market_prev_close THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN market_price < market_prev_close THEN 1 ELSE 0 END), 0),
+		        COALESCE(SUM(CASE WHEN market_price = market_prev_close THEN 1 ELSE 0 END), 0)
+		   FROM ticker WHERE market_prev_close > 0 AND market_price_datetime >= ?`,
+		since).Scan(&advancing, &declining, &unchanged)
+	return advancing, declining, unchanged, err
+}
+
+func (r sqlRepository) GetTickerIntradays(sublog zerolog.Logger, tickerId uint64, fromDatetime, toDatetime string) ([]TickerIntraday, error) {
+	var intraday TickerIntraday
+	intradays := make([]TickerIntraday, 0)
+
+	rows, err := r.db.Queryx(
+		`SELECT * FROM ticker_intraday WHERE ticker_id=? AND price_date >= ? AND price_date <= ? ORDER BY price_date`,
+		tickerId, fromDatetime, toDatetime)
+	if err != nil {
+		return intradays, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&intraday)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			intradays = append(intradays, intraday)
+		}
+	}
+	return intradays, rows.Err()
+}
+
+// a sample we already have (same ticker, same quote time) is left alone
+func (r sqlRepository) CreateTickerIntraday(ti TickerIntraday) error {
+	insert := "INSERT INTO ticker_intraday (ticker_id, price_date, last_price, volume) VALUES (?, ?, ?, ?)"
+	if r.dialect == sqliteDialect {
+		insert += " ON CONFLICT (ticker_id, price_date) DO NOTHING"
+	} else {
+		insert += " ON DUPLICATE KEY UPDATE intraday_id=intraday_id"
+	}
+	_, err := r.db.Exec(insert, ti.TickerId, ti.PriceDate, ti.LastPrice, ti.Volume)
+	return err
+}
+
+func (r sqlRepository) GetTickerDescription(tickerId uint64) (TickerDescription, error) {
+	var description TickerDescription
+	err := r.db.QueryRowx("SELECT * FROM ticker_description WHERE ticker_id=?", tickerId).StructScan(&description)
+	return description, err
+}
+
+func (r sqlRepository) CreateTickerDescription(td TickerDescription) error {
+	_, err := r.db.Exec("INSERT INTO ticker_description (ticker_id, business_summary) VALUES (?, ?)", td.TickerId, td.BusinessSummary)
+	return err
+}
+
+func (r sqlRepository) UpdateTickerDescription(td TickerDescription) error {
+	_, err := r.db.Exec("UPDATE ticker_description SET business_summary=? WHERE description_id=?", td.BusinessSummary, td.TickerDescriptionId)
+	return err
+}
+
+func (r sqlRepository) GetTickerUpDown(tickerId uint64, upDownDate sql.NullTime, upDownFirm string) (TickerUpDown, error) {
+	var upDown TickerUpDown
+	err := r.db.QueryRowx("SELECT * FROM ticker_updown WHERE ticker_id=? AND updown_date=? AND updown_firm=?", tickerId, upDownDate, upDownFirm).StructScan(&upDown)
+	return upDown, err
+}
+
+func (r sqlRepository) GetTickerUpDowns(sublog zerolog.Logger, tickerId uint64, since time.Time) ([]TickerUpDown, error) {
+	var upDown TickerUpDown
+	upDowns := make([]TickerUpDown, 0)
+
+	rows, err := r.db.Queryx(
+		"SELECT * FROM ticker_updown WHERE ticker_id=? AND updown_date > ? ORDER BY updown_date DESC",
+		tickerId, since.Format(sqlDateParseType))
+	if err != nil {
+		return upDowns, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&upDown)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+		} else {
+			upDowns = append(upDowns, upDown)
+		}
+	}
+	return upDowns, rows.Err()
+}
+
+func (r sqlRepository) CreateTickerUpDown(tud TickerUpDown) error {
+	insert := "INSERT INTO ticker_updown (ticker_id, updown_action, updown_fromgrade, updown_tograde, updown_date, updown_firm) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := r.db.Exec(insert, tud.TickerId, tud.UpDownAction, tud.UpDownFromGrade, tud.UpDownToGrade, tud.UpDownDate, tud.UpDownFirm)
+	return err
+}
+
+func (r sqlRepository) GetTickerSplit(tickerId uint64, splitDate time.Time) (TickerSplit, error) {
+	var split TickerSplit
+	err := r.db.QueryRowx("SELECT * FROM ticker_split WHERE ticker_id=? AND split_date=?", tickerId, splitDate).StructScan(&split)
+	return split, err
+}
+
+func (r sqlRepository) GetTickerSplits(sublog zerolog.Logger, tickerId uint64) ([]TickerSplit, error) {
+	var split TickerSplit
+	splits := make([]TickerSplit, 0)
+
+	rows, err := r.db.Queryx("SELECT * FROM ticker_split WHERE ticker_id=? ORDER BY split_date", tickerId)
+	if err != nil {
+		return splits, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&split)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			splits = append(splits, split)
+		}
+	}
+	return splits, rows.Err()
+}
+
+// every ticker that has split at least once
+func (r sqlRepository) GetSplitTickerIds() ([]uint64, error) {
+	var tickerId uint64
+	tickerIds := make([]uint64, 0)
+
+	rows, err := r.db.Queryx("SELECT DISTINCT ticker_id FROM ticker_split ORDER BY ticker_id")
+	if err != nil {
+		return tickerIds, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&tickerId)
+		if err != nil {
+			return tickerIds, err
+		}
+		tickerIds = append(tickerIds, tickerId)
+	}
+	return tickerIds, rows.Err()
+}
+
+func (r sqlRepository) CreateTickerSplit(ts TickerSplit) error {
+	_, err := r.db.Exec("INSERT INTO ticker_split (ticker_id, split_date, split_ratio) VALUES (?, ?, ?)", ts.TickerId, ts.SplitDate, ts.SplitRatio)
+	return err
+}
+
+// dividend_date is a DATE, so it is matched and stored as the UTC date; a
+// time.Time would carry the provider's time of day and never match
+func (r sqlRepository) GetTickerDividend(tickerId uint64, dividendDate time.Time) (TickerDividend, error) {
+	var dividend TickerDividend
+	err := r.db.QueryRowx("SELECT * FROM ticker_dividend WHERE ticker_id=? AND dividend_date=?", tickerId, dividendDate.UTC().Format(sqlDateParseType)).StructScan(&dividend)
+	return dividend, err
+}
+
+func (r sqlRepository) GetTickerDividends(sublog zerolog.Logger, tickerId uint64) ([]TickerDividend, error) {
+	var dividend TickerDividend
+	dividends := make([]TickerDividend, 0)
+
+	rows, err := r.db.Queryx("SELECT * FROM ticker_dividend WHERE ticker_id=? ORDER BY dividend_date", tickerId)
+	if err != nil {
+		return dividends, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&dividend)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			dividends = append(dividends, dividend)
+		}
+	}
+	return dividends, rows.Err()
+}
+
+func (r sqlRepository) CreateTickerDividend(td TickerDividend) error {
+	_, err := r.db.Exec("INSERT INTO ticker_dividend (ticker_id, dividend_date, amount) VALUES (?, ?, ?)", td.TickerId, td.DividendDate.UTC().Format(sqlDateParseType), td.Amount)
+	return err
+}
+
+func (r sqlRepository) UpdateTickerDividend(td TickerDividend) error {
+	_, err := r.db.Exec("UPDATE ticker_dividend SET amount=?, update_datetime=CURRENT_TIMESTAMP WHERE ticker_dividend_id=?", td.Amount, td.TickerDividendId)
+	return err
+}
+
+// in one transaction, so the charts never see a half-loaded set
+func (r sqlRepository) ReplaceTickerFinancials(tickerId uint64, financials []Financials) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM financials WHERE ticker_id=?", tickerId)
+	if err != nil {
+		return err
+	}
+	insert := `INSERT INTO financials (ticker_id, form_name, form_term_name, chart_name, chart_datetime, chart_type, is_percentage, chart_value)
+	           VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, f := range financials {
+		_, err = tx.Exec(insert, tickerId, f.FormName, f.FormTermName, f.ChartName, f.ChartDatetime, f.ChartType, f.IsPercentage, f.ChartValue)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// market indexes -------------------------------------------------------------
+
+// spelled out, as Aurora's marketindex_daily has columns the app doesn't use
+const marketIndexDailyColumns = "marketindex_daily_id, marketindex_id, price_date, open_price, high_price, low_price, close_price, volume, create_datetime, update_datetime"
+
+func (r sqlRepository) GetMarketIndexBySymbol(symbol string) (MarketIndex, error) {
+	var marketIndex MarketIndex
+	err := r.db.QueryRowx("SELECT * FROM marketindex WHERE marketindex_symbol=?", symbol).StructScan(&marketIndex)
+	return marketIndex, err
+}
+
+func (r sqlRepository) CreateMarketIndex(mi MarketIndex) error {
+	insert := "INSERT INTO marketindex (marketindex_symbol, marketindex_mic, marketindex_name, marketindex_has_intraday, marketindex_has_eod) VALUES (?, ?, ?, ?, ?)"
+	_, err := r.db.Exec(insert, mi.MarketIndexSymbol, mi.MarketIndexMic, mi.MarketIndexName, mi.HasIntraday, mi.HasEOD)
+	return err
+}
+
+func (r sqlRepository) GetMarketIndexDaily(marketIndexId uint64, priceDate string) (MarketIndexDaily, error) {
+	var daily MarketIndexDaily
+	err := r.db.QueryRowx("SELECT "+marketIndexDailyColumns+" FROM marketindex_daily WHERE marketindex_id=? AND price_date=?", marketIndexId, priceDate).StructScan(&daily)
+	return daily, err
+}
+
+// an empty beforeDate leaves the range open-ended
+func (r sqlRepository) GetMarketIndexDailies(sublog zerolog.Logger, marketIndexId uint64, fromDate, beforeDate string) ([]MarketIndexDaily, error) {
+	query := "SELECT " + marketIndexDailyColumns + " FROM marketindex_daily WHERE marketindex_id=? AND price_date >= ?"
+	args := []interface{}{marketIndexId, fromDate}
+	if beforeDate != "" {
+		query += " AND price_date < ?"
+		args = append(args, beforeDate)
+	}
+	return r.getMarketIndexDailies(sublog, query+" ORDER BY price_date", args...)
+}
+
+// the last count days that traded, oldest first
+func (r sqlRepository) GetLastMarketIndexDailies(sublog zerolog.Logger, marketIndexId uint64, count int) ([]MarketIndexDaily, error) {
+	return r.getMarketIndexDailies(sublog,
+		`SELECT * FROM (
+		   SELECT `+marketIndexDailyColumns+` FROM marketindex_daily WHERE marketindex_id=? AND volume > 0
+		     ORDER BY price_date DESC LIMIT ?) DT1
+		 ORDER BY price_date`,
+		marketIndexId, count)
+}
+
+func (r sqlRepository) GetPriorMarketIndexClose(marketIndexId uint64, beforeDate string) (float64, error) {
+	var closePrice float64
+	err := r.db.QueryRowx("SELECT close_price FROM marketindex_daily WHERE marketindex_id=? AND price_date < ? ORDER BY price_date DESC LIMIT 1", marketIndexId, beforeDate).Scan(&closePrice)
+	return closePrice, err
+}
+
+func (r sqlRepository) SaveMarketIndexDaily(mid MarketIndexDaily) error {
+	insert := "INSERT INTO marketindex_daily (marketindex_id, price_date, open_price, high_price, low_price, close_price, volume) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	if r.dialect == sqliteDialect {
+		insert += " ON CONFLICT (marketindex_id, price_date) DO UPDATE SET open_price=excluded.open_price, high_price=excluded.high_price, low_price=excluded.low_price, close_price=excluded.close_price, volume=excluded.volume, update_datetime=CURRENT_TIMESTAMP"
+	} else {
+		insert += " ON DUPLICATE KEY UPDATE open_price=VALUES(open_price), high_price=VALUES(high_price), low_price=VALUES(low_price), close_price=VALUES(close_price), volume=VALUES(volume), update_datetime=CURRENT_TIMESTAMP"
+	}
+	_, err := r.db.Exec(insert, mid.MarketIndexId, mid.PriceDate, mid.OpenPrice, mid.HighPrice, mid.LowPrice, mid.ClosePrice, mid.Volume)
+	return err
+}
+
+func (r sqlRepository) GetMarketIndexIntradays(sublog zerolog.Logger, marketIndexId uint64, fromDatetime, toDatetime string) ([]MarketIndexIntraday, error) {
+	var intraday MarketIndexIntraday
+	intradays := make([]MarketIndexIntraday, 0)
+
+	rows, err := r.db.Queryx(
+		`SELECT * FROM marketindex_intraday WHERE marketindex_id=? AND price_date >= ? AND price_date <= ? ORDER BY price_date`,
+		marketIndexId, fromDatetime, toDatetime)
+	if err != nil {
+		return intradays, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&intraday)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			intradays = append(intradays, intraday)
+		}
+	}
+	return intradays, rows.Err()
+}
+
+// a sample we already have (same index, same quote time) is left alone
+func (r sqlRepository) CreateMarketIndexIntraday(mii MarketIndexIntraday) error {
+	insert := "INSERT INTO marketindex_intraday (marketindex_id, price_date, last_price, volume) VALUES (?, ?, ?, ?)"
+	if r.dialect == sqliteDialect {
+		insert += " ON CONFLICT (marketindex_id, price_date) DO NOTHING"
+	} else {
+		insert += " ON DUPLICATE KEY UPDATE intraday_id=intraday_id"
+	}
+	_, err := r.db.Exec(insert, mii.MarketIndexId, mii.PriceDate, mii.LastPrice, mii.Volume)
+	return err
+}
+
+func (r sqlRepository) getMarketIndexDailies(sublog zerolog.Logger, query string, args ...interface{}) ([]MarketIndexDaily, error) {
+	var daily MarketIndexDaily
+	dailies := make([]MarketIndexDaily, 0)
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		return dailies, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&daily)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading result rows")
+		} else {
+			dailies = append(dailies, daily)
+		}
+	}
+	return dailies, rows.Err()
+}
+
+// watchers -------------------------------------------------------------------
+
+func (r sqlRepository) GetWatcher(watcherId uint64) (Watcher, error) {
+	var watcher Watcher
+	err := r.db.QueryRowx("SELECT * FROM watcher WHERE watcher_id=?", watcherId).StructScan(&watcher)
+	return watcher, err
+}
+
+func (r sqlRepository) GetWatcherIdBySession(session string) (uint64, error) {
+	var watcherId uint64
+	err := r.db.QueryRowx("SELECT watcher_id FROM watcher WHERE session_id=?", session).Scan(&watcherId)
+	return watcherId, err
+}
+
+func (r sqlRepository) GetWatcherIdByEmail(email string) (uint64, error) {
+	var watcherId uint64
+	err := r.db.QueryRowx("SELECT watcher_id FROM watcher_email WHERE email_address=?", email).Scan(&watcherId)
+	return watcherId, err
+}
+
+func (r sqlRepository) CountWatchersWithNickname(watcherId uint64, nickname string) (int, error) {
+	var count int
+	err := r.db.QueryRowx("SELECT count(*) FROM watcher WHERE watcher_id != ? AND watcher_nickname=?", watcherId, nickname).Scan(&count)
+	return count, err
+}
+
+func (r sqlRepository) CreateWatcher(w Watcher) error {
+	insert := "INSERT INTO watcher (watcher_sub, watcher_name, watcher_nickname, watcher_status, watcher_pic_url, session_id) VALUES (?, ?, ?, ?, ?, ?)"
+	_, err := r.db.Exec(insert, w.WatcherSub, w.WatcherName, w.WatcherNickname, w.WatcherStatus, w.WatcherPicURL, w.SessionId)
+	return err
+}
+
+func (r sqlRepository) UpdateWatcher(w Watcher) error {
+	update := "UPDATE watcher SET watcher_name=?, watcher_nickname=?, update_datetime=CURRENT_TIMESTAMP WHERE watcher_id=?"
+	_, err := r.db.Exec(update, w.WatcherName, w.WatcherNickname, w.WatcherId)
+	return err
+}
+
+func (r sqlRepository) UpdateWatcherOAuth(w Watcher) error {
+	update := "UPDATE watcher SET watcher_name=?, watcher_pic_url=?, session_id=? WHERE watcher_id=?"
+	_, err := r.db.Exec(update, w.WatcherName, w.WatcherPicURL, w.SessionId, w.WatcherId)
+	return err
+}
+
+func (r sqlRepository) UpdateWatcherCostBasisMethod(watcherId uint64, method string) error {
+	_, err := r.db.Exec("UPDATE watcher SET costbasis_method=? WHERE watcher_id=?", method, watcherId)
+	return err
+}
+
+func (r sqlRepository) ClearWatcherSession(session string) error {
+	_, err := r.db.Exec("UPDATE watcher SET session_id='' WHERE session_id=?", session)
+	return err
+}
+
+func (r sqlRepository) GetWatcherEmails(sublog zerolog.Logger, watcherId uint64) ([]WatcherEmail, error) {
+	var watcherEmail WatcherEmail
+	watcherEmails := make([]WatcherEmail, 0)
+
+	rows, err := r.db.Queryx("SELECT * FROM watcher_email WHERE watcher_id=? ORDER BY email_is_primary DESC, email_address", watcherId)
+	if err != nil {
+		return watcherEmails, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&watcherEmail)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+			continue
+		}
+		watcherEmails = append(watcherEmails, watcherEmail)
+	}
+	return watcherEmails, rows.Err()
+}
+
+// an address already on file is left alone
+func (r sqlRepository) AddWatcherEmail(watcherId uint64, email string, isPrimary bool) error {
+	insert := "INSERT INTO watcher_email (watcher_id, email_address, email_is_primary) VALUES (?, ?, ?)"
+	if r.dialect == sqliteDialect {
+		insert += " ON CONFLICT (email_address) DO NOTHING"
+	} else {
+		insert += " ON DUPLICATE KEY UPDATE watcher_id=watcher_id"
+	}
+	_, err := r.db.Exec(insert, watcherId, email, isPrimary)
+	return err
+}
+
+// recents --------------------------------------------------------------------
+
+func (r sqlRepository) GetWatcherRecents(sublog zerolog.Logger, watcherId uint64) ([]WatcherRecent, error) {
+	var watcherRecent WatcherRecent
+	watcherRecents := make([]WatcherRecent, 0, 30)
+
+	rows, err := r.db.Queryx(`
+	  SELECT watcher_recent.*, ticker.ticker_symbol
+	  FROM watcher_recent
+	  LEFT JOIN ticker USING (ticker_id)
+	  WHERE watcher_id=?
+	  ORDER BY watcher_recent.update_datetime DESC`, watcherId)
+	if err != nil {
+		return watcherRecents, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&watcherRecent)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error reading row")
+			continue
+		}
+		watcherRecents = append(watcherRecents, watcherRecent)
+	}
+	return watcherRecents, rows.Err()
+}
+
+func (r sqlRepository) GetWatcherRecent(watcherId, tickerId uint64) (WatcherRecent, error) {
+	var recent WatcherRecent
+	err := r.db.QueryRowx("SELECT * FROM watcher_recent WHERE watcher_id=? AND ticker_id=?", watcherId, tickerId).StructScan(&recent)
+	return recent, err
+}
+
+func (r sqlRepository) CountWatcherRecents(watcherId uint64) (int, error) {
+	var count int
+	err := r.db.QueryRowx("SELECT count(*) FROM watcher_recent WHERE watcher_id=?", watcherId).Scan(&count)
+	return count, err
+}
+
+func (r sqlRepository) CreateWatcherRecent(wr WatcherRecent) error {
+	_, err := r.db.Exec("INSERT INTO watcher_recent (watcher_id, ticker_id, locked) VALUES (?, ?, ?)", wr.WatcherId, wr.TickerId, wr.Locked)
+	return err
+}
+
+// bumps the recent to the front of the list
+func (r sqlRepository) TouchWatcherRecent(wr WatcherRecent) error {
+	update := "UPDATE watcher_recent SET locked=?, update_datetime=CURRENT_TIMESTAMP WHERE watcher_id=? AND ticker_id=?"
+	_, err := r.db.Exec(update, wr.Locked, wr.WatcherId, wr.TickerId)
+	return err
+}
+
+func (r sqlRepository) LockWatcherRecent(watcherId, tickerId uint64, locked bool) error {
+	_, err := r.db.Exec("UPDATE watcher_recent SET locked=? WHERE watcher_id=? AND ticker_id=?", locked, watcherId, tickerId)
+	return err
+}
+
+// drop the oldest unlocked recents; MySQL won't take a LIMIT directly inside
+// IN (...) and SQLite won't take one on DELETE, but both are fine with it in
+// a derived table
+func (r sqlRepository) TrimWatcherRecents(watcherId uint64, count int) error {
+	trim := `DELETE FROM watcher_recent WHERE watcher_recent_id IN (
+	             SELECT watcher_recent_id FROM (
+	               SELECT watcher_recent_id FROM watcher_recent WHERE watcher_id=? AND locked=false ORDER BY update_datetime LIMIT ?
+	             ) oldest)`
+	_, err := r.db.Exec(trim, watcherId, count)
+	return err
+}
+
+func (r sqlRepository) RemoveWatcherRecent(watcherId, tickerId uint64) error {
+	_, err := r.db.Exec("DELETE FROM watcher_recent WHERE watcher_id=? AND ticker_id=? AND locked=false", watcherId, tickerId)
+	return err
+}
+
+func (r sqlRepository) SaveRecent(recent Recent) error {
+	insert := "INSERT INTO recent (ticker_id, ms_performance_id) VALUES (?, ?)"
+	if r.dialect == sqliteDialect {
+		insert += " ON CONFLICT (ticker_id) DO UPDATE SET ms_performance_id=excluded.ms_performance_id, lastseen_datetime=CURRENT_TIMESTAMP"
+	} else {
+		insert += " ON DUPLICATE KEY UPDATE ms_performance_id=VALUES(ms_performance_id), lastseen_datetime=CURRENT_TIMESTAMP"
+	}
+	_, err := r.db.Exec(insert, recent.TickerId, recent.MSPerformanceId)
+	return err
+}
+
+// every ticker that is in at least one watcher's recents
+func (r sqlRepository) GetRecentTickerIds() ([]uint64, error) {
+	var tickerId uint64
+	tickerIds := make([]uint64, 0)
+
+	rows, err := r.db.Queryx("SELECT DISTINCT ticker_id FROM watcher_recent ORDER BY ticker_id")
+	if err != nil {
+		return tickerIds, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.Scan(&tickerId)
+		if err != nil {
+			return tickerIds, err
+		}
+		tickerIds = append(tickerIds, tickerId)
+	}
+	return tickerIds, rows.Err()
+}
+
+// watchlists -----------------------------------------------------------------
+
+func (r sqlRepository) GetWatcherWatchlists(sublog zerolog.Logger, watcherId uint64) ([]Watchlist, error) {
+	var watchlist Watchlist
+	watchlists := make([]Watchlist, 0)
+
+	rows, err := r.db.Queryx("SELECT * FROM watchlist WHERE watcher_id=? ORDER BY create_datetime, watchlist_id", watcherId)
+	if err != nil {
+		return watchlists, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&watchlist)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error reading row")
+			continue
+		}
+		watchlists = append(watchlists, watchlist)
+	}
+	return watchlists, rows.Err()
+}
+
+func (r sqlRepository) GetWatchlistByShareToken(shareToken string) (Watchlist, error) {
+	var watchlist Watchlist
+	err := r.db.QueryRowx("SELECT * FROM watchlist WHERE share_token=? AND share_token != ''", shareToken).StructScan(&watchlist)
+	return watchlist, err
+}
+
+func (r sqlRepository) CreateWatchlist(wl Watchlist) (uint64, error) {
+	res, err := r.db.Exec("INSERT INTO watchlist (watcher_id, watchlist_name, share_token) VALUES (?, ?, ?)", wl.WatcherId, wl.WatchlistName, wl.ShareToken)
+	if err != nil {
+		return 0, err
+	}
+	watchlistId, err := res.LastInsertId()
+	return uint64(watchlistId), err
+}
+
+func (r sqlRepository) UpdateWatchlist(wl Watchlist) error {
+	update := "UPDATE watchlist SET watchlist_name=?, share_token=?, update_datetime=CURRENT_TIMESTAMP WHERE watchlist_id=? AND watcher_id=?"
+	_, err := r.db.Exec(update, wl.WatchlistName, wl.ShareToken, wl.WatchlistId, wl.WatcherId)
+	return err
+}
+
+func (r sqlRepository) DeleteWatchlist(watchlistId uint64) error {
+	_, err := r.db.Exec("DELETE FROM watchlist_ticker WHERE watchlist_id=?", watchlistId)
+	if err != nil {
+		return err
+	}
+	_, err = r.db.Exec("DELETE FROM watchlist WHERE watchlist_id=?", watchlistId)
+	return err
+}
+
+func (r sqlRepository) GetWatchlistTickers(sublog zerolog.Logger, watchlistId uint64) ([]WatchlistTicker, error) {
+	var entry WatchlistTicker
+	entries := make([]WatchlistTicker, 0, 30)
+
+	rows, err := r.db.Queryx(`
+	  SELECT watchlist_ticker.*, ticker.ticker_symbol
+	  FROM watchlist_ticker
+	  JOIN ticker USING (ticker_id)
+	  WHERE watchlist_id=?
+	  ORDER BY watchlist_ticker.sort_order, watchlist_ticker.watchlist_ticker_id`, watchlistId)
+	if err != nil {
+		return entries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&entry)
+		if err != nil {
+			sublog.Error().Err(err).Msg("error reading row")
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// new entries go on the end of the list; the derived table is for MySQL,
+// which won't read from the table it is inserting into otherwise
+func (r sqlRepository) AddWatchlistTicker(entry WatchlistTicker) error {
+	insert := `INSERT INTO watchlist_ticker (watchlist_id, ticker_id, sort_order, note)
+	           SELECT ?, ?, COALESCE(MAX(sort_order), 0) + 1, ? FROM (
+	             SELECT sort_order FROM watchlist_ticker WHERE watchlist_id=?
+	           ) entries`
+	_, err := r.db.Exec(insert, entry.WatchlistId, entry.TickerId, entry.Note, entry.WatchlistId)
+	return err
+}
+
+func (r sqlRepository) UpdateWatchlistTicker(entry WatchlistTicker) error {
+	update := "UPDATE watchlist_ticker SET sort_order=?, note=?, update_datetime=CURRENT_TIMESTAMP WHERE watchlist_id=? AND ticker_id=?"
+	_, err := r.db.Exec(update, entry.SortOrder, entry.Note, entry.WatchlistId, entry.TickerId)
+	return err
+}
+
+func (r sqlRepository) RemoveWatchlistTicker(watchlistId, tickerId uint64) error {
+	_, err := r.db.Exec("DELETE FROM watchlist_ticker WHERE watchlist_id=? AND ticker_id=?", watchlistId, tickerId)
+	return err
+}
+
+// articles -------------------------------------------------------------------
+
+func (r sqlRepository) GetTickerArticles(sublog zerolog.Logger, tickerId uint64, fromDate string, max int) ([]WebArticle, error) {
+	query := `SELECT article.article_id, article.source_id, article.external_id, article.published_datetime, article.pubupdated_datetime,
+	            article.title, article.body, article.article_url, article.image_url,
+	            MAX(article_author.byline) AS author_byline,
+	            MAX(article_author.long_bio) AS author_long_bio,
+	            MAX(article_author.image_url) AS author_image_url,
+	            MAX(source.source_name) AS source_name,
+	            ` + r.groupConcat("article_keyword.keyword") + ` AS keywords,
+	            ` + r.groupConcat("article_tag.tag") + ` AS tags,
+	            ` + r.groupConcat("article_ticker.ticker_symbol") + ` AS symbols
+	          FROM article
+	          LEFT JOIN article_author USING (article_id)
+	          LEFT JOIN article_ticker USING (article_id)
+	          LEFT JOIN article_keyword USING (article_id)
+	          LEFT JOIN article_tag USING (article_id)
+	          LEFT JOIN source USING (source_id)
+	          WHERE published_datetime > ? AND ticker_id=?
+	          GROUP BY article_id
+	          ORDER BY published_datetime DESC
+	          LIMIT ?`
+	return r.getArticles(sublog, query, fromDate, tickerId, max)
+}
+
+func (r sqlRepository) GetRecentArticles(sublog zerolog.Logger, fromDate string, max int) ([]WebArticle, error) {
+	query := `SELECT article.article_id, article.source_id, article.external_id, article.published_datetime, article.pubupdated_datetime,
+	            article.title, article.body, article.article_url, article.image_url,
+	            article_author.byline AS author_byline,
+	            article_author.long_bio AS author_long_bio,
+	            article_author.image_url AS author_image_url,
+	            source.source_name AS source_name
+	          FROM article
+	          LEFT JOIN article_author USING (article_id)
+	          LEFT JOIN source USING (source_id)
+	          WHERE published_datetime > ?
+	          ORDER BY published_datetime DESC
+	          LIMIT ?`
+	return r.getArticles(sublog, query, fromDate, max)
+}
+
+func (r sqlRepository) GetArticleId(sourceId uint64, externalId string) (uint64, error) {
+	var articleId uint64
+	err := r.db.QueryRowx("SELECT article_id FROM article WHERE source_id=? AND external_id=?", sourceId, externalId).Scan(&articleId)
+	return articleId, err
+}
+
+func (r sqlRepository) CreateArticle(a Article) (uint64, error) {
+	insert := `INSERT INTO article (source_id, external_id, published_datetime, pubupdated_datetime, title, body, article_url, image_url)
+	           VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := r.db.Exec(insert, a.SourceId, a.ExternalId, a.PublishedDatetime, a.PubUpdatedDatetime, a.Title, a.Body, a.ArticleURL, a.ImageURL)
+	if err != nil {
+		return 0, err
+	}
+	articleId, err := res.LastInsertId()
+	return uint64(articleId), err
+}
+
+func (r sqlRepository) CreateArticleTicker(at ArticleTicker) error {
+	_, err := r.db.Exec("INSERT INTO article_ticker (article_id, ticker_symbol, ticker_id) VALUES (?, ?, ?)", at.ArticleId, at.TickerSymbol, at.TickerId)
+	return err
+}
+
+func (r sqlRepository) GetSourceId(sourceName string) (uint64, error) {
+	var sourceId uint64
+	err := r.db.QueryRowx("SELECT source_id FROM source WHERE source_name=?", sourceName).Scan(&sourceId)
+	return sourceId, err
+}
+
+func (r sqlRepository) CreateSource(s Source) (uint64, error) {
+	insert := "INSERT INTO source (source_company, source_name, source_website, source_email) VALUES (?, ?, ?, ?)"
+	res, err := r.db.Exec(insert, s.SourceCompany, s.SourceName, s.SourceWebsite, s.SourceEmail)
+	if err != nil {
+		return 0, err
+	}
+	sourceId, err := res.LastInsertId()
+	return uint64(sourceId), err
+}
+
+func (r sqlRepository) getArticles(sublog zerolog.Logger, query string, args ...interface{}) ([]WebArticle, error) {
+	var article WebArticle
+	articles := make([]WebArticle, 0)
+
+	rows, err := r.db.Queryx(query, args...)
+	if err != nil {
+		return articles, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&article)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("error reading row")
+			continue
+		}
+		articles = append(articles, article)
+	}
+	return articles, rows.Err()
+}
+
+// sorted, de-duplicated, comma-separated list of a column's values in the group
+func (r sqlRepository) groupConcat(column string) string {
+	if r.dialect == sqliteDialect {
+		return "REPLACE(GROUP_CONCAT(DISTINCT " + column + " ORDER BY " + column + "), ',', ', ')"
+	}
+	return "GROUP_CONCAT(DISTINCT " + column + " ORDER BY " + column + " SEPARATOR ', ')"
+}
+
+// movers ---------------------------------------------------------------------
+
+// ORDER BY rather than MAX() so the column keeps its date type on SQLite
+func (r sqlRepository) GetLatestMoverDate() (time.Time, error) {
+	var moverDate time.Time
+	err := r.db.QueryRowx("SELECT mover_date FROM mover ORDER BY mover_date DESC LIMIT 1").Scan(&moverDate)
+	return moverDate, err
+}
+
+func (r sqlRepository) GetMovers(sublog zerolog.Logger, moverDate time.Time) ([]Mover, error) {
+	var mover Mover
+	movers := make([]Mover, 0)
+
+	rows, err := r.db.Queryx("SELECT * FROM mover WHERE mover_date=?", moverDate.Format(sqlDateParseType))
+	if err != nil {
+		return movers, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&mover)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading row")
+			continue
+		}
+		movers = append(movers, mover)
+	}
+	return movers, rows.Err()
+}
+
+// the movers for a day are always loaded as a set
+func (r sqlRepository) ReplaceMovers(moverDate time.Time, movers []Mover) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM mover WHERE mover_date=?", moverDate.Format(sqlDateParseType))
+	if err != nil {
+		return err
+	}
+	insert := `INSERT INTO mover (source_id, ticker_id, mover_date, mover_type, last_price, price_change, price_change_pct, volume)
+	           VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	for _, m := range movers {
+		_, err = tx.Exec(insert, m.SourceId, m.TickerId, moverDate.Format(sqlDateParseType), m.MoverType, m.LastPrice, m.PriceChange, m.PriceChangePct, m.Volume)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// lastdone -------------------------------------------------------------------
+
+func (r sqlRepository) GetLastDone(activity, uniqueKey string) (LastDone, error) {
+	var lastdone LastDone
+	err := r.db.QueryRowx("SELECT * FROM lastdone WHERE activity=? AND unique_key=?", activity, uniqueKey).StructScan(&lastdone)
+	return lastdone, err
+}
+
+func (r sqlRepository) SaveLastDone(ld LastDone) error {
+	insert := "INSERT INTO lastdone (activity, unique_key, last_status, lastdone_datetime) VALUES (?, ?, ?, ?)"
+	if r.dialect == sqliteDialect {
+		insert += " ON CONFLICT (activity, unique_key) DO UPDATE SET last_status=excluded.last_status, lastdone_datetime=excluded.lastdone_datetime, update_datetime=CURRENT_TIMESTAMP"
+	} else {
+		insert += " ON DUPLICATE KEY UPDATE last_status=VALUES(last_status), lastdone_datetime=VALUES(lastdone_datetime), update_datetime=CURRENT_TIMESTAMP"
+	}
+	_, err := r.db.Exec(insert, ld.Activity, ld.UniqueKey, ld.LastStatus, ld.LastDoneDatetime)
+	return err
+}
+
+// misc -----------------------------------------------------------------------
+
+func setupRepositories(deps *Dependencies, dialect string) {
+	repository := sqlRepository{db: deps.db, dialect: dialect}
+
+	deps.tickers = repository
+	deps.marketIndexes = repository
+	deps.watchers = repository
+	deps.recents = repository
+	deps.watchlists = repository
+	deps.articles = repository
+	deps.movers = repository
+	deps.lastdone = repository
+}
@@ -1,19 +1,21 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
 	"time"
 
-	"github.com/gomodule/redigo/redis"
+	"github.com/gorilla/sessions"
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
 var (
 	forwardedRE      = regexp.MustCompile(`for=(.*)`)
-	skipLoggingPaths = regexp.MustCompile(`^/(ping|metrics|static|favicon.ico)`)
+	skipLoggingPaths = regexp.MustCompile(`^/(ping|healthz|readyz|metrics|static|favicon.ico)`)
 	obfuscateParams  = regexp.MustCompile(`(token|verifier|pwd|password|code|state)=([^\&]+)`)
 )
 
@@ -22,6 +24,21 @@ type StatusRecorder struct {
 	Status int
 }
 
+// everything that belongs to a single request. Dependencies is shared by every
+// request in flight, so none of this can live there; handlers get it back with
+// getRequestContext(r)
+type RequestContext struct {
+	session   *sessions.Session
+	config    map[string]interface{}
+	webdata   map[string]interface{}
+	messages  []Message
+	requestId string
+	nonce     string
+	logger    *zerolog.Logger
+}
+
+type requestContextKey struct{}
+
 // object methods -------------------------------------------------------------
 
 func (r *StatusRecorder) WriteHeader(status int) {
@@ -31,6 +48,22 @@ func (r *StatusRecorder) WriteHeader(status int) {
 
 // misc -----------------------------------------------------------------------
 
+// anything routed around requestHandler gets an empty context rather than a
+// nil one, so helpers don't have to check
+func getRequestContext(r *http.Request) *RequestContext {
+	rc, ok := r.Context().Value(requestContextKey{}).(*RequestContext)
+	if !ok {
+		sublog := log.With().Str("@tag", "stockwatch").Caller().Logger()
+		rc = &RequestContext{
+			config:   make(map[string]interface{}),
+			webdata:  make(map[string]interface{}),
+			messages: []Message{},
+			logger:   &sublog,
+		}
+	}
+	return rc
+}
+
 // requestHandler middleware --------------------------------------------------
 
 type requestHandler struct {
@@ -67,21 +100,23 @@ func (rh requestHandler) requestHandler(h http.HandlerFunc) http.HandlerFunc {
 				sublog.Fatal().Err(err).Msg("failed to save session")
 			}
 		}
-		rh.deps.session = session
 		defer session.Save(r, w)
 
 		// per-request setup
-		rh.deps.webdata = make(map[string]interface{})
-		rh.deps.config = make(map[string]interface{})
-		rh.deps.config["is_market_open"] = isMarketOpen()
+		rc := &RequestContext{
+			session:  session,
+			config:   make(map[string]interface{}),
+			webdata:  make(map[string]interface{}),
+			messages: []Message{},
+		}
+		rc.config["is_market_open"] = isMarketOpen(homeExchange)
 
 		// setup nonce for this request
-		nonce := RandStringMask(32)
-		rh.deps.nonce = nonce
-		rh.deps.webdata["nonce"] = nonce
+		rc.nonce = RandStringMask(32)
+		rc.webdata["nonce"] = rc.nonce
 
 		// more webdata defaults
-		rh.deps.webdata["timezone"] = "UTC"
+		rc.webdata["timezone"] = "UTC"
 
 		// Content Security Policy
 		csp := map[string][]string{
@@ -89,7 +124,7 @@ func (rh requestHandler) requestHandler(h http.HandlerFunc) http.HandlerFunc {
 			"default-src": {"'self'"},
 			"connect-src": {"'self'", "accounts.google.com", "www.google-analytics.com", "*.fontawesome.com", "api.amazon.com", "*.facebook.com"},
 			"style-src":   {"'self'", "fonts.googleapis.com", "accounts.google.com", "'unsafe-inline'"},
-			"script-src":  {"'self'", "apis.google.com", "www.googletagmanager.com", "accounts.google.com", "kit.fontawesome.com", "assets.loginwithamazon.com", "*.facebook.net", "'nonce-" + rh.deps.nonce + "'"},
+			"script-src":  {"'self'", "apis.google.com", "www.googletagmanager.com", "accounts.google.com", "kit.fontawesome.com", "assets.loginwithamazon.com", "*.facebook.net", "'nonce-" + rc.nonce + "'"},
 			"font-src":    {"'self'", "fonts.gstatic.com", "*.fontawesome.com"},
 			"frame-src":   {"'self'", "accounts.google.com", "*.amazon.com", "*.facebook.com"},
 			"img-src":     {"* data:"},
@@ -102,9 +137,9 @@ func (rh requestHandler) requestHandler(h http.HandlerFunc) http.HandlerFunc {
 			cspString += fmt.Sprintf("%s %s;\n", category, strings.Join(csp[category], " "))
 		}
 		resHeader.Set("Content-Security-Policy", cspString)
-		resHeader.Set("X-Nonce", rh.deps.nonce)
+		resHeader.Set("X-Nonce", rc.nonce)
 
-		reportTo := `{"group":"default","max-age":1800,"endpoints":[{"url":"https://stockwatch.graystorm.com/internal/cspviolations"}],"include_subdomains":true}`
+		reportTo := `{"group":"default","max-age":1800,"endpoints":[{"url":"` + rh.deps.config.siteURL("/internal/cspviolations") + `"}],"include_subdomains":true}`
 		resHeader.Set("Report-To", reportTo)
 
 		// RequestID
@@ -117,34 +152,23 @@ func (rh requestHandler) requestHandler(h http.HandlerFunc) http.HandlerFunc {
 			rid = reqHeader.Get("X-Request-ID")
 		}
 		resHeader.Set("X-Request-ID", rid)
-		rh.deps.request_id = rid
+		rc.requestId = rid
 
 		ridCookie = &http.Cookie{
 			Name:     "RID",
 			Value:    rid,
 			Path:     "/",
-			Secure:   true,
+			Secure:   rh.deps.config.siteIsSecure(),
 			HttpOnly: true,
 			Expires:  time.Now().Add(3 * time.Second),
 		}
 		http.SetCookie(w, ridCookie)
 
 		sublog = sublog.With().Str("request_id", rid).Logger()
-		rh.deps.logger = &sublog
-
-		// redis connection
-		rh.deps.redisPool = &redis.Pool{
-			MaxIdle:     10,
-			IdleTimeout: 240 * time.Second,
-			Dial: func() (redis.Conn, error) {
-				return redis.Dial("tcp", "localhost:6379")
-			},
-		}
-
-		// messages
-		rh.deps.messages = []Message{}
+		rc.logger = &sublog
 
 		// go handle the request
+		r = r.WithContext(context.WithValue(r.Context(), requestContextKey{}, rc))
 		rh.handler.ServeHTTP(w, r)
 
 		// don't logs these, no reason to
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+	"github.com/rs/zerolog"
+)
+
+// a Job is a refresh the scheduler runs on the market's clock, rather than
+// waiting for a page view to notice the data is stale. Runs are recorded in
+// lastdone under the job's Name and jobUniqueKey
+type Job struct {
+	Name        string
+	Description string
+	Schedule    string                                                // for the admin page
+	due         func(now, lastSuccess time.Time) bool                 // now is in the home market's time zone; lastSuccess is zero if it never worked
+	run         func(deps *Dependencies, sublog zerolog.Logger) error // returning an error marks the run a failure
+}
+
+type JobStatus struct {
+	Job      Job
+	LastDone LastDone
+}
+
+const jobUniqueKey = "stockwatch"
+
+var scheduledJobs = []Job{
+	{
+		Name:        "eod_backfill",
+		Description: "End-of-day prices for every ticker in any watcher's recents, and the benchmark indexes",
+		Schedule:    "market days, 30 minutes after the close",
+		due:         dueAfterClose,
+		run:         backfillRecentEODs,
+	},
+	{
+		// EODs were once stored in post-split shares, as the provider gives
+		// them; they are stored as traded now, so those have to go
+		Name:        "split_eod_reload",
+		Description: "End-of-day prices reloaded as traded for every ticker that has split",
+		Schedule:    "once",
+		due:         dueOnce,
+		run:         reloadSplitTickerEODs,
+	},
+	{
+		Name:        "premarket_movers",
+		Description: "Top gainers, losers and most active for the desktop",
+		Schedule:    "market days, 7:00am to 9:30am ET",
+		due:         duePreMarket,
+		run:         fetchMovers,
+	},
+	{
+		// the desktop reads this one with getLastDoneInfo
+		Name:        "financial_news",
+		Description: "General market news for the desktop",
+		Schedule:    "hourly",
+		due:         dueHourly,
+		run:         fetchMarketNews,
+	},
+}
+
+// misc -----------------------------------------------------------------------
+
+// check every job once a schedulerInterval until ctx is done. The worker runs
+// this (and the server too, with the local backend); the redis lock in runJob
+// keeps more than one of them from running the same job at once
+func runScheduler(deps *Dependencies, ctx context.Context) {
+	sublog := deps.logger.With().Str("task", "scheduler").Logger()
+
+	interval := time.NewTicker(schedulerInterval * time.Second)
+	defer interval.Stop()
+	sublog.Info().Int("jobs", len(scheduledJobs)).Int("interval", schedulerInterval).Msg("started scheduler")
+
+	for {
+		runDueJobs(deps, sublog)
+		select {
+		case <-ctx.Done():
+			sublog.Info().Msg("stopped scheduler")
+			return
+		case <-interval.C:
+		}
+	}
+}
+
+func runDueJobs(deps *Dependencies, sublog zerolog.Logger) {
+	now := time.Now().In(getMarketCalendar(homeExchange).location())
+
+	for _, job := range scheduledJobs {
+		lastdone := LastDone{Activity: job.Name, UniqueKey: jobUniqueKey}
+		err := lastdone.getByActivity(deps)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			sublog.Error().Err(err).Str("job", job.Name).Msg("failed to get lastdone for job")
+			continue
+		}
+		if job.due(now, lastdone.LastDoneDatetime.Time) {
+			runJob(deps, sublog, job)
+		}
+	}
+}
+
+// run the job if nobody else is, and record how it went. lastdone_datetime
+// only moves on success, so the job stays due after a failure; the lock is
+// kept for jobRetryDelay minutes instead, so it isn't retried every tick
+func runJob(deps *Dependencies, sublog zerolog.Logger, job Job) bool {
+	sublog = sublog.With().Str("job", job.Name).Logger()
+	lockKey := "scheduler/" + job.Name
+
+	locked, err := lockJob(deps, lockKey)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to lock job")
+		return false
+	}
+	if !locked {
+		sublog.Debug().Msg("job is running elsewhere or waiting to retry")
+		return false
+	}
+
+	lastdone := LastDone{Activity: job.Name, UniqueKey: jobUniqueKey}
+	lastdone.getByActivity(deps)
+	lastdone.LastStatus = "running"
+	if err := lastdone.createOrUpdate(deps); err != nil {
+		sublog.Error().Err(err).Msg("failed to record lastdone")
+	}
+
+	start := time.Now()
+	err = job.run(deps, sublog)
+	if err != nil {
+		sublog.Error().Err(err).Msg("job failed")
+		lastdone.LastStatus = "failure"
+		expireJobLock(deps, sublog, lockKey, jobRetryDelay*60)
+	} else {
+		sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("job done")
+		lastdone.LastStatus = "success"
+		lastdone.LastDoneDatetime = sql.NullTime{Valid: true, Time: time.Now()}
+		expireJobLock(deps, sublog, lockKey, 0)
+	}
+	if err := lastdone.createOrUpdate(deps); err != nil {
+		sublog.Error().Err(err).Msg("failed to record lastdone")
+	}
+	return true
+}
+
+// the lock outlives a crashed run by at most jobLockTimeout minutes
+func lockJob(deps *Dependencies, lockKey string) (bool, error) {
+	redisConn := deps.redisPool.Get()
+	defer redisConn.Close()
+
+	_, err := redis.String(redisConn.Do("SET", lockKey, time.Now().Unix(), "NX", "EX", jobLockTimeout*60))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// seconds of 0 releases the lock now
+func expireJobLock(deps *Dependencies, sublog zerolog.Logger, lockKey string, seconds int) {
+	redisConn := deps.redisPool.Get()
+	defer redisConn.Close()
+
+	var err error
+	if seconds > 0 {
+		_, err = redisConn.Do("EXPIRE", lockKey, seconds)
+	} else {
+		_, err = redisConn.Do("DEL", lockKey)
+	}
+	if err != nil {
+		sublog.Error().Err(err).Str("redis_key", lockKey).Msg("failed to update job lock")
+	}
+}
+
+func getJob(name string) (Job, bool) {
+	for _, job := range scheduledJobs {
+		if job.Name == name {
+			return job, true
+		}
+	}
+	return Job{}, false
+}
+
+func getJobStatuses(deps *Dependencies, sublog zerolog.Logger) []JobStatus {
+	statuses := make([]JobStatus, 0, len(scheduledJobs))
+	for _, job := range scheduledJobs {
+		lastdone := LastDone{Activity: job.Name, UniqueKey: jobUniqueKey}
+		err := lastdone.getByActivity(deps)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			sublog.Error().Err(err).Str("job", job.Name).Msg("failed to get lastdone for job")
+		}
+		statuses = append(statuses, JobStatus{job, lastdone})
+	}
+	return statuses
+}
+
+// schedules ------------------------------------------------------------------
+
+// market days and hours are the home market's, holidays and early closes
+// included
+
+// once a market day, after the close has settled; a close we were down for
+// (or started after, on a weekend) is still due until a run follows it
+func dueAfterClose(now, lastSuccess time.Time) bool {
+	closed := getMarketCalendar(homeExchange).lastClose(now.Add(-eodSettleDelay * time.Minute))
+	if closed.IsZero() {
+		return false
+	}
+	return lastSuccess.Before(closed.Add(eodSettleDelay * time.Minute))
+}
+
+// once a market day, between 7:00 and the open
+func duePreMarket(now, lastSuccess time.Time) bool {
+	sessions := getMarketCalendar(homeExchange).sessions(now)
+	if len(sessions) == 0 {
+		return false
+	}
+	preMarket := time.Date(now.Year(), now.Month(), now.Day(), 7, 0, 0, 0, now.Location())
+	return !now.Before(preMarket) && now.Before(sessions[0][0]) && lastSuccess.Before(preMarket)
+}
+
+func dueHourly(now, lastSuccess time.Time) bool {
+	return now.Sub(lastSuccess) >= time.Hour
+}
+
+// until it has worked once
+func dueOnce(now, lastSuccess time.Time) bool {
+	return lastSuccess.IsZero()
+}
+
+// jobs -----------------------------------------------------------------------
+
+// keep going past a ticker that fails, but fail the run so it is retried
+func backfillRecentEODs(deps *Dependencies, sublog zerolog.Logger) error {
+	tickerIds, err := deps.recents.GetRecentTickerIds()
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	loaded := 0
+	for _, tickerId := range tickerIds {
+		ticker := Ticker{TickerId: tickerId}
+		err := ticker.getById(deps, sublog)
+		if err != nil {
+			sublog.Warn().Err(err).Uint64("ticker_id", tickerId).Msg("failed to load ticker")
+			lastErr = err
+			continue
+		}
+		if !ticker.needEODs(deps, sublog) {
+			continue
+		}
+		err = fetchTickerEODs(deps, sublog, ticker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		loaded++
+	}
+
+	indexes := getBenchmarkIndexes(deps, sublog)
+	for _, mi := range indexes {
+		if !mi.needEODs(deps, sublog) {
+			continue
+		}
+		err := fetchMarketIndexEODs(deps, sublog, mi)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		loaded++
+	}
+	sublog.Info().Int("tickers", len(tickerIds)).Int("indexes", len(indexes)).Int("loaded", loaded).Msg("backfilled EODs")
+
+	return lastErr
+}
+
+// a ticker's EODs are deleted before they are fetched again, not just
+// overwritten: days older than the provider gives back would otherwise stay
+// in post-split shares and be adjusted a second time on every chart. Losing
+// them is better than charting them wrong
+func reloadSplitTickerEODs(deps *Dependencies, sublog zerolog.Logger) error {
+	tickerIds, err := deps.tickers.GetSplitTickerIds()
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	for _, tickerId := range tickerIds {
+		ticker := Ticker{TickerId: tickerId}
+		err := ticker.getById(deps, sublog)
+		if err != nil {
+			sublog.Warn().Err(err).Uint64("ticker_id", tickerId).Msg("failed to load ticker")
+			lastErr = err
+			continue
+		}
+		err = deps.tickers.DeleteTickerDailies(tickerId)
+		if err != nil {
+			sublog.Error().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to delete EODs")
+			lastErr = err
+			continue
+		}
+		// a failed fetch leaves the ticker without EODs until the next view
+		// or backfill notices, rather than with wrong ones
+		err = fetchTickerEODs(deps, sublog, ticker)
+		if err != nil {
+			lastErr = err
+		}
+	}
+	sublog.Info().Int("tickers", len(tickerIds)).Msg("reloaded EODs of split tickers")
+
+	return lastErr
+}
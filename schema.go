@@ -61,11 +61,11 @@ type ConfigData struct {
 }
 
 type WebWatch struct {
-	SourceDate    string
-	TargetPrice   float64
-	TargetDate    sql.NullString
-	SourceName    sql.NullString
-	SourceCompany sql.NullString
+	SourceDate    string         `db:"source_date"`
+	TargetPrice   float64        `db:"target_price"`
+	TargetDate    sql.NullString `db:"target_date"`
+	SourceName    sql.NullString `db:"source_name"`
+	SourceCompany sql.NullString `db:"source_company"`
 }
 
 type Message struct {
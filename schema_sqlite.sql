@@ -0,0 +1,425 @@
+-- schema for storageBackend "local", applied at every startup; mirrors the
+-- Aurora tables closely enough for the app, not the fetch/ingest jobs. A new
+-- table or column here also needs its MySQL DDL under migrations/mysql
+
+CREATE TABLE IF NOT EXISTS country (
+  country_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  country_code TEXT NOT NULL DEFAULT '',
+  country_name TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS currency (
+  currency_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  currency_code TEXT NOT NULL DEFAULT '',
+  currency_name TEXT NOT NULL DEFAULT '',
+  currency_symbol TEXT NOT NULL DEFAULT '',
+  currency_symbol_native TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS exchange (
+  exchange_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  exchange_mic TEXT NOT NULL DEFAULT '',
+  operating_mic TEXT NOT NULL DEFAULT '',
+  exchange_name TEXT NOT NULL DEFAULT '',
+  exchange_acronym TEXT NOT NULL DEFAULT '',
+  exchange_code TEXT NOT NULL DEFAULT '',
+  exchange_tz TEXT NOT NULL DEFAULT '',
+  city TEXT NOT NULL DEFAULT '',
+  country_id INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS exchange_code ON exchange (exchange_code);
+
+CREATE TABLE IF NOT EXISTS source (
+  source_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  source_company TEXT NOT NULL DEFAULT '',
+  source_name TEXT NOT NULL DEFAULT '',
+  source_website TEXT NOT NULL DEFAULT '',
+  source_email TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS source_name ON source (source_name);
+
+CREATE TABLE IF NOT EXISTS definition (
+  term TEXT PRIMARY KEY,
+  definition TEXT
+);
+
+CREATE TABLE IF NOT EXISTS ticker (
+  ticker_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_symbol TEXT NOT NULL,
+  ticker_type TEXT NOT NULL DEFAULT '',
+  ticker_market TEXT NOT NULL DEFAULT '',
+  exchange_id INTEGER NOT NULL DEFAULT 0,
+  ticker_name TEXT NOT NULL DEFAULT '',
+  company_name TEXT NOT NULL DEFAULT '',
+  address TEXT NOT NULL DEFAULT '',
+  city TEXT NOT NULL DEFAULT '',
+  state TEXT NOT NULL DEFAULT '',
+  zip TEXT NOT NULL DEFAULT '',
+  country TEXT NOT NULL DEFAULT '',
+  website TEXT NOT NULL DEFAULT '',
+  phone TEXT NOT NULL DEFAULT '',
+  sector TEXT NOT NULL DEFAULT '',
+  industry TEXT NOT NULL DEFAULT '',
+  market_price REAL NOT NULL DEFAULT 0,
+  market_prev_close REAL NOT NULL DEFAULT 0,
+  market_volume INTEGER NOT NULL DEFAULT 0,
+  market_price_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  favicon_s3key TEXT NOT NULL DEFAULT '',
+  fetch_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  ms_performance_id TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ticker_symbol ON ticker (ticker_symbol);
+
+CREATE TABLE IF NOT EXISTS ticker_attribute (
+  attribute_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  attribute_name TEXT NOT NULL,
+  attribute_comment TEXT NOT NULL DEFAULT '',
+  attribute_value TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS ticker_attribute_name ON ticker_attribute (ticker_id, attribute_name);
+
+CREATE TABLE IF NOT EXISTS ticker_daily (
+  ticker_daily_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  price_datetime DATETIME NOT NULL,
+  open_price REAL NOT NULL DEFAULT 0,
+  high_price REAL NOT NULL DEFAULT 0,
+  low_price REAL NOT NULL DEFAULT 0,
+  close_price REAL NOT NULL DEFAULT 0,
+  volume INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ticker_daily_datetime ON ticker_daily (ticker_id, price_datetime);
+
+CREATE TABLE IF NOT EXISTS ticker_intraday (
+  intraday_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  price_date TEXT NOT NULL,
+  last_price REAL NOT NULL DEFAULT 0,
+  volume INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ticker_intraday_date ON ticker_intraday (ticker_id, price_date);
+
+CREATE TABLE IF NOT EXISTS ticker_description (
+  description_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  business_summary TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ticker_description_ticker ON ticker_description (ticker_id);
+
+CREATE TABLE IF NOT EXISTS ticker_updown (
+  updown_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  updown_action TEXT NOT NULL DEFAULT '',
+  updown_fromgrade TEXT NOT NULL DEFAULT '',
+  updown_tograde TEXT NOT NULL DEFAULT '',
+  updown_date DATE,
+  updown_firm TEXT NOT NULL DEFAULT '',
+  updown_since TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS ticker_updown_date ON ticker_updown (ticker_id, updown_date);
+
+CREATE TABLE IF NOT EXISTS ticker_split (
+  ticker_split_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  split_date DATE NOT NULL,
+  split_ratio TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ticker_split_date ON ticker_split (ticker_id, split_date);
+
+CREATE TABLE IF NOT EXISTS ticker_dividend (
+  ticker_dividend_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  dividend_date DATE NOT NULL,
+  amount REAL NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS ticker_dividend_date ON ticker_dividend (ticker_id, dividend_date);
+
+CREATE TABLE IF NOT EXISTS financials (
+  financials_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  form_name TEXT NOT NULL DEFAULT '',
+  form_term_name TEXT NOT NULL DEFAULT '',
+  chart_type TEXT NOT NULL DEFAULT '',
+  is_percentage INTEGER NOT NULL DEFAULT 0,
+  chart_datetime DATETIME,
+  chart_name TEXT NOT NULL DEFAULT '',
+  chart_value REAL NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS marketindex (
+  marketindex_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  marketindex_symbol TEXT NOT NULL DEFAULT '',
+  marketindex_mic TEXT NOT NULL DEFAULT '',
+  marketindex_name TEXT NOT NULL DEFAULT '',
+  country_id INTEGER NOT NULL DEFAULT 0,
+  marketindex_has_intraday INTEGER NOT NULL DEFAULT 0,
+  marketindex_has_eod INTEGER NOT NULL DEFAULT 0,
+  currency_id INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS marketindex_daily (
+  marketindex_daily_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  marketindex_id INTEGER NOT NULL,
+  price_date TEXT NOT NULL,
+  open_price REAL NOT NULL DEFAULT 0,
+  high_price REAL NOT NULL DEFAULT 0,
+  low_price REAL NOT NULL DEFAULT 0,
+  close_price REAL NOT NULL DEFAULT 0,
+  volume INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS marketindex_daily_date ON marketindex_daily (marketindex_id, price_date);
+
+CREATE TABLE IF NOT EXISTS marketindex_intraday (
+  intraday_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  marketindex_id INTEGER NOT NULL,
+  price_date TEXT NOT NULL,
+  last_price REAL NOT NULL DEFAULT 0,
+  volume INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS marketindex_intraday_date ON marketindex_intraday (marketindex_id, price_date);
+
+CREATE TABLE IF NOT EXISTS watch (
+  watch_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  ticker_id INTEGER NOT NULL,
+  source_id INTEGER NOT NULL DEFAULT 0,
+  source_date TEXT NOT NULL DEFAULT '',
+  target_price REAL NOT NULL DEFAULT 0,
+  target_date DATETIME,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS mover (
+  mover_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  source_id INTEGER NOT NULL DEFAULT 0,
+  ticker_id INTEGER NOT NULL,
+  mover_date DATE NOT NULL,
+  mover_type TEXT NOT NULL DEFAULT '',
+  last_price REAL NOT NULL DEFAULT 0,
+  price_change REAL NOT NULL DEFAULT 0,
+  price_change_pct REAL NOT NULL DEFAULT 0,
+  volume INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS article (
+  article_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  source_id INTEGER NOT NULL DEFAULT 0,
+  external_id TEXT NOT NULL DEFAULT '',
+  published_datetime DATETIME,
+  pubupdated_datetime DATETIME,
+  title TEXT NOT NULL DEFAULT '',
+  body TEXT NOT NULL DEFAULT '',
+  article_url TEXT NOT NULL DEFAULT '',
+  image_url TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS article_external ON article (source_id, external_id);
+
+CREATE TABLE IF NOT EXISTS article_author (
+  article_author_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  article_id INTEGER NOT NULL,
+  byline TEXT NOT NULL DEFAULT '',
+  job_title TEXT NOT NULL DEFAULT '',
+  short_bio TEXT NOT NULL DEFAULT '',
+  long_bio TEXT NOT NULL DEFAULT '',
+  image_url TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS article_ticker (
+  article_ticker_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  article_id INTEGER NOT NULL,
+  ticker_symbol TEXT NOT NULL DEFAULT '',
+  ticker_id INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS article_keyword (
+  article_keyword_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  article_id INTEGER NOT NULL,
+  keyword TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS article_tag (
+  article_tag_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  article_id INTEGER NOT NULL,
+  tag TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS lastdone (
+  activity TEXT NOT NULL,
+  unique_key TEXT NOT NULL,
+  last_status TEXT NOT NULL DEFAULT '',
+  lastdone_datetime DATETIME,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (activity, unique_key)
+);
+
+CREATE TABLE IF NOT EXISTS watcher (
+  watcher_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watcher_sub TEXT NOT NULL DEFAULT '',
+  watcher_name TEXT NOT NULL DEFAULT '',
+  watcher_nickname TEXT NOT NULL DEFAULT '',
+  watcher_status TEXT NOT NULL DEFAULT 'active',
+  watcher_level TEXT NOT NULL DEFAULT 'standard',
+  watcher_timezone TEXT NOT NULL DEFAULT 'America/New_York',
+  watcher_pic_url TEXT NOT NULL DEFAULT '',
+  session_id TEXT NOT NULL DEFAULT '',
+  costbasis_method TEXT NOT NULL DEFAULT 'fifo',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS watcher_email (
+  watcher_email_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watcher_id INTEGER NOT NULL,
+  email_address TEXT NOT NULL,
+  email_is_primary INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS watcher_email_address ON watcher_email (email_address);
+
+CREATE TABLE IF NOT EXISTS watcher_recent (
+  watcher_recent_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watcher_id INTEGER NOT NULL,
+  ticker_id INTEGER NOT NULL,
+  locked INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS watcher_recent_ticker ON watcher_recent (watcher_id, ticker_id);
+
+CREATE TABLE IF NOT EXISTS recent (
+  ticker_id INTEGER PRIMARY KEY,
+  ms_performance_id TEXT NOT NULL DEFAULT '',
+  lastseen_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oauth (
+  oauth_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  oauth_issuer TEXT NOT NULL DEFAULT '',
+  oauth_sub TEXT NOT NULL,
+  oauth_issued DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  oauth_expires DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS oauth_sub ON oauth (oauth_sub);
+
+CREATE TABLE IF NOT EXISTS holding (
+  holding_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watcher_id INTEGER NOT NULL,
+  ticker_id INTEGER NOT NULL,
+  shares REAL NOT NULL DEFAULT 0,
+  cost_basis REAL NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS holding_watcher_ticker ON holding (watcher_id, ticker_id);
+
+CREATE TABLE IF NOT EXISTS `transaction` (
+  transaction_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  holding_id INTEGER NOT NULL,
+  watcher_id INTEGER NOT NULL,
+  transaction_type TEXT NOT NULL,
+  transaction_datetime DATETIME NOT NULL,
+  shares REAL NOT NULL DEFAULT 0,
+  share_price REAL NOT NULL DEFAULT 0,
+  lot_id INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS transaction_holding ON `transaction` (holding_id);
+
+CREATE TABLE IF NOT EXISTS alert (
+  alert_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watcher_id INTEGER NOT NULL,
+  ticker_id INTEGER NOT NULL,
+  alert_type TEXT NOT NULL,
+  alert_value REAL NOT NULL DEFAULT 0,
+  alert_status TEXT NOT NULL DEFAULT 'armed',
+  cooldown_minutes INTEGER NOT NULL DEFAULT 60,
+  last_triggered_datetime DATETIME,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS alert_trigger (
+  alert_trigger_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  alert_id INTEGER NOT NULL,
+  watcher_id INTEGER NOT NULL,
+  ticker_id INTEGER NOT NULL,
+  trigger_price REAL NOT NULL DEFAULT 0,
+  trigger_message TEXT NOT NULL DEFAULT '',
+  delivered INTEGER NOT NULL DEFAULT 0,
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS watchlist (
+  watchlist_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watcher_id INTEGER NOT NULL,
+  watchlist_name TEXT NOT NULL,
+  share_token TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS watchlist_name ON watchlist (watcher_id, watchlist_name);
+CREATE INDEX IF NOT EXISTS watchlist_share ON watchlist (share_token);
+
+CREATE TABLE IF NOT EXISTS watchlist_ticker (
+  watchlist_ticker_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  watchlist_id INTEGER NOT NULL,
+  ticker_id INTEGER NOT NULL,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  note TEXT NOT NULL DEFAULT '',
+  create_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  update_datetime DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS watchlist_ticker_ticker ON watchlist_ticker (watchlist_id, ticker_id);
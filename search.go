@@ -36,14 +36,15 @@ type SearchResult struct {
 
 func searchHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		webdata := deps.webdata
+		rc := getRequestContext(r)
+		webdata := rc.webdata
 
-		checkAuthState(w, r, deps, *deps.logger)
+		checkAuthState(w, r, deps, *rc.logger)
 
 		params := mux.Vars(r)
 		searchType := params["type"]
 
-		sublog := deps.logger.With().Str("search_type", searchType).Logger()
+		sublog := rc.logger.With().Str("search_type", searchType).Logger()
 
 		switch searchType {
 		case "ticker":
@@ -1,11 +1,16 @@
 package main
 
 import (
+	"context"
 	"html/template"
 	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -34,23 +39,31 @@ import (
 )
 
 type Dependencies struct {
-	awsconfig    *aws.Config
-	awssess      *session.Session
-	db           *sqlx.DB
-	ddb          *dynamodb.DynamoDB
-	logger       *zerolog.Logger
-	secureCookie *securecookie.SecureCookie
-	cookieStore  *dynastore.Store
-	redisPool    *redis.Pool
-	templates    *template.Template
-	bufpool      *bpool.BufferPool
-	secrets      map[string]string
-	session      *sessions.Session
-	config       map[string]interface{}
-	webdata      map[string]interface{}
-	messages     []Message
-	request_id   string
-	nonce        string
+	awsconfig     *aws.Config
+	awssess       *session.Session
+	db            *sqlx.DB
+	ddb           *dynamodb.DynamoDB
+	logger        *zerolog.Logger
+	secureCookie  *securecookie.SecureCookie
+	cookieStore   sessions.Store
+	redisPool     *redis.Pool
+	marketData    MarketDataProvider
+	tickers       TickerRepository
+	marketIndexes MarketIndexRepository
+	watchers      WatcherRepository
+	recents       RecentRepository
+	watchlists    WatchlistRepository
+	articles      ArticleRepository
+	movers        MoverRepository
+	lastdone      LastDoneRepository
+	queue         Queue
+	tasks         TaskQueue
+	blobs         BlobStore
+	templates     *template.Template
+	bufpool       *bpool.BufferPool
+	secrets       map[string]string
+	config        *Config
+	shuttingDown  atomic.Bool
 }
 
 // object methods -------------------------------------------------------------
@@ -72,9 +85,6 @@ func setupLogging(deps *Dependencies) {
 	if len(pgmPath) > 1 {
 		logTag = pgmPath[len(pgmPath)-1]
 	}
-	if debugging {
-		zerolog.SetGlobalLevel(zerolog.DebugLevel)
-	}
 	newlog := log.With().Str("@tag", logTag).Caller().Logger()
 
 	deps.logger = &newlog
@@ -82,14 +92,15 @@ func setupLogging(deps *Dependencies) {
 
 func setupAWS(deps *Dependencies) {
 	sublog := deps.logger
+	region := deps.config.AWSRegion
 
 	var err error
-	deps.awsconfig, err = myaws.AWSConfig("us-east-1")
+	deps.awsconfig, err = myaws.AWSConfig(region)
 	if err != nil {
-		sublog.Fatal().Err(err).Msg("failed to find us-east-1 configuration")
+		sublog.Fatal().Err(err).Str("region", region).Msg("failed to find {region} configuration")
 	}
 
-	deps.awssess = myaws.AWSMustConnect("us-east-1", "stockwatch")
+	deps.awssess = myaws.AWSMustConnect(region, "stockwatch")
 	deps.db = myaws.DBMustConnect(deps.awssess, "stockwatch")
 
 	// connect to Dynamo
@@ -118,45 +129,75 @@ func setupSecrets(deps *Dependencies) {
 	deps.secrets = secrets
 }
 
+// one pool for the life of the process, shared by requests and background tasks
+func setupRedis(deps *Dependencies) {
+	redisAddress := deps.config.RedisAddress
+
+	deps.redisPool = &redis.Pool{
+		MaxIdle:     10,
+		IdleTimeout: 240 * time.Second,
+		Dial: func() (redis.Conn, error) {
+			return redis.Dial("tcp", redisAddress)
+		},
+	}
+}
+
 func setupSessionStore(deps *Dependencies) {
+	config := deps.config
 	secrets := deps.secrets
 	sublog := deps.logger
 
 	var hashKey = []byte(secrets["cookie_auth_key"])
 	var blockKey = []byte(secrets["cookie_encryption_key"])
 	var secureCookie = securecookie.New(hashKey, blockKey)
+	deps.secureCookie = secureCookie
+
+	if config.StorageBackend == "local" {
+		sessionDir := filepath.Join(config.LocalDataDir, "sessions")
+		err := os.MkdirAll(sessionDir, 0700)
+		if err != nil {
+			sublog.Fatal().Err(err).Str("dir", sessionDir).Msg("failed to setup session management")
+		}
+		store := sessions.NewFilesystemStore(sessionDir, hashKey, blockKey)
+		store.Options = &sessions.Options{Path: "/", MaxAge: 60 * 60 * 24 * 365, Secure: config.siteIsSecure(), HttpOnly: true}
+		deps.cookieStore = store
+		return
+	}
 
 	// Initialize session manager and configure the session lifetime -------------
-	store, err := dynastore.New(
+	storeOptions := []dynastore.Option{
 		dynastore.AWSConfig(deps.awsconfig),
 		dynastore.DynamoDB(deps.ddb),
 		dynastore.TableName("stockwatch-session"),
-		dynastore.Secure(),
 		dynastore.HTTPOnly(),
-		dynastore.Domain("stockwatch.graystorm.com"),
+		dynastore.Domain(config.siteHost()),
 		dynastore.Path("/"),
-		dynastore.MaxAge(60*60*24*365),
+		dynastore.MaxAge(60 * 60 * 24 * 365),
 		dynastore.Codecs(secureCookie),
-	)
+	}
+	if config.siteIsSecure() {
+		storeOptions = append(storeOptions, dynastore.Secure())
+	}
+	store, err := dynastore.New(storeOptions...)
 	if err != nil {
 		sublog.Fatal().Err(err).Msg("failed to setup session management")
 	}
 
-	deps.secureCookie = secureCookie
 	deps.cookieStore = store
 }
 
 func setupOAuth(deps *Dependencies) {
+	config := deps.config
 	cookieStore := deps.cookieStore
 	secrets := deps.secrets
 
 	goth.UseProviders(
-		amazon.New(secrets["amazon_api_key"], secrets["amazon_api_secret"], "https://stockwatch.graystorm.com/auth/amazon/callback"),
-		facebook.New(secrets["facebook_api_key"], secrets["facebook_api_secret"], "https://stockwatch.graystorm.com/auth/facebook/callback", "email"),
-		github.New(secrets["github_api_key"], secrets["github_api_secret"], "https://stockwatch.graystorm.com/auth/github/callback"),
-		google.New(secrets["google_oauth_client_id"], secrets["google_oauth_client_secret"], "https://stockwatch.graystorm.com/auth/google/callback", "openid https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/userinfo.profile"),
-		twitter.New(secrets["twitter_api_key"], secrets["twitter_api_secret"], "https://stockwatch.graystorm.com/auth/twitter/callback"),
-		yahoo.New(secrets["yahoo_client_id"], secrets["yahoo_client_secret"], "https://stockwatch.graystorm.com/auth/yahoo/callback", "openid", "profile", "email"),
+		amazon.New(secrets["amazon_api_key"], secrets["amazon_api_secret"], config.siteURL("/auth/amazon/callback")),
+		facebook.New(secrets["facebook_api_key"], secrets["facebook_api_secret"], config.siteURL("/auth/facebook/callback"), "email"),
+		github.New(secrets["github_api_key"], secrets["github_api_secret"], config.siteURL("/auth/github/callback")),
+		google.New(secrets["google_oauth_client_id"], secrets["google_oauth_client_secret"], config.siteURL("/auth/google/callback"), "openid https://www.googleapis.com/auth/userinfo.email https://www.googleapis.com/auth/userinfo.profile"),
+		twitter.New(secrets["twitter_api_key"], secrets["twitter_api_secret"], config.siteURL("/auth/twitter/callback")),
+		yahoo.New(secrets["yahoo_client_id"], secrets["yahoo_client_secret"], config.siteURL("/auth/yahoo/callback"), "openid", "profile", "email"),
 	)
 
 	gothic.Store = cookieStore
@@ -204,7 +245,7 @@ func startServer(deps *Dependencies) {
 	sublog := deps.logger
 
 	// starting up web service ---------------------------------------------------
-	sublog.Info().Int("port", httpPort).Msg("started serving requests")
+	sublog.Info().Int("port", deps.config.HTTPPort).Msg("started serving requests")
 
 	// setup middleware chain
 	router := mux.NewRouter()
@@ -221,6 +262,8 @@ func startServer(deps *Dependencies) {
 	router.HandleFunc("/logout/{provider}", app.requestHandler(signoutHandler(deps))).Methods("GET")
 
 	router.HandleFunc("/ping", pingHandler()).Methods("GET")
+	router.HandleFunc("/healthz", healthzHandler()).Methods("GET")
+	router.HandleFunc("/readyz", readyzHandler(deps)).Methods("GET")
 	router.HandleFunc("/internal/cspviolations", app.requestHandler(JSONReportHandler(deps))).Methods("GET")
 	router.HandleFunc("/api/v1/{endpoint}", app.requestHandler(apiV1Handler(deps))).Methods("GET")
 	router.Handle("/metrics", promhttp.Handler())
@@ -228,9 +271,16 @@ func startServer(deps *Dependencies) {
 	router.HandleFunc("/profile/{status}", app.requestHandler(profileHandler(deps))).Methods("GET")
 	router.HandleFunc("/desktop", app.requestHandler(desktopHandler(deps))).Methods("GET")
 	router.HandleFunc("/view/{symbol}", app.requestHandler(viewTickerDailyHandler(deps))).Methods("GET")
+	router.HandleFunc("/compare", app.requestHandler(compareHandler(deps))).Methods("GET")
+	router.HandleFunc("/portfolio", app.requestHandler(portfolioHandler(deps))).Methods("GET")
+	router.HandleFunc("/index/{symbol}", app.requestHandler(viewMarketIndexHandler(deps))).Methods("GET")
+	router.HandleFunc("/watchlist/{token}", app.requestHandler(viewWatchlistHandler(deps))).Methods("GET")
+	router.HandleFunc("/import", app.requestHandler(importHandler(deps))).Methods("GET", "POST")
+	router.HandleFunc("/export/{what:watchlists|holdings|transactions}.{format:csv|json}", app.requestHandler(exportHandler(deps))).Methods("GET")
 	router.HandleFunc("/view/{symbol}/{articleEId}", app.requestHandler(viewTickerArticleHandler(deps))).Methods("GET")
 	router.HandleFunc("/{action:bought|sold}/{symbol}/{acronym}", app.requestHandler(transactionHandler(deps))).Methods("POST")
 	router.HandleFunc("/search/{type}", app.requestHandler(searchHandler(deps))).Methods("POST")
+	router.HandleFunc("/admin/jobs", app.requestHandler(adminJobsHandler(deps))).Methods("GET", "POST")
 
 	router.HandleFunc("/about", app.requestHandler(staticPageHandler(deps, "about"))).Methods("GET")
 	router.HandleFunc("/terms", app.requestHandler(staticPageHandler(deps, "terms"))).Methods("GET")
@@ -241,14 +291,36 @@ func startServer(deps *Dependencies) {
 	// starup or die
 	server := &http.Server{
 		Handler:      router,
-		Addr:         ":" + strconv.Itoa(httpPort),
+		Addr:         ":" + strconv.Itoa(deps.config.HTTPPort),
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
 	}
 
-	if err := server.ListenAndServe(); err != nil {
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// on SIGTERM (systemd stop) or ^C, report not-ready for long enough that
+	// the load balancer's health checks see it and stop sending us traffic,
+	// then let in-flight requests finish before we exit
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+
+	select {
+	case err := <-serverErr:
 		sublog.Fatal().Err(err).Msg("ended abnormally")
-	} else {
-		sublog.Info().Msg("stopped serving requests")
+	case sig := <-stop:
+		sublog.Info().Str("signal", sig.String()).Msg("shutting down")
+	}
+	deps.shuttingDown.Store(true)
+	time.Sleep(shutdownDrainDelay * time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		sublog.Error().Err(err).Msg("failed to drain requests before shutdown timeout")
+		return
 	}
+	sublog.Info().Msg("stopped serving requests")
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// object methods -------------------------------------------------------------
+
+// Factor is how many shares each pre-split share became: "4:1" (or "4/1") is
+// 4, a 1-for-10 reverse split "1:10" is 0.1
+func (ts TickerSplit) Factor() (float64, error) {
+	parts := strings.FieldsFunc(ts.SplitRatio, func(r rune) bool { return r == ':' || r == '/' })
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("unrecognized split ratio %q", ts.SplitRatio)
+	}
+	after, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("unrecognized split ratio %q", ts.SplitRatio)
+	}
+	before, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil || before == 0 || after == 0 {
+		return 0, fmt.Errorf("unrecognized split ratio %q", ts.SplitRatio)
+	}
+	return after / before, nil
+}
+
+// splits take effect at the open, so anything dated the split day is already
+// in post-split shares
+func (ts TickerSplit) AppliesTo(date time.Time) bool {
+	return date.Format(sqlDateParseType) < ts.SplitDate.Format(sqlDateParseType)
+}
+
+// the range's EODs, split-adjusted, in the range's interval
+func (t Ticker) getChartEODs(deps *Dependencies, sublog zerolog.Logger, chartRange ChartRange, location *time.Location) ([]TickerDaily, error) {
+	dailies, err := deps.tickers.GetTickerDailies(sublog, t.TickerId, chartRange.fromDate(), chartRange.beforeDate())
+	if err != nil {
+		return dailies, err
+	}
+
+	// the dividends are in the same unadjusted shares as the dailies, so
+	// they go back in before the split adjustment
+	if chartRange.TotalReturn {
+		dividends, err := t.getDividends(deps, sublog)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed to get dividends, charting price return only")
+		} else {
+			dailies = reinvestDividends(dailies, dividends, location)
+		}
+	}
+
+	splits, err := t.getSplits(deps, sublog)
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed to get splits, charting unadjusted prices")
+	} else {
+		dailies = adjustDailiesForSplits(sublog, dailies, splits)
+	}
+
+	dailies = chartRange.clip(dailies, location)
+	return resampleDailies(dailies, chartRange.interval(), location), nil
+}
+
+// misc -----------------------------------------------------------------------
+
+func adjustDailiesForSplits(sublog zerolog.Logger, dailies []TickerDaily, splits []TickerSplit) []TickerDaily {
+	if len(splits) == 0 {
+		return dailies
+	}
+
+	adjusted := make([]TickerDaily, 0, len(dailies))
+	for _, daily := range dailies {
+		factor := splitFactorSince(sublog, splits, daily.PriceDatetime)
+		if factor != 1.0 {
+			daily.OpenPrice /= factor
+			daily.HighPrice /= factor
+			daily.LowPrice /= factor
+			daily.ClosePrice /= factor
+			daily.Volume = int64(float64(daily.Volume) * factor)
+		}
+		adjusted = append(adjusted, daily)
+	}
+	return adjusted
+}
+
+// the other way: a price restated in today's shares back to what it traded at
+func unadjustDailyForSplits(sublog zerolog.Logger, daily TickerDaily, splits []TickerSplit) TickerDaily {
+	factor := splitFactorSince(sublog, splits, daily.PriceDatetime)
+	if factor != 1.0 {
+		daily.OpenPrice *= factor
+		daily.HighPrice *= factor
+		daily.LowPrice *= factor
+		daily.ClosePrice *= factor
+		daily.Volume = int64(float64(daily.Volume) / factor)
+	}
+	return daily
+}
+
+// how many of today's shares one share held on date has become
+func splitFactorSince(sublog zerolog.Logger, splits []TickerSplit, date time.Time) float64 {
+	factor := 1.0
+	for _, split := range splits {
+		if !split.AppliesTo(date) {
+			continue
+		}
+		splitFactor, err := split.Factor()
+		if err != nil {
+			sublog.Warn().Err(err).Msg("skipping split")
+			continue
+		}
+		factor *= splitFactor
+	}
+	return factor
+}
+
+// restate open lots for a split: more shares, each costing proportionally less,
+// so the lot's total cost basis is unchanged
+func splitLots(lots []Lot, factor float64) {
+	for x := range lots {
+		lots[x].Shares *= factor
+		lots[x].CostPerShare /= factor
+	}
+}
+
+// a new split changes the share count of everyone holding the ticker
+func recalculateTickerHoldings(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) {
+	db := deps.db
+
+	var holding Holding
+
+	rows, err := db.Queryx("SELECT * FROM holding WHERE ticker_id=?", ticker.TickerId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on SELECT")
+		return
+	}
+	defer rows.Close()
+
+	holdings := make([]Holding, 0)
+	for rows.Next() {
+		err = rows.StructScan(&holding)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+		} else {
+			holdings = append(holdings, holding)
+		}
+	}
+
+	for _, holding := range holdings {
+		err = holding.recalculate(deps, sublog)
+		if err != nil {
+			sublog.Error().Err(err).Uint64("holding_id", holding.HoldingId).Msg("failed to recalculate holding")
+		}
+	}
+}
@@ -2,12 +2,19 @@ var chart = $('#chartCall').data('chart');
 var symbol = $('#chartCall').data('symbol');
 var nonce = $('#chartCall').data('nonce');
 var timespan = $('#chartCall').data('timespan');
+var benchmark = $('#chartCall').data('benchmark');
+var totalreturn = $('#chartCall').data('totalreturn');
+var indicators; // left undefined, the chart draws its default indicators
+var interval = ''; // empty lets the server pick one from the timespan
+var from = '';
+var to = '';
 
 function loadChart(chart, symbol, timespan) {
     var response = $.ajax({
         type: 'GET',
         headers: { 'X-Nonce': nonce },
-        url: '/api/v1/chart?chart=' + chart + '&symbol=' + symbol + '&timespan=' +timespan,
+        url: '/api/v1/chart?chart=' + chart + '&symbol=' + symbol + '&timespan=' +timespan + (benchmark ? '&benchmark=' + benchmark : '') + (totalreturn ? '&totalreturn=1' : '') + (indicators !== undefined ? '&indicators=' + indicators : '') +
+            (interval ? '&interval=' + interval : '') + (from ? '&from=' + from : '') + (to ? '&to=' + to : ''),
         async: true,
         success: function(response) {
             if (response.success == true) {
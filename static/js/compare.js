@@ -0,0 +1,5 @@
+$(document).ready(function() {
+    if ($('#chartCall').length) {
+        loadChart(chart, symbol, timespan)
+    }
+});
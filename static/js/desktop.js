@@ -1,13 +1,16 @@
 $(document).ready(function() {
-    if (is_market_open) {
+    if (quotes_session === 'regular') {
         $('#auto_refresh_time').text('20 sec');
         setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000);
+    } else if (quotes_session === 'pre' || quotes_session === 'post') { // 3 times slower in extended hours
+        $('#auto_refresh_time').text('1 min');
+        setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000 * 3);
     } else { // 15 times slower if market isn't even open (so every 300 sec instead of 20 sec)
         $('#auto_refresh_time').text('5 min');
         setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000 * 15);
     }
 
-    if (is_market_open) {
+    if (quotes_market_open) {
         $("#ticker_quote_info").show();
         $("#auto_refresh").show();
     } else {
@@ -27,11 +30,18 @@ $(document).ready(function() {
         }
     })
 
+    // on a watchlist tab the cards come off the watchlist, not the recents
+    var watchlist = $('#watchlist_toolbar').data('list') || '';
+
     $('.btn-close').on('click', function() {
         var symbol = $(this).data('symbol')
+        var url = '/api/v1/recents?remove=' + symbol;
+        if (watchlist !== '') {
+            url = '/api/v1/watchlists?watchlist=' + watchlist + '&remove=' + symbol;
+        }
         var response = $.ajax({
             type: 'GET',
-            url: '/api/v1/recents?remove=' + symbol,
+            url: url,
             async: false,
             success: function(response) {
                 if (response.success) {
@@ -40,4 +50,87 @@ $(document).ready(function() {
             }
         });
     })
-});
\ No newline at end of file
+
+    $('#watchlist_create').on('click', function(e) {
+        e.preventDefault();
+        var name = prompt('Name for the new watchlist');
+        if (name) {
+            watchlistCall({create: name}, function(response) {
+                window.location = '/desktop?list=' + response.data.id;
+            });
+        }
+    })
+
+    $('#watchlist_add_form').on('submit', function(e) {
+        e.preventDefault();
+        var symbols = $('#watchlist_add_symbols').val().trim();
+        if (symbols !== '') {
+            watchlistCall({watchlist: watchlist, add: symbols, note: $('#watchlist_add_note').val()});
+        }
+    })
+
+    $('#watchlist_rename').on('click', function(e) {
+        e.preventDefault();
+        var name = prompt('New name for the watchlist', $(this).data('name'));
+        if (name) {
+            watchlistCall({watchlist: watchlist, rename: name});
+        }
+    })
+
+    $('#watchlist_share').on('click', function(e) {
+        e.preventDefault();
+        watchlistCall({watchlist: watchlist, share: 1});
+    })
+
+    $('#watchlist_unshare').on('click', function(e) {
+        e.preventDefault();
+        watchlistCall({watchlist: watchlist, share: 0});
+    })
+
+    $('#watchlist_delete').on('click', function(e) {
+        e.preventDefault();
+        if (confirm('Delete this watchlist?')) {
+            watchlistCall({watchlist: watchlist, delete: 1}, function() {
+                window.location = '/desktop';
+            });
+        }
+    })
+
+    $('.watchlist-note').on('click', function() {
+        if (watchlist === '') {
+            return;
+        }
+        var note = prompt('Note for ' + $(this).data('symbol'), $(this).data('note'));
+        if (note !== null) {
+            watchlistCall({watchlist: watchlist, symbol: $(this).data('symbol'), note: note});
+        }
+    })
+
+    // cards are in list order, so the new position is one step from this one
+    $('.watchlist-move').on('click', function(e) {
+        e.preventDefault();
+        var card = $('#' + $(this).data('symbol') + '_card');
+        var position = card.parent().children('.card').index(card) + 1 + $(this).data('step');
+        if (position >= 1) {
+            watchlistCall({watchlist: watchlist, move: $(this).data('symbol'), position: position});
+        }
+    })
+});
+
+// change a watchlist, then reload the tab (or do whatever done says) to show it
+function watchlistCall(params, done) {
+    $.ajax({
+        type: 'GET',
+        url: '/api/v1/watchlists',
+        data: params,
+        success: function(response) {
+            if (!response.success) {
+                alert(response.message.replace(/^failure: /, ''));
+            } else if (done) {
+                done(response);
+            } else {
+                window.location.reload();
+            }
+        }
+    });
+}
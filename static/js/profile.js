@@ -0,0 +1,16 @@
+$(document).ready(function() {
+    $('#costbasis_method').on('change', function() {
+        var method = $(this).val();
+        $.ajax({
+            type: 'GET',
+            url: '/api/v1/costbasis?method=' + method,
+            success: function(response) {
+                if (response.success) {
+                    $('#costbasis_status').removeClass('text-danger').addClass('text-success').text('saved, holdings recalculated');
+                } else {
+                    $('#costbasis_status').removeClass('text-success').addClass('text-danger').text(response.message);
+                }
+            }
+        });
+    })
+});
@@ -4,6 +4,8 @@ var scriptName = lastScript;
 
 var symbols = scriptName.getAttribute('data-symbols');
 var is_market_open = scriptName.getAttribute('data-is-market-open') === 'true';
+var quotes_market_open = scriptName.getAttribute('data-quotes-market-open') === 'true';
+var quotes_session = scriptName.getAttribute('data-quotes-session') || 'closed';
 
 var quote_refresh = 20; // scriptName.getAttribute('data-quote-refresh');
 var update_count = 180; // every 20 sec for 1 hour = 180 refreshes
@@ -22,6 +24,8 @@ function quoteRefresh() {
         async: true,
         success: function(response) {
             is_market_open = response.data.is_market_open;
+            quotes_market_open = response.data.quotes_market_open;
+            quotes_session = response.data.quotes_session;
             symbols.split(',').forEach(function(item) {
                 if (item == '') { return; }
                 symbol = item;
@@ -29,6 +33,17 @@ function quoteRefresh() {
                     phaseChangeSymbol(response, symbol, item)
                 });
 
+                // pre-market and after-hours trading, when there is any
+                if (typeof response.data[symbol+':ext_price'] !== 'undefined') {
+                    $('#'+symbol+'_ext_label').text(response.data[symbol+':session'] === 'pre' ? 'Pre-market' : 'After hours');
+                    ['ext_price', 'ext_change_amt', 'ext_change_pct', 'ext_asof'].forEach(function(item) {
+                        phaseChangeSymbol(response, symbol, item)
+                    });
+                    $('#'+symbol+'_ext_info').removeClass('hide');
+                } else {
+                    $('#'+symbol+'_ext_info').addClass('hide');
+                }
+
                 if (response.data.symbol+':change_dir' === 'down' && !$('#'+symbol+'_change_indicator').hasClass('fa-arrow-down')) {
                     $('#'+symbol+'_change_color').animate({opacity: 0}, 400, function() {
                         $('#'+symbol+'_change_color').removeClass('text-success').addClass('text-danger').animate({opacity: 1}, 400)
@@ -67,14 +82,18 @@ function quoteRefresh() {
                 $('#updating_news_now').addClass('hide');
             }
 
-            if (is_market_open && $('#is_market_open_color').hasClass('text-danger')) {
+            if (quotes_market_open) {
                 $('#ticker_quote_info').show();
                 $('#ticker_eod_info').hide();
+            } else {
+                $('#ticker_quote_info').hide();
+                $('#ticker_eod_info').show();
+            }
+
+            if (is_market_open && $('#is_market_open_color').hasClass('text-danger')) {
                 $('#is_market_open_color').animate({opacity: 0}, 400, function() { $('#is_market_open_color').removeClass('text-danger').addClass('text-success').animate({opacity: 1}, 400) });
                 $('#is_market_open').animate({opacity: 0}, 400, function() { $('#is_market_open').text('TRADING').animate({opacity: 1}, 400) });
             } else if (!is_market_open && $('#is_market_open_color').hasClass('text-success')) {
-                $('#ticker_quote_info').hide();
-                $('#ticker_eod_info').show();
                 $('#is_market_open_color').animate({opacity: 0}, 400, function() { ($('#is_market_open_color').removeClass('text-success').addClass('text-danger').animate({opacity: 1}, 400)) });
                 $('#is_market_open').animate({opacity: 0}, 400, function() { $('#is_market_open').text('CLOSED').animate({opacity: 1}, 400) });
             }
@@ -83,9 +102,12 @@ function quoteRefresh() {
             setTimeout(function() { $('#auto_refresh_working').addClass('hide'); }, 1000);
             if (update_count > 1) {
                 update_count--;
-                if (is_market_open) {
+                if (quotes_session === 'regular') {
                     $('#auto_refresh_time').text('20 sec');
                     setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000);
+                } else if (quotes_session === 'pre' || quotes_session === 'post') { // 3 times slower in extended hours
+                    $('#auto_refresh_time').text('1 min');
+                    setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000 * 3);
                 } else { // 15 times slower if market isn't even open (so every 300 sec instead of 20 sec)
                     $('#auto_refresh_time').text('5 min');
                     setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000 * 15);
@@ -1,6 +1,7 @@
 var showing = 'symbolLine';
 
 $(document).ready(function() {
+    indicators = $('input[name=pickIndicator]:checked').map(function() { return this.value; }).get().join(',');
     loadChart("symbolLine", symbol, timespan)
 
     setTimeout(function() {
@@ -12,7 +13,7 @@ $(document).ready(function() {
         this.innerHTML = '<a href="/view/' + symbol + '">' + symbol + '</a>';
     });
 
-    if (is_market_open) {
+    if (quotes_market_open) {
         $("#ticker_quote_info").show();
     } else {
         $("#ticker_eod_info").show();
@@ -42,6 +43,28 @@ $(document).ready(function() {
     });
 
 
+    $('input[name=pickInterval], input[name=pickFrom], input[name=pickTo]').on('change', function() {
+        chart = $('input[name=pickChart]:checked').attr('id');
+        timespan = $('input[name=pickTimespan]:checked').data('timespan')
+        interval = $('input[name=pickInterval]:checked').val();
+        from = $('#pickFrom').val();
+        to = $('#pickTo').val();
+        $('#tickerChart').fadeOut('fast', function() {
+            loadChart(chart, symbol, timespan);
+            $('#tickerChart').fadeIn('fast');
+        });
+    });
+
+    $('input[name=pickIndicator]').on('change', function() {
+        chart = $('input[name=pickChart]:checked').attr('id');
+        timespan = $('input[name=pickTimespan]:checked').data('timespan')
+        indicators = $('input[name=pickIndicator]:checked').map(function() { return this.value; }).get().join(',');
+        $('#tickerChart').fadeOut('fast', function() {
+            loadChart(chart, symbol, timespan);
+            $('#tickerChart').fadeIn('fast');
+        });
+    });
+
     $('input[name=pickTimespan]').on('change', function() {
         chart = $('input[name=pickChart]:checked').attr('id');
         timespan = $('input[name=pickTimespan]:checked').data('timespan')
@@ -58,5 +81,6 @@ $(document).ready(function() {
     });
 
     $('#PurchaseDate').val(new Date().toDateInputValue());
+    $('#SoldDate').val(new Date().toDateInputValue());
 
 });
@@ -0,0 +1,12 @@
+$(document).ready(function() {
+    loadChart(chart, symbol, timespan)
+
+    $('input[name=pickChart], input[name=pickTimespan]').on('change', function() {
+        chart = $('input[name=pickChart]:checked').attr('id');
+        timespan = $('input[name=pickTimespan]:checked').data('timespan')
+        $('#tickerChart').fadeOut('fast', function() {
+            loadChart(chart, symbol, timespan);
+            $('#tickerChart').fadeIn('fast');
+        });
+    });
+});
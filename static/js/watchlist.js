@@ -0,0 +1,12 @@
+$(document).ready(function() {
+    if (typeof quotes_session === 'undefined') {
+        return;
+    }
+    if (quotes_session === 'regular') {
+        setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000);
+    } else if (quotes_session === 'pre' || quotes_session === 'post') { // 3 times slower in extended hours
+        setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000 * 3);
+    } else { // 15 times slower if market isn't even open
+        setTimeout(function() { quoteRefresh(); }, quote_refresh * 1000 * 15);
+    }
+});
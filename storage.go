@@ -0,0 +1,111 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/rs/zerolog"
+)
+
+//go:embed schema_sqlite.sql
+var sqliteSchema string
+
+// secrets the local backend can make up for itself on first run, by length
+var localGeneratedSecrets = map[string]int{
+	"cookie_auth_key":           64,
+	"cookie_encryption_key":     32,
+	"skip64_alert":              10,
+	"skip64_article":            10,
+	"skip64_exchange":           10,
+	"skip64_ticker":             10,
+	"skip64_ticker_description": 10,
+	"skip64_watcher":            10,
+	"skip64_watchlist":          10,
+}
+
+// misc -----------------------------------------------------------------------
+
+// "aws" is production: Aurora, DynamoDB sessions, SQS and S3. "local" keeps
+// everything under LocalDataDir (a SQLite file, session files, blobs and a
+// secrets.json) with an in-process queue, so it runs without any AWS at all
+func setupStorage(deps *Dependencies) {
+	config := deps.config
+	sublog := deps.logger
+
+	switch config.StorageBackend {
+	case "aws":
+		setupAWS(deps)
+		setupSecrets(deps)
+		setupRepositories(deps, mysqlDialect)
+		deps.queue = sqsQueue{deps.awssess}
+		deps.blobs = s3BlobStore{deps.awssess, config.PrivateBucketName}
+	case "local":
+		setupLocalStorage(deps)
+		setupRepositories(deps, sqliteDialect)
+		deps.queue = newLocalQueue()
+		deps.blobs = localBlobStore{filepath.Join(config.LocalDataDir, "blobs")}
+	default:
+		sublog.Fatal().Str("backend", config.StorageBackend).Msg("unknown storage backend")
+	}
+}
+
+func setupLocalStorage(deps *Dependencies) {
+	sublog := deps.logger
+	dataDir := deps.config.LocalDataDir
+
+	err := os.MkdirAll(dataDir, 0700)
+	if err != nil {
+		sublog.Fatal().Err(err).Str("dir", dataDir).Msg("failed to create local data dir")
+	}
+
+	dbFile := filepath.Join(dataDir, "stockwatch.db")
+	db, err := sqlx.Connect(sqliteDialect, dbFile+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		sublog.Fatal().Err(err).Str("db", dbFile).Msg("failed to open sqlite db")
+	}
+	_, err = db.Exec(sqliteSchema)
+	if err != nil {
+		sublog.Fatal().Err(err).Str("db", dbFile).Msg("failed to apply sqlite schema")
+	}
+	deps.db = db
+
+	deps.secrets = loadLocalSecrets(*sublog, dataDir)
+}
+
+// API keys and such go in secrets.json by hand; anything we can generate is
+// filled in and saved back so sessions and EIds survive a restart
+func loadLocalSecrets(sublog zerolog.Logger, dataDir string) map[string]string {
+	secretsFile := filepath.Join(dataDir, "secrets.json")
+	secrets := make(map[string]string)
+
+	contents, err := os.ReadFile(secretsFile)
+	if err == nil {
+		err = json.Unmarshal(contents, &secrets)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		sublog.Fatal().Err(err).Str("file", secretsFile).Msg("failed to read local secrets")
+	}
+
+	generated := false
+	for key, length := range localGeneratedSecrets {
+		if secrets[key] == "" {
+			secrets[key] = RandStringMask(length)
+			generated = true
+		}
+	}
+	if generated {
+		contents, _ = json.MarshalIndent(secrets, "", "  ")
+		err = os.WriteFile(secretsFile, contents, 0600)
+		if err != nil {
+			sublog.Warn().Err(err).Str("file", secretsFile).Msg("failed to save generated secrets")
+		}
+	}
+
+	return secrets
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Task is one piece of work for the worker. Tasks with the same Key that are
+// enqueued within the queue's dedupe window of each other collapse into the
+// first; an empty Key is never deduped
+type Task struct {
+	Action string
+	Key    string
+	Body   interface{}
+}
+
+// TaskQueue sits in front of a Queue so that a burst of page views asking for
+// the same refresh sends one message, not dozens. Enqueue reports whether the
+// task was actually sent
+type TaskQueue interface {
+	Enqueue(task Task) (bool, error)
+}
+
+// the claims live in redis so every web instance shares the same window
+type sqsTaskQueue struct {
+	queue     Queue
+	queueName string
+	redisPool *redis.Pool
+	window    time.Duration
+}
+
+// claims only need to be seen by this process, the same as the localQueue
+// they front
+type localTaskQueue struct {
+	queue     Queue
+	queueName string
+	window    time.Duration
+	mu        sync.Mutex
+	claims    map[string]time.Time // key -> when the claim expires
+}
+
+// object methods -------------------------------------------------------------
+
+func (tq sqsTaskQueue) Enqueue(task Task) (bool, error) {
+	if task.Key != "" {
+		claimed, err := tq.claim(task.Key)
+		if err != nil {
+			// a duplicate task beats a lost one, so send it anyway
+			claimed = true
+		}
+		if !claimed {
+			return false, nil
+		}
+	}
+
+	err := sendTask(tq.queue, tq.queueName, task)
+	if err != nil && task.Key != "" {
+		tq.release(task.Key)
+	}
+	return err == nil, err
+}
+
+func (tq sqsTaskQueue) claim(key string) (bool, error) {
+	redisConn := tq.redisPool.Get()
+	defer redisConn.Close()
+
+	// SET NX answers nil, not OK, when someone else holds the key
+	_, err := redis.String(redisConn.Do("SET", "taskqueue/"+key, time.Now().Unix(), "NX", "EX", int(tq.window.Seconds())))
+	if err == redis.ErrNil {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (tq sqsTaskQueue) release(key string) {
+	redisConn := tq.redisPool.Get()
+	defer redisConn.Close()
+
+	redisConn.Do("DEL", "taskqueue/"+key)
+}
+
+func (tq *localTaskQueue) Enqueue(task Task) (bool, error) {
+	if task.Key != "" && !tq.claim(task.Key) {
+		return false, nil
+	}
+
+	err := sendTask(tq.queue, tq.queueName, task)
+	if err != nil && task.Key != "" {
+		tq.release(task.Key)
+	}
+	return err == nil, err
+}
+
+func (tq *localTaskQueue) claim(key string) bool {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+
+	now := time.Now()
+	for claimed, expires := range tq.claims {
+		if expires.Before(now) {
+			delete(tq.claims, claimed)
+		}
+	}
+	if _, ok := tq.claims[key]; ok {
+		return false
+	}
+	tq.claims[key] = now.Add(tq.window)
+	return true
+}
+
+func (tq *localTaskQueue) release(key string) {
+	tq.mu.Lock()
+	defer tq.mu.Unlock()
+
+	delete(tq.claims, key)
+}
+
+// misc -----------------------------------------------------------------------
+
+func sendTask(queue Queue, queueName string, task Task) error {
+	messageBytes, err := json.Marshal(task.Body)
+	if err != nil {
+		return err
+	}
+	return queue.Send(queueName, string(messageBytes), map[string]string{"action": task.Action})
+}
+
+func newLocalTaskQueue(queue Queue, queueName string, window time.Duration) *localTaskQueue {
+	return &localTaskQueue{queue: queue, queueName: queueName, window: window, claims: map[string]time.Time{}}
+}
+
+// needs the Queue from setupStorage and, for aws, the pool from setupRedis
+func setupTaskQueue(deps *Dependencies) {
+	config := deps.config
+	window := taskDedupeWindow * time.Second
+
+	if config.StorageBackend == "local" {
+		deps.tasks = newLocalTaskQueue(deps.queue, config.TickerQueueName, window)
+		return
+	}
+	deps.tasks = sqsTaskQueue{queue: deps.queue, queueName: config.TickerQueueName, redisPool: deps.redisPool, window: window}
+}
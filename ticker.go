@@ -1,13 +1,14 @@
 package main
 
 import (
-	"bytes"
 	"database/sql"
 	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"net/http"
+	"net/url"
 	"regexp"
 	"runtime"
 	"sort"
@@ -15,15 +16,9 @@ import (
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/s3"
-	"github.com/aws/aws-sdk-go/service/sqs"
 	"github.com/gomodule/redigo/redis"
-	"github.com/jmoiron/sqlx"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
-	"github.com/weirdtangent/mytime"
-	"github.com/weirdtangent/yhfinance"
 
 	"golang.org/x/text/cases"
 	"golang.org/x/text/language"
@@ -97,6 +92,8 @@ type TickerDescription struct {
 	UpdateDatetime      time.Time `db:"update_datetime"`
 }
 
+// one sample of the live quote while the exchange is open; PriceDate is the
+// quote time in UTC and Volume is the day's running total at that moment
 type TickerIntraday struct {
 	TickerIntradayId uint64 `db:"intraday_id"`
 	EId              string
@@ -136,16 +133,36 @@ type TickerSplit struct {
 	UpdateDatetime time.Time `db:"update_datetime"`
 }
 
+// TickerDividend is a cash dividend per share as it was paid, before any later
+// split, dated by its ex-dividend date: whoever held the shares going into
+// that day gets it
+type TickerDividend struct {
+	TickerDividendId uint64 `db:"ticker_dividend_id"`
+	EId              string
+	TickerId         uint64    `db:"ticker_id"`
+	DividendDate     time.Time `db:"dividend_date"`
+	Amount           float64   `db:"amount"`
+	CreateDatetime   time.Time `db:"create_datetime"`
+	UpdateDatetime   time.Time `db:"update_datetime"`
+}
+
 type TickerQuote struct {
 	Ticker      Ticker
 	Exchange    Exchange
 	Description TickerDescription
-	LiveQuote   yhfinance.YHQuote
+	LiveQuote   Quote
 	LastEOD     TickerDaily
+	MarketOpen  bool      // the ticker's own exchange, not the home market
+	Session     string    // pre, regular, post or closed, also on the ticker's exchange
+	NextOpen    time.Time // in the exchange's time zone
+	Extended    ExtendedHours
 	ChangeDir   string
 	ChangeAmt   float32
 	ChangePct   float32
 	Locked      bool
+	Note        string // the watchlist entry's note, when shown on a watchlist
+	Holding     Holding
+	HoldingPL   HoldingPL
 	FavIcon     string
 	SymbolNews  struct {
 		LastChecked time.Time
@@ -163,61 +180,43 @@ type TickerDetails struct {
 // object methods -------------------------------------------------------------
 
 func (t *Ticker) Update(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
-	var update = "UPDATE ticker SET ticker_type=?, ticker_market=?, exchange_id=?, ticker_name=?, company_name=?, address=?, city=?, state=?, zip=?, country=?, website=?, phone=?, sector=?, industry=?, market_price=?, market_prev_close=?, market_volume=?, market_price_datetime=?, favicon_s3key=?, fetch_datetime=now() WHERE ticker_id=?"
-	_, err := db.Exec(update, t.TickerType, t.TickerMarket, t.ExchangeId, t.TickerName, t.CompanyName, t.Address, t.City, t.State, t.Zip, t.Country, t.Website, t.Phone, t.Sector, t.Industry, t.MarketPrice, t.MarketPrevClose, t.MarketVolume, t.MarketPriceDatetime, t.FavIconS3Key, t.TickerId)
-	return err
+	return deps.tickers.UpdateTicker(*t)
 }
 
-func (t *Ticker) UpdateTickerWithLiveQuote(deps *Dependencies, sublog zerolog.Logger, quote yhfinance.YHQuote) error {
-	db := deps.db
-
+func (t *Ticker) UpdateTickerWithLiveQuote(deps *Dependencies, sublog zerolog.Logger, quote Quote) error {
 	t.MarketPrice = quote.QuotePrice
 	t.MarketPrevClose = quote.QuotePrevClose
 	t.MarketVolume = quote.QuoteVolume
 	t.MarketPriceDatetime = time.Unix(quote.QuoteTime, 0)
 
-	var update = "UPDATE ticker SET market_price=?, market_volume=?, market_prev_close=?, market_price_datetime=? WHERE ticker_id=?"
-	_, err := db.Exec(update, t.MarketPrice, t.MarketVolume, t.MarketPrevClose, t.MarketPriceDatetime, t.TickerId)
-	return err
+	return deps.tickers.UpdateTickerQuote(*t)
 }
 
 func (t *Ticker) getIdBySymbol(deps *Dependencies, sublog zerolog.Logger) (uint64, error) {
-	db := deps.db
-
-	var tickerId uint64
-	err := db.QueryRowx("SELECT ticker_id FROM ticker WHERE ticker_symbol=?", t.TickerSymbol).Scan(&tickerId)
-	return tickerId, err
+	ticker, err := deps.tickers.GetTickerBySymbol(t.TickerSymbol)
+	return ticker.TickerId, err
 }
 
 func (t *Ticker) getById(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
-	err := db.QueryRowx("SELECT * FROM ticker WHERE ticker_id=?", t.TickerId).StructScan(t)
+	ticker, err := deps.tickers.GetTicker(t.TickerId)
+	if err == nil {
+		*t = ticker
+	}
 	return err
 }
 
 func (t *Ticker) create(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
 	if t.TickerSymbol == "" {
 		// refusing to add ticker with blank symbol
 		return nil
 	}
 
-	insert := "INSERT INTO ticker SET ticker_symbol=?, ticker_type=?, ticker_market=?, exchange_id=?, ticker_name=?, company_name=?, address=?, city=?, state=?, zip=?, country=?, website=?, phone=?, sector=?, industry=?, market_price=?, market_prev_close=?, market_volume=?, fetch_datetime=?"
-	res, err := db.Exec(insert, t.TickerSymbol, t.TickerType, t.TickerMarket, t.ExchangeId, t.TickerName, t.CompanyName, t.Address, t.City, t.State, t.Zip, t.Country, t.Website, t.Phone, t.Sector, t.Industry, t.MarketPrice, t.MarketPrevClose, t.MarketVolume, t.FetchDatetime)
+	tickerId, err := deps.tickers.CreateTicker(*t)
 	if err != nil {
 		sublog.Fatal().Err(err).Msg("failed on INSERT")
 		return err
 	}
-	tickerId, err := res.LastInsertId()
-	if err != nil {
-		sublog.Fatal().Err(err).Msg("failed on LAST_INSERTID")
-		return err
-	}
-	t.TickerId = uint64(tickerId)
+	t.TickerId = tickerId
 	return nil
 }
 
@@ -243,8 +242,6 @@ func (t *Ticker) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error
 }
 
 func (t *Ticker) createOrUpdateAttribute(deps *Dependencies, sublog zerolog.Logger, attributeName, attributeComment, attributeValue string) error {
-	db := deps.db
-
 	if attributeName == "" || attributeValue == "" {
 		return nil
 	}
@@ -252,199 +249,128 @@ func (t *Ticker) createOrUpdateAttribute(deps *Dependencies, sublog zerolog.Logg
 	attribute := TickerAttribute{0, "", t.TickerId, attributeName, sql.NullString{}, attributeComment, attributeValue, time.Now(), time.Now()}
 	err := attribute.getByUniqueKey(deps)
 	if err == nil {
-		var update = "UPDATE ticker_attribute SET attribute_value=? WHERE ticker_id=? AND attribute_name=? AND attribute_comment=?"
-		db.Exec(update, attributeValue, t.TickerId, attributeName, attributeComment)
+		attribute.AttributeComment = attributeComment
+		attribute.AttributeValue = attributeValue
+		deps.tickers.UpdateTickerAttribute(attribute)
 		return nil
 	}
 
-	var insert = "INSERT INTO ticker_attribute SET ticker_id=?, attribute_name=?, attribute_comment=?, attribute_value=?"
-	db.Exec(insert, t.TickerId, attributeName, attributeComment, attributeValue)
+	deps.tickers.CreateTickerAttribute(attribute)
 	return nil
 }
 
-// if it is a workday after 4 and we don't have the EOD, or we don't have the prior workday EOD, get them
+// if we don't have the EOD for the last full session on the ticker's
+// exchange, go get them
 func (t *Ticker) needEODs(deps *Dependencies, sublog zerolog.Logger) bool {
-	EasternTZ, _ := time.LoadLocation("America/New_York")
-	currentDate := time.Now().In(EasternTZ)
-	currentTimeStr := currentDate.Format("1505")
-	currentDateStr := currentDate.Format("2006-01-02")
-	currentWeekday := currentDate.Weekday()
-
-	todayEOD := t.haveEODForDate(deps, currentDateStr)
-
-	// if it's a workday and the market closed for the day and we don't have today's EOD, then YES
-	if currentWeekday != time.Saturday && currentWeekday != time.Sunday && currentTimeStr >= "1600" && !todayEOD {
-		return true
+	exchange, err := getExchangeById(deps, sublog, t.ExchangeId)
+	if err != nil {
+		sublog.Warn().Err(err).Uint64("exchange_id", t.ExchangeId).Msg("failed to get exchange, using the home market calendar")
+		exchange = homeExchange
 	}
 
-	priorWorkDate := mytime.PriorWorkDate(currentDate)
-	priorWorkDateStr := priorWorkDate.Format("2006-01-02")
-
-	priorEOD := t.haveEODForDate(deps, priorWorkDateStr)
-
-	return !priorEOD
+	closed := lastClose(exchange)
+	if closed.IsZero() {
+		return false
+	}
+	return !t.haveEODForDate(deps, closed.Format(sqlDateParseType))
 }
 
 func (t Ticker) haveEODForDate(deps *Dependencies, dateStr string) bool {
-	db := deps.db
-
 	// for past days, a time of exactly 9:30:00 is considered a locked-in value
 	// but if it is anything else, it needs to be after 16:00:00
-	var count int
-	err := db.QueryRowx("SELECT COUNT(*) FROM ticker_daily WHERE ticker_id=? AND price_datetime IS BETWEEN ? AND ?", t.TickerId, dateStr+"20:00:00", dateStr+"23:59:59").Scan(&count)
+	count, err := deps.tickers.CountTickerDailies(t.TickerId, dateStr+" 20:00:00", dateStr+" 23:59:59")
 	return err == nil && count > 0
 }
 
-func (t Ticker) EarliestEOD(db *sqlx.DB) (string, float64, error) {
-	type Earliest struct {
-		date  string
-		price float64
+func (t Ticker) isStale(deps *Dependencies, sublog zerolog.Logger) bool {
+	if t.FetchDatetime.Before(time.Now().Add(-24 * time.Hour)) {
+		return true
 	}
-	var earliest Earliest
-	err := db.QueryRowx("SELECT price_datetime, close_price FROM ticker_daily WHERE ticker_id=? ORDER BY price_datetime LIMIT 1", t.TickerId).StructScan(&earliest)
-	return earliest.date, earliest.price, err
-}
 
-func (t Ticker) getTickerEODs(deps *Dependencies, sublog zerolog.Logger, days int) ([]TickerDaily, error) {
-	db := deps.db
-
-	var ticker_daily TickerDaily
-
-	fromDate := mytime.DateStr(days * -1)
-	dailies := make([]TickerDaily, 0, days)
-
-	rows, err := db.Queryx(
-		`SELECT * FROM (
-           SELECT * FROM ticker_daily WHERE ticker_id=? AND volume > 0 AND price_datetime > ?
-		   ORDER BY price_datetime DESC) DT1
-		 ORDER BY price_datetime`,
-		t.TickerId, fromDate)
+	exchange, err := getExchangeById(deps, sublog, t.ExchangeId)
 	if err != nil {
-		return dailies, err
+		sublog.Warn().Err(err).Uint64("exchange_id", t.ExchangeId).Msg("failed to get exchange, using the home market calendar")
+		exchange = homeExchange
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		err = rows.StructScan(&ticker_daily)
-		if err != nil {
-			log.Warn().Err(err).Msg("error reading result rows")
-		} else {
-			dailies = append(dailies, ticker_daily)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return dailies, err
+	since := time.Since(t.FetchDatetime)
+	switch marketSession(exchange) {
+	case sessionRegular:
+		return since > time.Duration(deps.config.TickerReloadDelayOpen)*time.Minute
+	case sessionPre, sessionPost:
+		return since > time.Duration(deps.config.TickerReloadDelayExtended)*time.Minute
 	}
-
-	return dailies, nil
+	settled := lastClose(exchange).Add(time.Duration(deps.config.TickerReloadDelayClosed) * time.Minute)
+	return since > time.Duration(deps.config.TickerReloadDelayClosed)*time.Minute && t.FetchDatetime.Before(settled)
 }
 
-func (t Ticker) getLastTickerEOD(deps *Dependencies, sublog zerolog.Logger) (TickerDaily, error) {
-	db := deps.db
-
-	var eod TickerDaily
-
-	err := db.QueryRowx(`SELECT * FROM ticker_daily WHERE ticker_id=? ORDER BY price_datetime DESC`, t.TickerId).StructScan(&eod)
-	if err != nil {
-		return TickerDaily{}, err
+// keep a sample of the live quote for the intraday chart; a refresh that gets
+// the same quote time again adds nothing
+func (t Ticker) saveIntraday(deps *Dependencies, sublog zerolog.Logger, quote Quote) {
+	if quote.QuoteTime == 0 || quote.QuotePrice == 0 {
+		return
 	}
-
-	return eod, nil
-}
-
-func (t Ticker) getUpDowns(deps *Dependencies, sublog zerolog.Logger, daysAgo int) ([]TickerUpDown, error) {
-	db := deps.db
-
-	var tickerUpDown TickerUpDown
-	upDowns := make([]TickerUpDown, 0)
-
-	rows, err := db.Queryx(
-		`SELECT * FROM ticker_updown WHERE ticker_id=? AND TIMESTAMPDIFF(DAY, updown_date, NOW()) < ? ORDER BY updown_date DESC`,
-		t.TickerId, daysAgo)
+	intraday := TickerIntraday{
+		TickerId:  t.TickerId,
+		PriceDate: time.Unix(quote.QuoteTime, 0).UTC().Format(sqlDatetimeSearchType),
+		LastPrice: quote.QuotePrice,
+		Volume:    quote.QuoteVolume,
+	}
+	err := deps.tickers.CreateTickerIntraday(intraday)
 	if err != nil {
-		return upDowns, err
+		sublog.Warn().Err(err).Str("ticker", t.TickerSymbol).Msg("failed to save intraday sample")
 	}
-	defer rows.Close()
+}
 
-	for rows.Next() {
-		err = rows.StructScan(&tickerUpDown)
-		if err != nil {
-			sublog.Warn().Err(err).Msg("failed reading result rows")
-		} else {
-			upDowns = append(upDowns, tickerUpDown)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return upDowns, err
+// the samples for the exchange's current session or, outside of one, its
+// most recent
+func (t Ticker) getSessionIntradays(deps *Dependencies, sublog zerolog.Logger, exchange Exchange) ([]TickerIntraday, error) {
+	sessions := getMarketCalendar(exchange).latestSessions(time.Now())
+	if len(sessions) == 0 {
+		return []TickerIntraday{}, nil
 	}
 
-	return upDowns, nil
+	from := sessions[0][0].UTC().Format(sqlDatetimeSearchType)
+	to := sessions[len(sessions)-1][1].UTC().Format(sqlDatetimeSearchType)
+	return deps.tickers.GetTickerIntradays(sublog, t.TickerId, from, to)
 }
 
-func (t Ticker) getAttributes(deps *Dependencies, sublog zerolog.Logger) ([]TickerAttribute, error) {
-	db := deps.db
+func (t Ticker) getLastTickerEOD(deps *Dependencies, sublog zerolog.Logger) (TickerDaily, error) {
+	return deps.tickers.GetLastTickerDaily(t.TickerId)
+}
 
-	var tickerAttribute TickerAttribute
-	tickerAttributes := make([]TickerAttribute, 0)
+func (t Ticker) getUpDowns(deps *Dependencies, sublog zerolog.Logger, daysAgo int) ([]TickerUpDown, error) {
+	return deps.tickers.GetTickerUpDowns(sublog, t.TickerId, time.Now().AddDate(0, 0, -daysAgo))
+}
 
-	rows, err := db.Queryx(
-		`SELECT ticker_attribute.*,definition.definition FROM ticker_attribute LEFT JOIN definition ON (REPLACE(attribute_name,"_"," ") = term) WHERE ticker_id=?`, t.TickerId)
+func (t Ticker) getAttributes(deps *Dependencies, sublog zerolog.Logger) ([]TickerAttribute, error) {
+	tickerAttributes, err := deps.tickers.GetTickerAttributes(sublog, t.TickerId)
 	if err != nil {
 		return tickerAttributes, err
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		err = rows.StructScan(&tickerAttribute)
-		if err != nil {
-			sublog.Warn().Err(err).Msg("error reading result rows")
-		} else {
-			underscore_rx := regexp.MustCompile(`_`)
-			tickerAttribute.AttributeName = string(underscore_rx.ReplaceAll([]byte(tickerAttribute.AttributeName), []byte(" ")))
-			tickerAttribute.AttributeName = cases.Title(language.English).String(strings.ToLower(tickerAttribute.AttributeName))
-			tickerAttributes = append(tickerAttributes, tickerAttribute)
-		}
-	}
-	if err := rows.Err(); err != nil {
-		return tickerAttributes, err
+	underscore_rx := regexp.MustCompile(`_`)
+	for x := range tickerAttributes {
+		tickerAttributes[x].AttributeName = string(underscore_rx.ReplaceAll([]byte(tickerAttributes[x].AttributeName), []byte(" ")))
+		tickerAttributes[x].AttributeName = cases.Title(language.English).String(strings.ToLower(tickerAttributes[x].AttributeName))
 	}
 
 	return tickerAttributes, nil
 }
 
 func (t Ticker) getSplits(deps *Dependencies, sublog zerolog.Logger) ([]TickerSplit, error) {
-	db := deps.db
-
-	var tickerSplit TickerSplit
-	tickerSplits := make([]TickerSplit, 0)
-
-	rows, err := db.Queryx(
-		`SELECT * FROM ticker_split WHERE ticker_id=?`, t.TickerId)
-	if err != nil {
-		return tickerSplits, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		err = rows.StructScan(&tickerSplit)
-		if err != nil {
-			log.Warn().Err(err).Msg("error reading result rows")
-		} else {
-			tickerSplits = append(tickerSplits, tickerSplit)
-		}
-
-	}
-	if err := rows.Err(); err != nil {
-		return tickerSplits, err
-	}
+	return deps.tickers.GetTickerSplits(sublog, t.TickerId)
+}
 
-	return tickerSplits, nil
+func (t Ticker) getDividends(deps *Dependencies, sublog zerolog.Logger) ([]TickerDividend, error) {
+	return deps.tickers.GetTickerDividends(sublog, t.TickerId)
 }
 
 func (ta *TickerAttribute) getByUniqueKey(deps *Dependencies) error {
-	db := deps.db
-
-	err := db.QueryRowx(`SELECT * FROM ticker_attribute WHERE ticker_id=? AND attribute_name=?`, ta.TickerId, ta.AttributeName).StructScan(ta)
+	attribute, err := deps.tickers.GetTickerAttribute(ta.TickerId, ta.AttributeName)
+	if err == nil {
+		*ta = attribute
+	}
 	return err
 }
 
@@ -475,15 +401,10 @@ func (td *TickerDaily) IsFinalPrice() bool {
 }
 
 func (td *TickerDaily) checkByDate(deps *Dependencies) uint64 {
-	db := deps.db
-
-	var tickerDailyId uint64
-	db.QueryRowx(`SELECT ticker_daily_id FROM ticker_daily WHERE ticker_id=? AND price_datetime=?`, td.TickerId, td.PriceDatetime).Scan(&tickerDailyId)
-	return tickerDailyId
+	return deps.tickers.GetTickerDailyId(td.TickerId, td.PriceDatetime)
 }
 
 func (td *TickerDaily) create(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
 	_, calling_file, calling_line, _ := runtime.Caller(1)
 	tasklog := sublog.With().Str("called_by", fmt.Sprintf("%s %d", calling_file, calling_line)).Logger()
 
@@ -492,8 +413,7 @@ func (td *TickerDaily) create(deps *Dependencies, sublog zerolog.Logger) error {
 		return nil
 	}
 
-	var insert = "INSERT INTO ticker_daily SET ticker_id=?, price_datetime=?, open_price=?, high_price=?, low_price=?, close_price=?, volume=?"
-	_, err := db.Exec(insert, td.TickerId, td.PriceDatetime, td.OpenPrice, td.HighPrice, td.LowPrice, td.ClosePrice, td.Volume)
+	err := deps.tickers.CreateTickerDaily(*td)
 	if err != nil {
 		tasklog.Fatal().Err(err).Msg("failed on INSERT")
 	}
@@ -501,8 +421,6 @@ func (td *TickerDaily) create(deps *Dependencies, sublog zerolog.Logger) error {
 }
 
 func (td *TickerDaily) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
 	if td.Volume == 0 {
 		// Refusing to add ticker daily with 0 volume
 		return nil
@@ -513,8 +431,7 @@ func (td *TickerDaily) createOrUpdate(deps *Dependencies, sublog zerolog.Logger)
 		return td.create(deps, sublog)
 	}
 
-	var update = "UPDATE ticker_daily SET price_datetime=?, open_price=?, high_price=?, low_price=?, close_price=?, volume=? WHERE ticker_id=? AND price_datetime=?"
-	_, err := db.Exec(update, td.PriceDatetime, td.OpenPrice, td.HighPrice, td.LowPrice, td.ClosePrice, td.Volume, td.TickerId, td.PriceDatetime)
+	err := deps.tickers.UpdateTickerDaily(*td)
 	if err != nil {
 		sublog.Warn().Err(err).Msg("failed on UPDATE")
 	}
@@ -522,15 +439,14 @@ func (td *TickerDaily) createOrUpdate(deps *Dependencies, sublog zerolog.Logger)
 }
 
 func (td *TickerDescription) getByUniqueKey(deps *Dependencies) error {
-	db := deps.db
-
-	err := db.QueryRowx(`SELECT * FROM ticker_description WHERE ticker_id=?`, td.TickerId).StructScan(td)
+	description, err := deps.tickers.GetTickerDescription(td.TickerId)
+	if err == nil {
+		*td = description
+	}
 	return err
 }
 
 func (td *TickerDescription) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
 	if td.BusinessSummary == "" {
 		return nil
 	}
@@ -538,16 +454,15 @@ func (td *TickerDescription) createOrUpdate(deps *Dependencies, sublog zerolog.L
 	newBusinessSummary := td.BusinessSummary
 	err := td.getByUniqueKey(deps)
 	if err == nil {
-		update := "UPDATE ticker_description SET business_summary=? WHERE description_id=?"
-		_, err = db.Exec(update, newBusinessSummary, td.TickerDescriptionId)
+		td.BusinessSummary = newBusinessSummary
+		err = deps.tickers.UpdateTickerDescription(*td)
 		if err != nil {
 			sublog.Fatal().Err(err).Msg("failed on update")
 		}
 		return err
 	}
 
-	var insert = "INSERT INTO ticker_description SET ticker_id=?, business_summary=?"
-	_, err = db.Exec(insert, td.TickerId, td.BusinessSummary)
+	err = deps.tickers.CreateTickerDescription(*td)
 	if err != nil {
 		sublog.Fatal().Err(err).Msg("failed on insert")
 	}
@@ -577,15 +492,14 @@ func (i TickerIntradays) Count() int {
 }
 
 func (tud *TickerUpDown) getByUniqueKey(deps *Dependencies) error {
-	db := deps.db
-
-	err := db.QueryRowx(`SELECT * FROM ticker_updown WHERE ticker_id=? AND updown_date=? AND updown_firm=?`, tud.TickerId, tud.UpDownDate, tud.UpDownFirm).StructScan(tud)
+	upDown, err := deps.tickers.GetTickerUpDown(tud.TickerId, tud.UpDownDate, tud.UpDownFirm)
+	if err == nil {
+		*tud = upDown
+	}
 	return err
 }
 
 func (tud *TickerUpDown) createIfNew(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
 	if tud.UpDownToGrade == "" {
 		return nil
 	}
@@ -596,8 +510,7 @@ func (tud *TickerUpDown) createIfNew(deps *Dependencies, sublog zerolog.Logger)
 		return nil
 	}
 
-	var insert = "INSERT INTO ticker_updown SET ticker_id=?, updown_action=?, updown_fromgrade=?, updown_tograde=?, updown_date=?, updown_firm=?"
-	_, err = db.Exec(insert, tud.TickerId, tud.UpDownAction, tud.UpDownFromGrade, tud.UpDownToGrade, tud.UpDownDate, tud.UpDownFirm)
+	err = deps.tickers.CreateTickerUpDown(*tud)
 	if err != nil {
 		sublog.Fatal().Err(err).Msg("failed on INSERT")
 	}
@@ -605,15 +518,14 @@ func (tud *TickerUpDown) createIfNew(deps *Dependencies, sublog zerolog.Logger)
 }
 
 func (ts *TickerSplit) getByDate(deps *Dependencies) error {
-	db := deps.db
-
-	err := db.QueryRowx(`SELECT * FROM ticker_split WHERE ticker_id=? AND split_date=?`, ts.TickerId, ts.SplitDate).StructScan(ts)
+	split, err := deps.tickers.GetTickerSplit(ts.TickerId, ts.SplitDate)
+	if err == nil {
+		*ts = split
+	}
 	return err
 }
 
 func (ts *TickerSplit) createIfNew(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
 	if ts.SplitRatio == "" {
 		// Refusing to add ticker split with blank ratio
 		return nil
@@ -624,14 +536,49 @@ func (ts *TickerSplit) createIfNew(deps *Dependencies, sublog zerolog.Logger) er
 		return nil
 	}
 
-	var insert = "INSERT INTO ticker_split SET ticker_id=?, split_date=?, split_ratio=?"
-	_, err = db.Exec(insert, ts.TickerId, ts.SplitDate, ts.SplitRatio)
+	err = deps.tickers.CreateTickerSplit(*ts)
 	if err != nil {
 		sublog.Fatal().Err(err).Msg("failed on INSERT")
 	}
 	return err
 }
 
+func (td *TickerDividend) getByDate(deps *Dependencies) error {
+	dividend, err := deps.tickers.GetTickerDividend(td.TickerId, td.DividendDate)
+	if err == nil {
+		*td = dividend
+	}
+	return err
+}
+
+// an amount that changed is updated, which also corrects one stored before
+// amounts were restated to what was paid
+func (td *TickerDividend) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error {
+	if td.Amount <= 0 {
+		return nil
+	}
+
+	existing := TickerDividend{TickerId: td.TickerId, DividendDate: td.DividendDate}
+	err := existing.getByDate(deps)
+	if err != nil {
+		err = deps.tickers.CreateTickerDividend(*td)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed on INSERT")
+		}
+		return err
+	}
+	if math.Abs(existing.Amount-td.Amount) < dividendEpsilon {
+		return nil
+	}
+
+	td.TickerDividendId = existing.TickerDividendId
+	err = deps.tickers.UpdateTickerDividend(*td)
+	if err != nil {
+		sublog.Warn().Err(err).Msg("failed on UPDATE")
+	}
+	return err
+}
+
 func (t *Ticker) GetFinancials(deps *Dependencies, sublog zerolog.Logger, period, chartType string, isPercentage int) ([]string, []map[string]float64, error) {
 	db := deps.db
 
@@ -683,45 +630,7 @@ func (t *Ticker) GetFinancials(deps *Dependencies, sublog zerolog.Logger, period
 	return periodStrs, barValues, nil
 }
 
-func (t Ticker) queueSaveFavIcon(deps *Dependencies, sublog zerolog.Logger) error {
-	awssess := deps.awssess
-	awssvc := sqs.New(awssess)
-	queueName := "stockwatch-tickers"
-
-	urlResult, err := awssvc.GetQueueUrl(&sqs.GetQueueUrlInput{
-		QueueName: &queueName,
-	})
-	if err != nil {
-		return err
-	}
-
-	type TaskTickerNewsBody struct {
-		TickerId     uint64 `json:"ticker_id"`
-		EId          string
-		TickerSymbol string `json:"ticker_symbol"`
-		ExchangeId   uint64 `json:"exchange_id"`
-	}
-
-	// get next message from queue, if any
-	queueURL := urlResult.QueueUrl
-	messageBytes, _ := json.Marshal(TaskTickerNewsBody{TickerSymbol: t.TickerSymbol})
-	messageBody := string(messageBytes)
-	messageAttributes := map[string]*sqs.MessageAttributeValue{
-		"action": {
-			DataType:    aws.String("String"),
-			StringValue: aws.String("favicon"),
-		}}
-	_, err = awssvc.SendMessage(&sqs.SendMessageInput{
-		MessageBody:       aws.String(messageBody),
-		MessageAttributes: messageAttributes,
-		QueueUrl:          queueURL,
-	})
-	return err
-}
-
 func (t *Ticker) getFavIconCDATA(deps *Dependencies, sublog zerolog.Logger) string {
-	awssess := deps.awssess
-
 	if t.FavIconS3Key == "none" {
 		return ""
 	}
@@ -734,40 +643,20 @@ func (t *Ticker) getFavIconCDATA(deps *Dependencies, sublog zerolog.Logger) stri
 	redisConn := redisPool.Get()
 	defer redisConn.Close()
 
-	// pull URL from redis (1 day expire), or go get from AWS
+	// pull URL from redis (1 day expire), or go get from the blob store
 	redisKey := "aws/s3/" + t.FavIconS3Key
 	data, err := redis.String(redisConn.Do("GET", redisKey))
 	if err == nil && !skipRedisChecks {
 		return data
 	}
 
-	s3svc := s3.New(awssess)
-
-	inputGetObj := &s3.GetObjectInput{
-		Bucket: aws.String(awsPrivateBucketName),
-		Key:    aws.String(t.FavIconS3Key),
-	}
-	resp, err := s3svc.GetObject(inputGetObj)
+	contents, err := deps.blobs.Get(t.FavIconS3Key)
 	if err != nil {
-		sublog.Info().Msg(fmt.Sprintf("%#v", err))
-		sublog.Error().Err(err).Str("symbol", t.TickerSymbol).Str("s3key", t.FavIconS3Key).Msg("failed to get s3 object from aws")
+		sublog.Error().Err(err).Str("symbol", t.TickerSymbol).Str("s3key", t.FavIconS3Key).Msg("failed to get favicon from blob store")
 		return ""
 	}
-	defer resp.Body.Close()
-
-	size := resp.ContentLength
-	buffer := make([]byte, int(*size))
-	var bbuffer bytes.Buffer
-	for {
-		num, rerr := resp.Body.Read(buffer)
-		if num > 0 {
-			bbuffer.Write(buffer[:num])
-		} else if rerr == io.EOF || rerr != nil {
-			break
-		}
-	}
 
-	data = base64.StdEncoding.EncodeToString(bbuffer.Bytes())
+	data = base64.StdEncoding.EncodeToString(contents)
 
 	_, err = redisConn.Do("SET", redisKey, data, "EX", 60*60*24)
 	if err != nil {
@@ -777,13 +666,57 @@ func (t *Ticker) getFavIconCDATA(deps *Dependencies, sublog zerolog.Logger) stri
 	return data
 }
 
+// grab /favicon.ico from the company website into the blob store. A ticker
+// with no website, or a website with no favicon, gets "none" so we stop asking
+func (t *Ticker) saveFavIcon(deps *Dependencies, sublog zerolog.Logger) error {
+	website, err := url.Parse(t.Website)
+	if t.Website == "" || err != nil || website.Host == "" {
+		t.FavIconS3Key = "none"
+		return deps.tickers.UpdateTickerFavIcon(*t)
+	}
+	if website.Scheme == "" {
+		website.Scheme = "https"
+	}
+	faviconURL := website.Scheme + "://" + website.Host + "/favicon.ico"
+
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(faviconURL)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone {
+		sublog.Info().Str("symbol", t.TickerSymbol).Str("url", faviconURL).Msg("no favicon on company website")
+		t.FavIconS3Key = "none"
+		return deps.tickers.UpdateTickerFavIcon(*t)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fetching %s: %s", faviconURL, resp.Status)
+	}
+
+	contents, err := io.ReadAll(io.LimitReader(resp.Body, maxFavIconBytes+1))
+	if err != nil {
+		return err
+	}
+	if len(contents) == 0 || len(contents) > maxFavIconBytes {
+		sublog.Info().Str("symbol", t.TickerSymbol).Str("url", faviconURL).Int("bytes", len(contents)).Msg("favicon unusable")
+		t.FavIconS3Key = "none"
+		return deps.tickers.UpdateTickerFavIcon(*t)
+	}
+
+	s3key := "favicons/" + t.TickerSymbol + ".ico"
+	if err := deps.blobs.Put(s3key, contents); err != nil {
+		return err
+	}
+	t.FavIconS3Key = s3key
+	return deps.tickers.UpdateTickerFavIcon(*t)
+}
+
 // misc -----------------------------------------------------------------------
 
 func getTickerBySymbol(deps *Dependencies, sublog zerolog.Logger, symbol string) (Ticker, error) {
-	db := deps.db
-
-	ticker := Ticker{}
-	err := db.QueryRowx("SELECT * FROM ticker WHERE ticker_symbol=?", symbol).StructScan(&ticker)
+	ticker, err := deps.tickers.GetTickerBySymbol(symbol)
 	if err == nil {
 		ticker.EId = encryptId(deps, *deps.logger, "ticker", ticker.TickerId)
 	}
@@ -791,10 +724,7 @@ func getTickerBySymbol(deps *Dependencies, sublog zerolog.Logger, symbol string)
 }
 
 func getTickerDescriptionById(deps *Dependencies, sublog zerolog.Logger, ticker_id uint64) (TickerDescription, error) {
-	db := deps.db
-
-	var tickerDescription TickerDescription
-	err := db.QueryRowx(`SELECT * FROM ticker_description WHERE ticker_id=?`, ticker_id).StructScan(&tickerDescription)
+	tickerDescription, err := deps.tickers.GetTickerDescription(ticker_id)
 	if err == nil {
 		tickerDescription.EId = encryptId(deps, *deps.logger, "ticker_description", tickerDescription.TickerDescriptionId)
 	}
@@ -819,22 +749,32 @@ func getTickerQuote(deps *Dependencies, sublog zerolog.Logger, watcher Watcher,
 		return TickerQuote{}, err
 	}
 	tickerQuote.Exchange = exchange
+	tickerQuote.MarketOpen = isMarketOpen(exchange)
+	tickerQuote.Session = marketSession(exchange)
+	tickerQuote.NextOpen = nextOpen(exchange)
 
 	tickerDescription, err := getTickerDescriptionById(deps, sublog, ticker.TickerId)
 	if err == nil {
 		tickerQuote.Description = tickerDescription
 	}
 
-	// if the market is open, lets get a live quote
-	if isMarketOpen() {
-		quote, err := fetchTickerQuoteFromYH(deps, sublog, ticker)
+	// if the market is open, lets get a live quote; before and after the
+	// regular session there is still the extended-hours trading to show
+	if tickerQuote.Session != sessionClosed {
+		quote, err := fetchTickerQuote(deps, sublog, ticker)
 		if err == nil {
 			tickerQuote.LiveQuote = quote
-			tickerQuote.Ticker.UpdateTickerWithLiveQuote(deps, sublog, quote)
+			tickerQuote.Extended = quote.extendedHours(tickerQuote.Session)
+			if tickerQuote.MarketOpen {
+				tickerQuote.Ticker.UpdateTickerWithLiveQuote(deps, sublog, quote)
+				tickerQuote.Ticker.saveIntraday(deps, sublog, quote)
+			}
 		}
-	} else {
+	}
+
+	if !tickerQuote.MarketOpen {
 		if tickerQuote.Ticker.needEODs(deps, sublog) {
-			err := fetchTickerEODsFromYH(deps, sublog, ticker)
+			err := fetchTickerEODs(deps, sublog, ticker)
 			if err != nil {
 				sublog.Error().Err(err).Msg("failed to fetch tickerEODs")
 				return TickerQuote{}, err
@@ -864,6 +804,17 @@ func getTickerQuote(deps *Dependencies, sublog zerolog.Logger, watcher Watcher,
 		return TickerQuote{}, err
 	}
 
+	tickerQuote.Holding, err = getWatcherHolding(deps, sublog, watcher, ticker)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to getWatcherHolding")
+		return TickerQuote{}, err
+	}
+
+	tickerQuote.HoldingPL, err = getHoldingPL(deps, sublog, watcher, tickerQuote.Holding, ticker.MarketPrice)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to getHoldingPL")
+	}
+
 	articles, err := getArticlesByTicker(deps, sublog, ticker, 20, time.Duration(180*24*time.Hour))
 	if err != nil {
 		sublog.Error().Err(err).Msg("failed to getArticlesByTicker")
@@ -876,7 +827,7 @@ func getTickerQuote(deps *Dependencies, sublog zerolog.Logger, watcher Watcher,
 	err = lastdone.getByActivity(deps)
 	if err == nil && lastdone.LastStatus == "success" {
 		tickerQuote.SymbolNews.LastChecked = lastdone.LastDoneDatetime.Time
-		if lastdone.LastDoneDatetime.Time.Add(time.Minute * minTickerNewsDelay).Before(time.Now()) {
+		if lastdone.LastDoneDatetime.Time.Add(time.Minute * time.Duration(deps.config.TickerNewsDelay)).Before(time.Now()) {
 			err = ticker.queueUpdateNews(deps)
 			if err != nil {
 				sublog.Error().Err(err).Msg("failed to queue UpdateNews")
@@ -895,7 +846,7 @@ func getTickerQuote(deps *Dependencies, sublog zerolog.Logger, watcher Watcher,
 	lastdone = LastDone{Activity: "ticker_financials", UniqueKey: ticker.TickerSymbol}
 	err = lastdone.getByActivity(deps)
 	if err == nil && lastdone.LastStatus == "success" {
-		if lastdone.LastDoneDatetime.Time.Add(time.Minute * minTickerFinancialsDelay).Before(time.Now()) {
+		if lastdone.LastDoneDatetime.Time.Add(time.Minute * time.Duration(deps.config.TickerFinancialsDelay)).Before(time.Now()) {
 			err = ticker.queueUpdateFinancials(deps)
 			if err != nil {
 				sublog.Error().Err(err).Msg("failed to queue UpdateFinancials")
@@ -914,12 +865,14 @@ func getTickerQuote(deps *Dependencies, sublog zerolog.Logger, watcher Watcher,
 	return tickerQuote, err
 }
 
+// load ticker from DB or go get from YH if new; also update from YH if the
+// ticker is over 24 hours old, or stale for its exchange's trading hours.
+// Once the exchange has closed, one fetch after the close is enough until it
+// opens again
 func getFreshTicker(deps *Dependencies, sublog zerolog.Logger, symbol string) (Ticker, error) {
-	// load ticker from DB or go get from YH if new
-	// also, update from YH if ticker is over 24 hours old
 	ticker, err := getTickerBySymbol(deps, sublog, symbol)
 	if err != nil && errors.Is(err, sql.ErrNoRows) {
-		ticker, err = fetchTickerInfoFromYH(deps, sublog, symbol)
+		ticker, err = fetchTickerInfo(deps, sublog, symbol)
 		if err != nil {
 			return Ticker{}, err
 		}
@@ -930,10 +883,8 @@ func getFreshTicker(deps *Dependencies, sublog zerolog.Logger, symbol string) (T
 		}
 	} else if err != nil {
 		return Ticker{}, err
-	} else if ticker.FetchDatetime.Before(time.Now().Add(-24*time.Hour)) ||
-		(!isMarketOpen() && time.Since(ticker.FetchDatetime).Minutes() > minTickerReloadDelayOpen) ||
-		(isMarketOpen() && time.Since(ticker.FetchDatetime).Minutes() > minTickerReloadDelayClosed) {
-		ticker, err = fetchTickerInfoFromYH(deps, sublog, symbol)
+	} else if ticker.isStale(deps, sublog) {
+		ticker, err = fetchTickerInfo(deps, sublog, symbol)
 		if err != nil {
 			return Ticker{}, err
 		}
@@ -1005,19 +956,58 @@ func getTickerDetails(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 }
 
 func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, recents []WatcherRecent) ([]TickerQuote, error) {
-	start := time.Now()
-	tickerQuotes := []TickerQuote{}
-
 	symbols := []string{}
 	for _, recent := range recents {
 		symbols = append(symbols, recent.TickerSymbol)
 	}
 
+	tickerQuotes, err := getListQuotes(deps, sublog, watcher, symbols)
+	if err != nil {
+		return []TickerQuote{}, err
+	}
+	for x := range tickerQuotes {
+		tickerQuotes[x].Locked, err = isWatcherRecent(deps, sublog, watcher, tickerQuotes[x].Ticker)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to check isWatcherRecent")
+			return []TickerQuote{}, err
+		}
+	}
+	return tickerQuotes, nil
+}
+
+// whether any ticker on the list is trading, and the busiest session any of
+// them is in, which sets how quickly the page refreshes
+func listSession(tickerQuotes []TickerQuote) (bool, string) {
+	quotesMarketOpen := false
+	quotesSession := sessionClosed
+	for _, tickerQuote := range tickerQuotes {
+		quotesMarketOpen = quotesMarketOpen || tickerQuote.MarketOpen
+		if tickerQuote.Session == sessionRegular || (tickerQuote.Session != sessionClosed && quotesSession == sessionClosed) {
+			quotesSession = tickerQuote.Session
+		}
+	}
+	return quotesMarketOpen, quotesSession
+}
+
+// quotes, news and holdings for a list of symbols (the recents or any of the
+// watcher's watchlists), kept in the order the list has them
+func getListQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, symbols []string) ([]TickerQuote, error) {
+	start := time.Now()
+	tickerQuotes := []TickerQuote{}
+
 	tickers, err := getFreshTickers(deps, sublog, symbols)
 	if err != nil {
 		sublog.Error().Err(err).Msg("failed to getFreshTicker")
 		return []TickerQuote{}, err
 	}
+	position := make(map[string]int, len(symbols))
+	for x, symbol := range symbols {
+		position[symbol] = x
+	}
+	sort.SliceStable(tickers, func(i, j int) bool {
+		return position[tickers[i].TickerSymbol] < position[tickers[j].TickerSymbol]
+	})
+
 	for _, ticker := range tickers {
 		symbol := ticker.TickerSymbol
 		tickerQuote := TickerQuote{}
@@ -1029,6 +1019,9 @@ func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 			return []TickerQuote{}, err
 		}
 		tickerQuote.Exchange = exchange
+		tickerQuote.MarketOpen = isMarketOpen(exchange)
+		tickerQuote.Session = marketSession(exchange)
+		tickerQuote.NextOpen = nextOpen(exchange)
 
 		tickerDescription, err := getTickerDescriptionById(deps, sublog, ticker.TickerId)
 		if err == nil {
@@ -1040,9 +1033,9 @@ func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 			tickerQuote.ChangePct = float32((ticker.MarketPrice - ticker.MarketPrevClose) / ticker.MarketPrevClose * 100)
 		}
 
-		tickerQuote.Locked, err = isWatcherRecent(deps, sublog, watcher, ticker)
+		tickerQuote.Holding, err = getWatcherHolding(deps, sublog, watcher, ticker)
 		if err != nil {
-			sublog.Error().Err(err).Msg("failed to check isWatcherRecent")
+			sublog.Error().Err(err).Msg("failed to getWatcherHolding")
 			return []TickerQuote{}, err
 		}
 
@@ -1053,7 +1046,7 @@ func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 		}
 		tickerQuote.SymbolNews.Articles = articles
 
-		if !isMarketOpen() {
+		if !tickerQuote.MarketOpen {
 			lastEOD, err := tickerQuote.Ticker.getLastTickerEOD(deps, sublog)
 			if err == nil {
 				tickerQuote.LastEOD = lastEOD
@@ -1065,7 +1058,7 @@ func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 		err = lastdone.getByActivity(deps)
 		if err == nil && lastdone.LastStatus == "success" {
 			tickerQuote.SymbolNews.LastChecked = lastdone.LastDoneDatetime.Time
-			if lastdone.LastDoneDatetime.Time.Add(time.Minute * minTickerNewsDelay).Before(time.Now()) {
+			if lastdone.LastDoneDatetime.Time.Add(time.Minute * time.Duration(deps.config.TickerNewsDelay)).Before(time.Now()) {
 				err = ticker.queueUpdateNews(deps)
 				if err != nil {
 					sublog.Error().Err(err).Str("ticker", symbol).Uint64("exchange_id", ticker.ExchangeId).Msg("failed to queue UpdateNews")
@@ -1084,7 +1077,7 @@ func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 		lastdone = LastDone{Activity: "ticker_financials", UniqueKey: ticker.TickerSymbol}
 		err = lastdone.getByActivity(deps)
 		if err == nil && lastdone.LastStatus == "success" {
-			if lastdone.LastDoneDatetime.Time.Add(time.Minute * minTickerFinancialsDelay).Before(time.Now()) {
+			if lastdone.LastDoneDatetime.Time.Add(time.Minute * time.Duration(deps.config.TickerFinancialsDelay)).Before(time.Now()) {
 				err = ticker.queueUpdateFinancials(deps)
 				if err != nil {
 					sublog.Error().Err(err).Msg("failed to queue UpdateFinancials")
@@ -1101,6 +1094,6 @@ func getRecentsQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher
 		tickerQuotes = append(tickerQuotes, tickerQuote)
 	}
 
-	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: getRecentsQuotes")
+	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: getListQuotes")
 	return tickerQuotes, err
 }
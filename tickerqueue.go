@@ -1,10 +1,7 @@
 package main
 
 import (
-	"encoding/json"
-
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/rs/zerolog"
 )
 
 type TaskTickerBody struct {
@@ -15,88 +12,27 @@ type TaskTickerBody struct {
 }
 
 func (t Ticker) queueUpdateInfo(deps *Dependencies) error {
-	awssess := deps.awssess
-	awssvc := sqs.New(awssess)
-	queueName := "stockwatch-tickers"
-
-	urlResult, err := awssvc.GetQueueUrl(&sqs.GetQueueUrlInput{
-		QueueName: &queueName,
-	})
-	if err != nil {
-		return err
-	}
-
-	// get next message from queue, if any
-	queueURL := urlResult.QueueUrl
-	messageBytes, _ := json.Marshal(TaskTickerBody{TickerSymbol: t.TickerSymbol})
-	messageBody := string(messageBytes)
-	messageAttributes := map[string]*sqs.MessageAttributeValue{
-		"action": {
-			DataType:    aws.String("String"),
-			StringValue: aws.String("info"),
-		}}
-	_, err = awssvc.SendMessage(&sqs.SendMessageInput{
-		MessageBody:       aws.String(messageBody),
-		MessageAttributes: messageAttributes,
-		QueueUrl:          queueURL,
-	})
-	return err
+	return t.queueTask(deps, "info")
 }
 
 func (t Ticker) queueUpdateNews(deps *Dependencies) error {
-	awssess := deps.awssess
-	awssvc := sqs.New(awssess)
-	queueName := "stockwatch-tickers"
-
-	urlResult, err := awssvc.GetQueueUrl(&sqs.GetQueueUrlInput{
-		QueueName: &queueName,
-	})
-	if err != nil {
-		return err
-	}
-
-	// get next message from queue, if any
-	queueURL := urlResult.QueueUrl
-	messageBytes, _ := json.Marshal(TaskTickerBody{TickerSymbol: t.TickerSymbol})
-	messageBody := string(messageBytes)
-	messageAttributes := map[string]*sqs.MessageAttributeValue{
-		"action": {
-			DataType:    aws.String("String"),
-			StringValue: aws.String("news"),
-		}}
-	_, err = awssvc.SendMessage(&sqs.SendMessageInput{
-		MessageBody:       aws.String(messageBody),
-		MessageAttributes: messageAttributes,
-		QueueUrl:          queueURL,
-	})
-	return err
+	return t.queueTask(deps, "news")
 }
 
 func (t Ticker) queueUpdateFinancials(deps *Dependencies) error {
-	awssess := deps.awssess
-	awssvc := sqs.New(awssess)
-	queueName := "stockwatch-tickers"
+	return t.queueTask(deps, "financials")
+}
 
-	urlResult, err := awssvc.GetQueueUrl(&sqs.GetQueueUrlInput{
-		QueueName: &queueName,
-	})
-	if err != nil {
-		return err
-	}
+func (t Ticker) queueSaveFavIcon(deps *Dependencies, sublog zerolog.Logger) error {
+	return t.queueTask(deps, "favicon")
+}
 
-	// get next message from queue, if any
-	queueURL := urlResult.QueueUrl
-	messageBytes, _ := json.Marshal(TaskTickerBody{TickerSymbol: t.TickerSymbol})
-	messageBody := string(messageBytes)
-	messageAttributes := map[string]*sqs.MessageAttributeValue{
-		"action": {
-			DataType:    aws.String("String"),
-			StringValue: aws.String("financials"),
-		}}
-	_, err = awssvc.SendMessage(&sqs.SendMessageInput{
-		MessageBody:       aws.String(messageBody),
-		MessageAttributes: messageAttributes,
-		QueueUrl:          queueURL,
+// one task per action+symbol per dedupe window, however many pages ask
+func (t Ticker) queueTask(deps *Dependencies, action string) error {
+	_, err := deps.tasks.Enqueue(Task{
+		Action: action,
+		Key:    action + "/" + t.TickerSymbol,
+		Body:   TaskTickerBody{TickerSymbol: t.TickerSymbol},
 	})
 	return err
 }
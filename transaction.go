@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog"
 )
 
 type Transaction struct {
@@ -15,33 +19,196 @@ type Transaction struct {
 	WatcherId           uint64    `db:"watcher_id"`
 	TransactionType     string    `db:"transaction_type"`
 	TransactionDateTime string    `db:"transaction_datetime"`
-	Shares              uint64    `db:"shares"`
+	Shares              float64   `db:"shares"`
 	SharePrice          float64   `db:"share_price"`
+	LotId               uint64    `db:"lot_id"` // for a specific-lot sale, the transaction_id of the lot sold
 	CreateDatetime      time.Time `db:"create_datetime"`
 	UpdateDatetime      time.Time `db:"update_datetime"`
 }
 
 // object methods -------------------------------------------------------------
 
+func (t *Transaction) create(deps *Dependencies, sublog zerolog.Logger) error {
+	return t.insert(deps.db, sublog)
+}
+
+// insert on the connection or inside a transaction
+func (t *Transaction) insert(db sqlx.Execer, sublog zerolog.Logger) error {
+	insert := "INSERT INTO `transaction` (holding_id, watcher_id, transaction_type, transaction_datetime, shares, share_price, lot_id) VALUES (?, ?, ?, ?, ?, ?, ?)"
+	res, err := db.Exec(insert, t.HoldingId, t.WatcherId, t.TransactionType, t.TransactionDateTime, t.Shares, t.SharePrice, t.LotId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on INSERT")
+		return err
+	}
+	transactionId, err := res.LastInsertId()
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed on LAST_INSERTID")
+		return err
+	}
+	t.TransactionId = uint64(transactionId)
+	return nil
+}
+
 // misc -----------------------------------------------------------------------
 
+func getTransactionsByHolding(deps *Dependencies, sublog zerolog.Logger, holdingId uint64) ([]Transaction, error) {
+	return queryTransactionsByHolding(deps.db, sublog, holdingId)
+}
+
+// on the connection or inside a transaction
+func queryTransactionsByHolding(db sqlx.Queryer, sublog zerolog.Logger, holdingId uint64) ([]Transaction, error) {
+	var transaction Transaction
+	transactions := make([]Transaction, 0)
+
+	rows, err := db.Queryx("SELECT * FROM `transaction` WHERE holding_id=? ORDER BY transaction_datetime, transaction_id", holdingId)
+	if err != nil {
+		return transactions, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		err = rows.StructScan(&transaction)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed reading result rows")
+		} else {
+			transactions = append(transactions, transaction)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return transactions, err
+	}
+
+	return transactions, nil
+}
+
 func transactionHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		watcher := checkAuthState(w, r, deps, *deps.logger)
+		rc := getRequestContext(r)
+		watcher := checkAuthState(w, r, deps, *rc.logger)
 
 		params := mux.Vars(r)
 		action := params["action"]
 		symbol := params["symbol"]
 		acronym := params["acronym"]
 
-		sublog := deps.logger.With().Str("watcher", watcher.EId).Str("transaction", action).Str("symbol", symbol).Logger()
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("transaction", action).Str("symbol", symbol).Logger()
+
+		if watcher.WatcherId == 0 {
+			rc.messages = append(rc.messages, Message{"Sorry, you need to be signed in to record a transaction", "error"})
+			renderTemplate(w, r, deps, sublog, "update")
+			return
+		}
 
 		Shares, _ := strconv.ParseFloat(r.FormValue("Shares"), 64)
 		SharePrice, _ := strconv.ParseFloat(r.FormValue("SharePrice"), 64)
 		PurchaseDate := r.FormValue("PurchaseDate")
+		var LotId uint64
+		if action == "sold" {
+			PurchaseDate = r.FormValue("SoldDate")
+			LotId, _ = strconv.ParseUint(r.FormValue("LotId"), 10, 64)
+		}
+		if PurchaseDate == "" {
+			PurchaseDate = time.Now().Format(sqlDateParseType)
+		}
+		if _, err := time.Parse(sqlDateParseType, PurchaseDate); err != nil || Shares <= 0 || SharePrice < 0 {
+			sublog.Warn().Float64("shares", Shares).Float64("share_price", SharePrice).Str("purchase_date", PurchaseDate).Msg("invalid transaction")
+			rc.messages = append(rc.messages, Message{"Sorry, the quantity, price or date given was not valid", "error"})
+			renderTemplate(w, r, deps, sublog, "update")
+			return
+		}
+
+		ticker, err := getTickerBySymbol(deps, sublog, symbol)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to find ticker")
+			rc.messages = append(rc.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
+			renderTemplate(w, r, deps, sublog, "update")
+			return
+		}
+
+		holding, err := getWatcherHolding(deps, sublog, watcher, ticker)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to get holding")
+			rc.messages = append(rc.messages, Message{"Sorry, there was a problem recording that transaction", "error"})
+			renderTemplate(w, r, deps, sublog, "update")
+			return
+		}
+
+		transaction := Transaction{
+			HoldingId:           holding.HoldingId,
+			WatcherId:           watcher.WatcherId,
+			TransactionType:     action,
+			TransactionDateTime: PurchaseDate,
+			Shares:              Shares,
+			SharePrice:          SharePrice,
+			LotId:               LotId,
+		}
+
+		// a sale has to fit the shares held on its own date, counted in the
+		// shares of that date and not today's, and a lot it names has to have
+		// been open then
+		if action == "sold" {
+			transactions, err := getTransactionsByHolding(deps, sublog, holding.HoldingId)
+			if err == nil {
+				var splits []TickerSplit
+				splits, err = ticker.getSplits(deps, sublog)
+				if err == nil {
+					err = checkSaleLot(transactions, splits, watcher.CostBasisMethod, transaction)
+				}
+				if err == nil {
+					_, err = calcHoldingPL(append(transactions, transaction), splits, watcher.CostBasisMethod, 0, time.Now())
+				}
+			}
+			if errors.Is(err, errLotNotOpen) {
+				sublog.Warn().Err(err).Msg("sale names a lot that was not open")
+				rc.messages = append(rc.messages, Message{fmt.Sprintf("Sorry, the lot chosen wasn't one of your open lots of %s on %s", symbol, PurchaseDate), "error"})
+				renderTemplate(w, r, deps, sublog, "update")
+				return
+			}
+			if err != nil {
+				sublog.Warn().Err(err).Msg("sale does not match held lots")
+				rc.messages = append(rc.messages, Message{fmt.Sprintf("Sorry, you didn't hold %g shares of %s on %s", Shares, symbol, PurchaseDate), "error"})
+				renderTemplate(w, r, deps, sublog, "update")
+				return
+			}
+		}
+
+		err = recordTransaction(deps, sublog, &holding, &transaction)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to record transaction")
+			rc.messages = append(rc.messages, Message{"Sorry, there was a problem recording that transaction", "error"})
+			renderTemplate(w, r, deps, sublog, "update")
+			return
+		}
 
 		sublog.Info().Float64("shares", Shares).Float64("share_price", SharePrice).Str("purchase_date", PurchaseDate).Str("acronym", acronym).Msg("transaction recorded")
 
-		renderTemplate(w, r, deps, sublog, "update")
+		http.Redirect(w, r, fmt.Sprintf("/view/%s", ticker.TickerSymbol), http.StatusFound)
 	})
 }
+
+// the holding (only added once the transaction is known to be good), the
+// trade and the holding's new totals go in together or not at all
+func recordTransaction(deps *Dependencies, sublog zerolog.Logger, holding *Holding, transaction *Transaction) error {
+	tx, err := deps.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if holding.HoldingId == 0 {
+		err = holding.insertOrGet(tx, sublog)
+		if err != nil {
+			return err
+		}
+		transaction.HoldingId = holding.HoldingId
+	}
+	err = transaction.insert(tx, sublog)
+	if err != nil {
+		return err
+	}
+	err = holding.recalculateIn(deps, tx, sublog)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
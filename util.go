@@ -40,22 +40,6 @@ func SinceColorCSS(sinceStr string) string {
 	}
 }
 
-func isMarketOpen() bool {
-	EasternTZ, _ := time.LoadLocation("America/New_York")
-	currentDate := time.Now().In(EasternTZ)
-	timeStr := currentDate.Format("1504")
-	weekday := currentDate.Weekday()
-
-	if weekday == time.Saturday || weekday == time.Sunday {
-		return false
-	}
-	if timeStr >= "0930" && timeStr < "1600" {
-		return true
-	}
-
-	return false
-}
-
 func PriceMoveColorCSS(amt float32) string {
 	if amt > 0 {
 		return "text-success"
@@ -8,18 +8,19 @@ import (
 
 func viewTickerDailyHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		webdata := deps.webdata
-		watcher := checkAuthState(w, r, deps, *deps.logger)
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
 
 		params := mux.Vars(r)
 		symbol := params["symbol"]
 
-		sublog := deps.logger.With().Str("watcher", watcher.EId).Str("symbol", symbol).Logger()
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("symbol", symbol).Logger()
 
 		tickerQuote, err := getTickerQuote(deps, sublog, watcher, symbol)
 		if err != nil {
 			sublog.Error().Err(err).Msg("getTickerQuote failed, redirecting to /desktop")
-			deps.messages = append(deps.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
+			rc.messages = append(rc.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
 			renderTemplate(w, r, deps, sublog, "desktop")
 			return
 		}
@@ -27,7 +28,7 @@ func viewTickerDailyHandler(deps *Dependencies) http.HandlerFunc {
 
 		tickerDetails, err := getTickerDetails(deps, sublog, watcher, symbol)
 		if err != nil {
-			deps.messages = append(deps.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
+			rc.messages = append(rc.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
 			renderTemplate(w, r, deps, sublog, "desktop")
 			return
 		}
@@ -43,25 +44,28 @@ func viewTickerDailyHandler(deps *Dependencies) http.HandlerFunc {
 			webdata["timespan"] = 180
 		}
 
+		rc.messages = append(rc.messages, deliverWatcherAlerts(deps, sublog, watcher)...)
+
 		renderTemplate(w, r, deps, sublog, "view-daily")
 	})
 }
 
 func viewTickerArticleHandler(deps *Dependencies) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		webdata := deps.webdata
-		watcher := checkAuthState(w, r, deps, *deps.logger)
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
 
 		params := mux.Vars(r)
 		symbol := params["symbol"]
 		articleEId := params["articleEId"]
 
-		sublog := deps.logger.With().Str("watcher", watcher.EId).Str("symbol", symbol).Logger()
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("symbol", symbol).Logger()
 
 		tickerQuote, err := getTickerQuote(deps, sublog, watcher, symbol)
 		if err != nil {
 			sublog.Error().Err(err).Msg("getTickerQuote failed, redirecting to /desktop")
-			deps.messages = append(deps.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
+			rc.messages = append(rc.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
 			renderTemplate(w, r, deps, sublog, "desktop")
 			return
 		}
@@ -69,7 +73,7 @@ func viewTickerArticleHandler(deps *Dependencies) http.HandlerFunc {
 
 		tickerDetails, err := getTickerDetails(deps, sublog, watcher, symbol)
 		if err != nil {
-			deps.messages = append(deps.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
+			rc.messages = append(rc.messages, Message{"Sorry, that ticker symbol could not be found", "error"})
 			renderTemplate(w, r, deps, sublog, "desktop")
 			return
 		}
@@ -86,3 +90,44 @@ func viewTickerArticleHandler(deps *Dependencies) http.HandlerFunc {
 		renderTemplate(w, r, deps, sublog, "view-daily")
 	})
 }
+
+func viewMarketIndexHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+
+		params := mux.Vars(r)
+		symbol := params["symbol"]
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("index", symbol).Logger()
+
+		mi, err := getMarketIndex(deps, sublog, symbol)
+		if err != nil {
+			sublog.Error().Err(err).Msg("getMarketIndex failed, redirecting to /desktop")
+			rc.messages = append(rc.messages, Message{"Sorry, that market index could not be found", "error"})
+			renderTemplate(w, r, deps, sublog, "desktop")
+			return
+		}
+		if mi.needEODs(deps, sublog) {
+			fetchMarketIndexEODs(deps, sublog, mi)
+		}
+
+		indexes := getBenchmarkIndexes(deps, sublog)
+		indexQuotes := getIndexQuotes(deps, sublog, indexes)
+		webdata["IndexQuotes"] = indexQuotes
+		webdata["IndexQuote"] = IndexQuote{MarketIndex: mi}
+		for _, indexQuote := range indexQuotes {
+			if indexQuote.MarketIndex.MarketIndexId == mi.MarketIndexId {
+				webdata["IndexQuote"] = indexQuote
+			}
+		}
+		webdata["AdvanceDecline"] = getAdvanceDecline(deps, sublog)
+
+		if webdata["timespan"] == nil {
+			webdata["timespan"] = 180
+		}
+
+		renderTemplate(w, r, deps, sublog, "view-index")
+	})
+}
@@ -1,28 +1,63 @@
 package main
 
-import "github.com/rs/zerolog"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+// object methods -------------------------------------------------------------
+
+// who set the target and when, e.g. "Jane Doe/Acme Research, 2022-04-01"
+func (w WebWatch) label() string {
+	who := make([]string, 0, 2)
+	if w.SourceName.Valid && w.SourceName.String != "" {
+		who = append(who, w.SourceName.String)
+	}
+	if w.SourceCompany.Valid && w.SourceCompany.String != "" {
+		who = append(who, w.SourceCompany.String)
+	}
+	if len(who) == 0 {
+		who = append(who, "Target")
+	}
+	return fmt.Sprintf("%s, %s", strings.Join(who, "/"), datePart(w.SourceDate))
+}
+
+// misc -----------------------------------------------------------------------
+
+// the date part of a date or datetime column, however the driver returned it
+func datePart(datetime string) string {
+	if len(datetime) < len(sqlDateParseType) {
+		return datetime
+	}
+	return datetime[:len(sqlDateParseType)]
+}
 
 func loadWebWatches(deps *Dependencies, sublog zerolog.Logger, ticker_id uint64) ([]WebWatch, error) {
 	db := deps.db
 
+	webwatches := make([]WebWatch, 0, 30)
 	rows, err := db.Queryx("SELECT target_date,target_price,source_date,source_company,source_name FROM watch LEFT JOIN source USING (source_id) WHERE ticker_id = ? ORDER BY source_date", ticker_id)
 	if err != nil {
-		sublog.Fatal().Err(err).Msg("failed on SELECT")
+		sublog.Error().Err(err).Msg("failed on SELECT")
+		return webwatches, err
 	}
 	defer rows.Close()
 
 	var webWatch WebWatch
-	webwatches := make([]WebWatch, 0, 30)
 	for rows.Next() {
 		err = rows.StructScan(&webWatch)
 		if err != nil {
-			sublog.Fatal().Err(err).Msg("Error reading result rows")
+			sublog.Error().Err(err).Msg("Error reading result rows")
+			continue
 		}
 		webwatches = append(webwatches, webWatch)
 	}
 	if err := rows.Err(); err != nil {
-		sublog.Fatal().Err(err).Msg("Error reading result rows")
+		sublog.Error().Err(err).Msg("Error reading result rows")
+		return webwatches, err
 	}
 
-	return webwatches, err
+	return webwatches, nil
 }
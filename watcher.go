@@ -19,6 +19,7 @@ type Watcher struct {
 	WatcherTimezone string    `db:"watcher_timezone"`
 	WatcherPicURL   string    `db:"watcher_pic_url"`
 	SessionId       string    `db:"session_id"`
+	CostBasisMethod string    `db:"costbasis_method"`
 	CreateDatetime  time.Time `db:"create_datetime"`
 	UpdateDatetime  time.Time `db:"update_datetime"`
 }
@@ -53,40 +54,24 @@ type WebWatcher struct {
 }
 
 func getWatcherById(deps *Dependencies, watcherId uint64) (Watcher, error) {
-	db := deps.db
-
-	w := Watcher{}
-	err := db.QueryRowx("SELECT * FROM watcher WHERE watcher_id=?", watcherId).StructScan(&w)
-	return w, err
+	return deps.watchers.GetWatcher(watcherId)
 }
 
 func updateWatcher(deps *Dependencies, sublog zerolog.Logger, w Watcher) error {
-	db := deps.db
-
-	update := "UPDATE watcher SET watcher_name=?, watcher_nickname=?, update_createtime=now() WHERE watcher_id=?"
-	_, err := db.Exec(update, w.WatcherName, w.WatcherNickname, w.WatcherId)
-	return err
+	return deps.watchers.UpdateWatcher(w)
 }
 
 func updateWatcherFromOAuth(deps *Dependencies, w Watcher, email string) error {
-	db := deps.db
-
-	update := "UPDATE watcher SET watcher_name=?, watcher_pic_url=?, session_id=? WHERE watcher_id=?"
-	_, err := db.Exec(update, w.WatcherName, w.WatcherPicURL, w.SessionId, w.WatcherId)
+	err := deps.watchers.UpdateWatcherOAuth(w)
 	if err != nil {
 		return err
 	}
 
-	update = "INSERT INTO watcher_email SET watcher_id=?, email_address=? ON DUPLICATE KEY UPDATE watcher_id=watcher_id"
-	_, err = db.Exec(update, w.WatcherId, email)
-	return err
+	return deps.watchers.AddWatcherEmail(w.WatcherId, email, false)
 }
 
 func createWatcher(deps *Dependencies, w Watcher, email string) (Watcher, error) {
-	db := deps.db
-
-	insert := "INSERT INTO watcher SET watcher_sub=?, watcher_name=?, watcher_nickname=?, watcher_status=?, watcher_pic_url=?, session_id=?"
-	_, err := db.Exec(insert, w.WatcherSub, w.WatcherName, w.WatcherNickname, w.WatcherStatus, w.WatcherPicURL, w.SessionId)
+	err := deps.watchers.CreateWatcher(w)
 	if err != nil {
 		return Watcher{}, err
 	}
@@ -96,8 +81,7 @@ func createWatcher(deps *Dependencies, w Watcher, email string) (Watcher, error)
 		return Watcher{}, err
 	}
 
-	insert = "INSERT INTO watcher_email SET watcher_id=?, email_address=?, email_is_primary=1"
-	_, err = db.Exec(insert, w.WatcherId, email)
+	err = deps.watchers.AddWatcherEmail(w.WatcherId, email, true)
 
 	return w, err
 }
@@ -133,22 +117,14 @@ func (w Watcher) IsRoot() bool {
 
 // misc -----------------------------------------------------------------------
 func isNicknameAvailable(deps *Dependencies, watcherId uint64, nickname string) bool {
-	db := deps.db
-
-	var count int
-	err := db.QueryRowx("SELECT count(*) FROM watcher WHERE watcher_id != ? and watcher_nickname=?", watcherId, nickname).Scan(&count)
-	if err != nil && errors.Is(err, sql.ErrNoRows) {
-		return true
-	}
-	return false
+	count, err := deps.watchers.CountWatchersWithNickname(watcherId, nickname)
+	return err == nil && count == 0
 }
 
 func getWatcherIdBySession(deps *Dependencies, session string) (uint64, error) {
-	db := deps.db
 	sublog := deps.logger
 
-	var watcherId uint64
-	err := db.QueryRowx("SELECT watcher_id FROM watcher WHERE session_id=?", session).Scan(&watcherId)
+	watcherId, err := deps.watchers.GetWatcherIdBySession(session)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			sublog.Info().Msg("no rows returned for getWatcherIdBySession")
@@ -163,11 +139,9 @@ func getWatcherIdBySession(deps *Dependencies, session string) (uint64, error) {
 }
 
 func getWatcherIdByEmail(deps *Dependencies, email string) (uint64, error) {
-	db := deps.db
 	sublog := deps.logger
 
-	var watcherId uint64
-	err := db.QueryRowx("SELECT watcher_id FROM watcher_email WHERE email_address=?", email).Scan(&watcherId)
+	watcherId, err := deps.watchers.GetWatcherIdByEmail(email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			sublog.Info().Msg("no rows returned for getWatcherIdByEmail")
@@ -182,10 +156,7 @@ func getWatcherIdByEmail(deps *Dependencies, email string) (uint64, error) {
 }
 
 func (wr *WatcherRecent) create(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
-	insert := "INSERT INTO watcher_recent SET watcher_id=?, ticker_id=?, locked=?"
-	_, err := db.Exec(insert, wr.WatcherId, wr.TickerId, wr.Locked)
+	err := deps.recents.CreateWatcherRecent(*wr)
 	if err != nil {
 		sublog.Error().Err(err).Msg("failed on INSERT")
 		return err
@@ -194,24 +165,8 @@ func (wr *WatcherRecent) create(deps *Dependencies, sublog zerolog.Logger) error
 	return nil
 }
 
-func (wr *WatcherRecent) createOrUpdate(deps *Dependencies, sublog zerolog.Logger) error {
-	db := deps.db
-
-	insert_or_update := "INSERT INTO watcher_recent SET watcher_id=?, ticker_id=?, locked=? ON DUPLICATE KEY UPDATE locked=? update_datetime=now()"
-	_, err := db.Exec(insert_or_update, wr.WatcherId, wr.TickerId, wr.Locked, wr.Locked)
-	if err != nil {
-		sublog.Error().Err(err).Msg("failed on INSERT OR UPDATE")
-		return err
-	}
-
-	return nil
-}
-
 func (wr *WatcherRecent) update(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, ticker Ticker) error {
-	db := deps.db
-
-	insert_or_update := "UPDATE watcher_recent SET locked=?, update_datetime=now() WHERE watcher_id=? and ticker_id=?"
-	_, err := db.Exec(insert_or_update, wr.Locked, wr.WatcherId, wr.TickerId)
+	err := deps.recents.TouchWatcherRecent(*wr)
 	if err != nil {
 		sublog.Error().Err(err).Msg("failed on UPDATE")
 		return err
@@ -221,10 +176,7 @@ func (wr *WatcherRecent) update(deps *Dependencies, sublog zerolog.Logger, watch
 }
 
 func lockWatcherRecent(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, ticker Ticker) bool {
-	db := deps.db
-
-	var update = "UPDATE watcher_recent SET locked=true WHERE watcher_id=? AND ticker_id=?"
-	_, err := db.Exec(update, watcher.WatcherId, ticker.TickerId)
+	err := deps.recents.LockWatcherRecent(watcher.WatcherId, ticker.TickerId, true)
 	if err != nil {
 		sublog.Warn().Err(err).Msg("failed on UPDATE")
 		return false
@@ -233,10 +185,7 @@ func lockWatcherRecent(deps *Dependencies, sublog zerolog.Logger, watcher Watche
 }
 
 func unlockWatcherRecent(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, ticker Ticker) bool {
-	db := deps.db
-
-	var update = "UPDATE watcher_recent SET locked=false WHERE watcher_id=? AND ticker_id=?"
-	_, err := db.Exec(update, watcher.WatcherId, ticker.TickerId)
+	err := deps.recents.LockWatcherRecent(watcher.WatcherId, ticker.TickerId, false)
 	if err != nil {
 		sublog.Warn().Err(err).Msg("failed on UPDATE")
 		return false
@@ -0,0 +1,331 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+// Watchlist is a named list of tickers a watcher keeps, beyond the recents;
+// it can be shared read-only with anyone who has its share link
+type Watchlist struct {
+	WatchlistId    uint64 `db:"watchlist_id"`
+	EId            string
+	WatcherId      uint64    `db:"watcher_id"`
+	WatchlistName  string    `db:"watchlist_name"`
+	ShareToken     string    `db:"share_token"` // empty when it isn't shared
+	CreateDatetime time.Time `db:"create_datetime"`
+	UpdateDatetime time.Time `db:"update_datetime"`
+}
+
+type WatchlistTicker struct {
+	WatchlistTickerId uint64    `db:"watchlist_ticker_id"`
+	WatchlistId       uint64    `db:"watchlist_id"`
+	TickerId          uint64    `db:"ticker_id"`
+	TickerSymbol      string    `db:"ticker_symbol"`
+	SortOrder         int       `db:"sort_order"`
+	Note              string    `db:"note"`
+	CreateDatetime    time.Time `db:"create_datetime"`
+	UpdateDatetime    time.Time `db:"update_datetime"`
+}
+
+var errWatchlistNotFound = errors.New("unknown watchlist")
+
+// shows a watchlist someone has shared, to anyone with the link
+func viewWatchlistHandler(deps *Dependencies) http.HandlerFunc {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rc := getRequestContext(r)
+		webdata := rc.webdata
+		watcher := checkAuthState(w, r, deps, *rc.logger)
+
+		params := mux.Vars(r)
+		shareToken := params["token"]
+
+		sublog := rc.logger.With().Str("watcher", watcher.EId).Str("share_token", shareToken).Logger()
+
+		watchlist, err := getSharedWatchlist(deps, sublog, shareToken)
+		if err != nil {
+			sublog.Warn().Err(err).Msg("failed to find shared watchlist")
+			rc.messages = append(rc.messages, Message{"Sorry, that watchlist isn't shared, or doesn't exist", "error"})
+			renderTemplate(w, r, deps, sublog, "watchlist")
+			return
+		}
+
+		tickerQuotes, err := getWatchlistQuotes(deps, sublog, watcher, watchlist)
+		if err != nil {
+			sublog.Error().Err(err).Msg("getWatchlistQuotes failed")
+			rc.messages = append(rc.messages, Message{"Sorry, one or more ticker symbols could not be found", "error"})
+		}
+		webdata["Watchlist"] = watchlist
+		webdata["TickerQuotes"] = tickerQuotes
+		webdata["SharedView"] = true
+		webdata["QuotesMarketOpen"], webdata["QuotesSession"] = listSession(tickerQuotes)
+
+		renderTemplate(w, r, deps, sublog, "watchlist")
+	})
+}
+
+// object methods -------------------------------------------------------------
+
+func (wl *Watchlist) create(deps *Dependencies, sublog zerolog.Logger) error {
+	watchlistId, err := deps.watchlists.CreateWatchlist(*wl)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to create watchlist")
+		return err
+	}
+	wl.WatchlistId = watchlistId
+	wl.EId = encryptId(deps, sublog, "watchlist", wl.WatchlistId)
+	return nil
+}
+
+func (wl *Watchlist) update(deps *Dependencies, sublog zerolog.Logger) error {
+	err := deps.watchlists.UpdateWatchlist(*wl)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to update watchlist")
+	}
+	return err
+}
+
+func (wl *Watchlist) delete(deps *Dependencies, sublog zerolog.Logger) error {
+	err := deps.watchlists.DeleteWatchlist(wl.WatchlistId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to delete watchlist")
+	}
+	return err
+}
+
+// sharing hands out a new link each time, so unsharing and sharing again
+// shuts out anyone holding the old one; the link is all that guards the list,
+// so it comes from crypto/rand
+func (wl *Watchlist) share(deps *Dependencies, sublog zerolog.Logger, shared bool) error {
+	wl.ShareToken = ""
+	if shared {
+		token := make([]byte, watchlistShareTokenLen)
+		if _, err := crand.Read(token); err != nil {
+			sublog.Error().Err(err).Msg("failed to generate watchlist share token")
+			return err
+		}
+		wl.ShareToken = base64.RawURLEncoding.EncodeToString(token)
+	}
+	return wl.update(deps, sublog)
+}
+
+func (wl Watchlist) SharePath() string {
+	if wl.ShareToken == "" {
+		return ""
+	}
+	return "/watchlist/" + wl.ShareToken
+}
+
+func (wl Watchlist) entries(deps *Dependencies, sublog zerolog.Logger) ([]WatchlistTicker, error) {
+	entries, err := deps.watchlists.GetWatchlistTickers(sublog, wl.WatchlistId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to get watchlist tickers")
+	}
+	return entries, err
+}
+
+// adding a ticker already on the list just updates its note
+func (wl Watchlist) addTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, note string) error {
+	entries, err := wl.entries(deps, sublog)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.TickerId == ticker.TickerId {
+			if note == "" {
+				return nil
+			}
+			entry.Note = note
+			return deps.watchlists.UpdateWatchlistTicker(entry)
+		}
+	}
+	if len(entries) >= maxWatchlistSymbols {
+		return fmt.Errorf("watchlist already has %d symbols", maxWatchlistSymbols)
+	}
+	return deps.watchlists.AddWatchlistTicker(WatchlistTicker{WatchlistId: wl.WatchlistId, TickerId: ticker.TickerId, Note: note})
+}
+
+func (wl Watchlist) removeTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) error {
+	return deps.watchlists.RemoveWatchlistTicker(wl.WatchlistId, ticker.TickerId)
+}
+
+func (wl Watchlist) setNote(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, note string) error {
+	entries, err := wl.entries(deps, sublog)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.TickerId == ticker.TickerId {
+			entry.Note = note
+			return deps.watchlists.UpdateWatchlistTicker(entry)
+		}
+	}
+	return fmt.Errorf("%s is not on the watchlist", ticker.TickerSymbol)
+}
+
+// move the ticker to position (1 is the top) and renumber the rest around it
+func (wl Watchlist) moveTicker(deps *Dependencies, sublog zerolog.Logger, ticker Ticker, position int) error {
+	entries, err := wl.entries(deps, sublog)
+	if err != nil {
+		return err
+	}
+
+	from := -1
+	for x, entry := range entries {
+		if entry.TickerId == ticker.TickerId {
+			from = x
+			break
+		}
+	}
+	if from == -1 {
+		return fmt.Errorf("%s is not on the watchlist", ticker.TickerSymbol)
+	}
+	to := position - 1
+	if to < 0 {
+		to = 0
+	}
+	if to >= len(entries) {
+		to = len(entries) - 1
+	}
+
+	moving := entries[from]
+	entries = append(entries[:from], entries[from+1:]...)
+	entries = append(entries[:to], append([]WatchlistTicker{moving}, entries[to:]...)...)
+
+	for x, entry := range entries {
+		if entry.SortOrder == x+1 {
+			continue
+		}
+		entry.SortOrder = x + 1
+		err := deps.watchlists.UpdateWatchlistTicker(entry)
+		if err != nil {
+			sublog.Error().Err(err).Msg("failed to reorder watchlist")
+			return err
+		}
+	}
+	return nil
+}
+
+// misc -----------------------------------------------------------------------
+
+func getWatcherWatchlists(deps *Dependencies, sublog zerolog.Logger, watcher Watcher) ([]Watchlist, error) {
+	if watcher.WatcherId == 0 {
+		return []Watchlist{}, nil
+	}
+
+	watchlists, err := deps.watchlists.GetWatcherWatchlists(sublog, watcher.WatcherId)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to get watchlists")
+		return []Watchlist{}, err
+	}
+	for x := range watchlists {
+		watchlists[x].EId = encryptId(deps, sublog, "watchlist", watchlists[x].WatchlistId)
+	}
+	return watchlists, nil
+}
+
+// one of the watcher's own watchlists, by its EId
+func getWatcherWatchlist(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, watchlistEId string) (Watchlist, error) {
+	watchlists, err := getWatcherWatchlists(deps, sublog, watcher)
+	if err != nil {
+		return Watchlist{}, err
+	}
+	for _, watchlist := range watchlists {
+		if watchlist.EId == watchlistEId {
+			return watchlist, nil
+		}
+	}
+	return Watchlist{}, errWatchlistNotFound
+}
+
+func getSharedWatchlist(deps *Dependencies, sublog zerolog.Logger, shareToken string) (Watchlist, error) {
+	if shareToken == "" {
+		return Watchlist{}, errWatchlistNotFound
+	}
+	watchlist, err := deps.watchlists.GetWatchlistByShareToken(shareToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Watchlist{}, errWatchlistNotFound
+	}
+	if err != nil {
+		return Watchlist{}, err
+	}
+	watchlist.EId = encryptId(deps, sublog, "watchlist", watchlist.WatchlistId)
+	return watchlist, nil
+}
+
+func createWatchlist(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, name string) (Watchlist, error) {
+	name = cleanWatchlistName(name)
+	if name == "" {
+		return Watchlist{}, fmt.Errorf("watchlist needs a name")
+	}
+
+	watchlists, err := getWatcherWatchlists(deps, sublog, watcher)
+	if err != nil {
+		return Watchlist{}, err
+	}
+	if len(watchlists) >= maxWatchlists {
+		return Watchlist{}, fmt.Errorf("already have %d watchlists", maxWatchlists)
+	}
+	for _, watchlist := range watchlists {
+		if strings.EqualFold(watchlist.WatchlistName, name) {
+			return Watchlist{}, fmt.Errorf("already have a watchlist named %q", name)
+		}
+	}
+
+	watchlist := Watchlist{WatcherId: watcher.WatcherId, WatchlistName: name}
+	err = watchlist.create(deps, sublog)
+	return watchlist, err
+}
+
+// the watchlist's tickers in its order, each with its note
+func getWatchlistQuotes(deps *Dependencies, sublog zerolog.Logger, watcher Watcher, watchlist Watchlist) ([]TickerQuote, error) {
+	entries, err := watchlist.entries(deps, sublog)
+	if err != nil {
+		return []TickerQuote{}, err
+	}
+	if len(entries) == 0 {
+		return []TickerQuote{}, nil
+	}
+
+	symbols := make([]string, 0, len(entries))
+	notes := make(map[uint64]string, len(entries))
+	for _, entry := range entries {
+		symbols = append(symbols, entry.TickerSymbol)
+		notes[entry.TickerId] = entry.Note
+	}
+
+	tickerQuotes, err := getListQuotes(deps, sublog, watcher, symbols)
+	if err != nil {
+		return []TickerQuote{}, err
+	}
+	for x := range tickerQuotes {
+		tickerQuotes[x].Note = notes[tickerQuotes[x].Ticker.TickerId]
+	}
+	return tickerQuotes, nil
+}
+
+func cleanWatchlistName(name string) string {
+	return truncateText(strings.Join(strings.Fields(name), " "), maxWatchlistNameLength)
+}
+
+func cleanWatchlistNote(note string) string {
+	return truncateText(strings.TrimSpace(note), maxWatchlistNoteLength)
+}
+
+// at most max characters, not cutting one in half
+func truncateText(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return strings.TrimSpace(string(runes[:max]))
+}
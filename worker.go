@@ -0,0 +1,222 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// a tickerTask does the work for one action on the ticker queue
+type tickerTask func(deps *Dependencies, sublog zerolog.Logger, symbol string) error
+
+// by the "action" attribute that queueUpdateInfo, queueUpdateNews,
+// queueUpdateFinancials and queueSaveFavIcon put on their messages
+var tickerTasks = map[string]tickerTask{
+	"info":       updateTickerInfoTask,
+	"news":       updateTickerNewsTask,
+	"financials": updateTickerFinancialsTask,
+	"favicon":    saveTickerFavIconTask,
+}
+
+// misc -----------------------------------------------------------------------
+
+// `stockwatch worker` runs this, the scheduler and the alert evaluator,
+// instead of the web server until SIGTERM
+func startWorker(deps *Dependencies) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		runScheduler(deps, ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		runAlertEvaluator(deps, ctx)
+	}()
+
+	runWorker(deps, ctx)
+	wg.Wait()
+}
+
+// pulls messages off the ticker queue and hands them to WorkerConcurrency
+// goroutines. Once ctx is done it stops receiving, lets anything already
+// received finish and returns
+func runWorker(deps *Dependencies, ctx context.Context) {
+	config := deps.config
+	sublog := deps.logger.With().Str("task", "worker").Logger()
+
+	messages := make(chan QueueMessage)
+	var wg sync.WaitGroup
+	for i := 0; i < config.WorkerConcurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for message := range messages {
+				handleTickerMessage(deps, ctx, sublog, message)
+			}
+		}()
+	}
+	sublog.Info().Str("queue", config.TickerQueueName).Int("concurrency", config.WorkerConcurrency).Msg("started worker")
+
+	for ctx.Err() == nil {
+		received, err := deps.queue.Receive(config.TickerQueueName, 10)
+		if err != nil {
+			sublog.Error().Err(err).Str("queue", config.TickerQueueName).Msg("failed to receive from queue")
+		}
+		if len(received) == 0 {
+			// SQS long-polls for us, but the local queue comes back empty at once
+			select {
+			case <-ctx.Done():
+			case <-time.After(workerIdleWait * time.Second):
+			}
+			continue
+		}
+		for _, message := range received {
+			messages <- message
+		}
+	}
+
+	close(messages)
+	wg.Wait()
+	sublog.Info().Msg("stopped worker")
+}
+
+// run the task, retrying with backoff, and record how it went in lastdone.
+// Once it has failed WorkerMaxAttempts times it goes to the dead-letter queue
+// instead; either way it is then deleted, unless it couldn't be dead-lettered
+// in which case the queue will hand it out again later. Shutting down during a
+// backoff leaves the message for the queue to hand out again as well
+func handleTickerMessage(deps *Dependencies, ctx context.Context, sublog zerolog.Logger, message QueueMessage) {
+	config := deps.config
+	action := message.Attributes["action"]
+
+	var body TaskTickerBody
+	err := json.Unmarshal([]byte(message.Body), &body)
+	sublog = sublog.With().Str("action", action).Str("symbol", body.TickerSymbol).Logger()
+
+	task, ok := tickerTasks[action]
+	if err != nil || !ok || body.TickerSymbol == "" {
+		// no number of retries will fix a message we can't read
+		sublog.Error().Err(err).Str("body", message.Body).Msg("unusable ticker task")
+		if deadLetterTickerMessage(deps, sublog, message, fmt.Errorf("unusable ticker task: %v", err)) {
+			deleteTickerMessage(deps, sublog, message)
+		}
+		return
+	}
+
+	start := time.Now()
+	attempt := 1
+	for {
+		err = task(deps, sublog, body.TickerSymbol)
+		if err == nil || attempt >= config.WorkerMaxAttempts {
+			break
+		}
+		backoff := time.Duration(workerRetryBackoff<<(attempt-1)) * time.Second
+		sublog.Warn().Err(err).Int("attempt", attempt).Dur("backoff", backoff).Msg("ticker task failed, will retry")
+		select {
+		case <-ctx.Done():
+			sublog.Info().Int("attempts", attempt).Msg("shutting down, leaving ticker task on the queue")
+			return
+		case <-time.After(backoff):
+		}
+		attempt++
+	}
+
+	lastdone := LastDone{
+		Activity:         "ticker_" + action,
+		UniqueKey:        body.TickerSymbol,
+		LastStatus:       "success",
+		LastDoneDatetime: sql.NullTime{Valid: true, Time: time.Now()},
+	}
+	if err != nil {
+		lastdone.LastStatus = "failure"
+	}
+	if lderr := lastdone.createOrUpdate(deps); lderr != nil {
+		sublog.Error().Err(lderr).Msg("failed to record lastdone")
+	}
+
+	if err != nil {
+		sublog.Error().Err(err).Int("attempts", attempt).Msg("ticker task failed")
+		if !deadLetterTickerMessage(deps, sublog, message, err) {
+			return
+		}
+	} else {
+		sublog.Info().Int("attempts", attempt).Int64("response_time", time.Since(start).Nanoseconds()).Msg("ticker task done")
+	}
+	deleteTickerMessage(deps, sublog, message)
+}
+
+// the message goes to the dead-letter queue as it was, plus why it failed
+func deadLetterTickerMessage(deps *Dependencies, sublog zerolog.Logger, message QueueMessage, cause error) bool {
+	deadLetterQueue := deps.config.TickerDeadLetterQueueName
+
+	attributes := map[string]string{}
+	for name, value := range message.Attributes {
+		attributes[name] = value
+	}
+	attributes["error"] = cause.Error()
+	attributes["failed_at"] = time.Now().UTC().Format(time.RFC3339)
+
+	err := deps.queue.Send(deadLetterQueue, message.Body, attributes)
+	if err != nil {
+		sublog.Error().Err(err).Str("queue", deadLetterQueue).Msg("failed to dead-letter ticker task")
+		return false
+	}
+	sublog.Warn().Str("queue", deadLetterQueue).Msg("dead-lettered ticker task")
+	return true
+}
+
+func deleteTickerMessage(deps *Dependencies, sublog zerolog.Logger, message QueueMessage) {
+	err := deps.queue.Delete(deps.config.TickerQueueName, message)
+	if err != nil {
+		sublog.Error().Err(err).Msg("failed to delete ticker task from queue")
+	}
+}
+
+// ticker tasks ---------------------------------------------------------------
+
+func updateTickerInfoTask(deps *Dependencies, sublog zerolog.Logger, symbol string) error {
+	ticker, err := fetchTickerInfo(deps, sublog, symbol)
+	if err != nil {
+		return err
+	}
+	if ticker.needEODs(deps, sublog) {
+		return fetchTickerEODs(deps, sublog, ticker)
+	}
+	return nil
+}
+
+func updateTickerNewsTask(deps *Dependencies, sublog zerolog.Logger, symbol string) error {
+	ticker, err := getTickerBySymbol(deps, sublog, symbol)
+	if err != nil {
+		return err
+	}
+	return fetchTickerNews(deps, sublog, ticker)
+}
+
+func updateTickerFinancialsTask(deps *Dependencies, sublog zerolog.Logger, symbol string) error {
+	ticker, err := getTickerBySymbol(deps, sublog, symbol)
+	if err != nil {
+		return err
+	}
+	return fetchTickerFinancials(deps, sublog, ticker)
+}
+
+func saveTickerFavIconTask(deps *Dependencies, sublog zerolog.Logger, symbol string) error {
+	ticker, err := getTickerBySymbol(deps, sublog, symbol)
+	if err != nil {
+		return err
+	}
+	return ticker.saveFavIcon(deps, sublog)
+}
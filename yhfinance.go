@@ -1,7 +1,6 @@
 package main
 
 import (
-	"database/sql"
 	"encoding/json"
 	"fmt"
 	"strings"
@@ -9,22 +8,128 @@ import (
 
 	"github.com/gomodule/redigo/redis"
 	"github.com/rs/zerolog"
-	"github.com/rs/zerolog/log"
 	"github.com/weirdtangent/yhfinance"
 )
 
-// fetch ticker info (and possibly new exchange) from yhfinance
-func fetchTickerInfoFromYH(deps *Dependencies, sublog zerolog.Logger, symbol string) (Ticker, error) {
+// yhfinance on RapidAPI, the original (and default) market data provider
+type yhfinanceProvider struct {
+	apiKey  string
+	apiHost string
+}
+
+// the yhfinance package doesn't model the news and financials responses, so
+// these pick out just the fields we keep
+type yhRaw struct {
+	Raw float64 `json:"raw"`
+}
+
+// the historical feed's events are splits and dividends together, but the
+// yhfinance package only carries the split fields
+type yhHistoricalEventsResponse struct {
+	EventsData []struct {
+		Date   int64   `json:"date"`
+		Type   string  `json:"type"`
+		Amount float64 `json:"amount"`
+	} `json:"eventsData"`
+}
+
+type yhExtendedQuotesResponse struct {
+	QuoteResponse struct {
+		Result []struct {
+			Symbol                  string  `json:"symbol"`
+			PreMarketPrice          float64 `json:"preMarketPrice"`
+			PreMarketChange         float64 `json:"preMarketChange"`
+			PreMarketChangePercent  float64 `json:"preMarketChangePercent"`
+			PreMarketTime           int64   `json:"preMarketTime"`
+			PostMarketPrice         float64 `json:"postMarketPrice"`
+			PostMarketChange        float64 `json:"postMarketChange"`
+			PostMarketChangePercent float64 `json:"postMarketChangePercent"`
+			PostMarketTime          int64   `json:"postMarketTime"`
+		} `json:"result"`
+	} `json:"quoteResponse"`
+}
+
+type yhNewsResponse struct {
+	Data struct {
+		Main struct {
+			Stream []struct {
+				Id      string `json:"id"`
+				Content struct {
+					Title    string `json:"title"`
+					Summary  string `json:"summary"`
+					PubDate  string `json:"pubDate"`
+					Provider struct {
+						DisplayName string `json:"displayName"`
+					} `json:"provider"`
+					ClickThroughURL struct {
+						URL string `json:"url"`
+					} `json:"clickThroughUrl"`
+					CanonicalURL struct {
+						URL string `json:"url"`
+					} `json:"canonicalUrl"`
+					Thumbnail struct {
+						Resolutions []struct {
+							URL string `json:"url"`
+						} `json:"resolutions"`
+					} `json:"thumbnail"`
+					Finance struct {
+						StockTickers []struct {
+							Symbol string `json:"symbol"`
+						} `json:"stockTickers"`
+					} `json:"finance"`
+				} `json:"content"`
+			} `json:"stream"`
+		} `json:"main"`
+	} `json:"data"`
+}
+
+type yhIncomeStatement struct {
+	EndDate         yhRaw `json:"endDate"`
+	TotalRevenue    yhRaw `json:"totalRevenue"`
+	GrossProfit     yhRaw `json:"grossProfit"`
+	OperatingIncome yhRaw `json:"operatingIncome"`
+	NetIncome       yhRaw `json:"netIncome"`
+}
+
+type yhFinancialsResponse struct {
+	IncomeStatementHistory struct {
+		IncomeStatementHistory []yhIncomeStatement `json:"incomeStatementHistory"`
+	} `json:"incomeStatementHistory"`
+	IncomeStatementHistoryQuarterly struct {
+		IncomeStatementHistory []yhIncomeStatement `json:"incomeStatementHistory"`
+	} `json:"incomeStatementHistoryQuarterly"`
+}
+
+type yhMoversResponse struct {
+	Finance struct {
+		Result []struct {
+			CanonicalName string `json:"canonicalName"`
+			Quotes        []struct {
+				Symbol string `json:"symbol"`
+			} `json:"quotes"`
+		} `json:"result"`
+	} `json:"finance"`
+}
+
+// yhfinance names for the lists, and what we call them in the mover table
+var yhMoverTypes = map[string]string{
+	"DAY_GAINERS":  "gainer",
+	"DAY_LOSERS":   "loser",
+	"MOST_ACTIVES": "active",
+}
+
+// object methods -------------------------------------------------------------
+
+func (yh yhfinanceProvider) Name() string {
+	return "yhfinance"
+}
+
+func (yh yhfinanceProvider) GetSummary(deps *Dependencies, sublog zerolog.Logger, symbol string) (yhfinance.YHStockSummaryResponse, error) {
 	redisPool := deps.redisPool
-	secrets := deps.secrets
-	sublog = sublog.With().Str("symbol", symbol).Logger()
 
 	redisConn := redisPool.Get()
 	defer redisConn.Close()
 
-	apiKey := secrets["yhfinance_rapidapi_key"]
-	apiHost := secrets["yhfinance_rapidapi_host"]
-
 	// pull recent response from redis (1 day expire), or go get from YF
 	redisKey := "yhfinance/summary/" + symbol
 	response, err := redis.String(redisConn.Do("GET", redisKey))
@@ -32,10 +137,10 @@ func fetchTickerInfoFromYH(deps *Dependencies, sublog zerolog.Logger, symbol str
 		sublog.Info().Str("redis_key", redisKey).Msg("redis cache hit")
 	} else {
 		start := time.Now()
-		response, err = yhfinance.GetYHFinanceStockSummary(&sublog, apiKey, apiHost, symbol)
+		response, err = yhfinance.GetYHFinanceStockSummary(&sublog, yh.apiKey, yh.apiHost, symbol)
 		sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: yhfinance stockSummary")
 		if err != nil {
-			return Ticker{}, err
+			return yhfinance.YHStockSummaryResponse{}, err
 		}
 
 		_, err = redisConn.Do("SET", redisKey, response, "EX", 60*60*24)
@@ -44,321 +149,306 @@ func fetchTickerInfoFromYH(deps *Dependencies, sublog zerolog.Logger, symbol str
 		}
 	}
 
-	var summaryResponse yhfinance.YHStockSummaryResponse
-	err = json.NewDecoder(strings.NewReader(response)).Decode(&summaryResponse)
-	if err != nil {
-		sublog.Fatal().Err(err).Msg("failed to decode json")
-	}
-
-	// can't create exchange - all we get is ExchangeCode and I can't find a
-	// table to translate those to Exchange MIC or Acronym... so I have to
-	// link them manually for now
-	exchange := Exchange{ExchangeCode: summaryResponse.Price.ExchangeCode}
-	err = exchange.getByCode(deps, sublog)
-	if err != nil {
-		sublog.Error().Err(err).Str("ticker", summaryResponse.QuoteType.Symbol).Str("exchange_code", summaryResponse.Price.ExchangeCode).Msg("failed to find exchange_code matched to exchange mic record")
-		return Ticker{}, err
-	}
-
-	// create/update ticker
-	ticker := Ticker{
-		0,
-		"",
-		summaryResponse.QuoteType.Symbol,
-		summaryResponse.Price.QuoteType,
-		summaryResponse.QuoteType.Market,
-		exchange.ExchangeId,
-		summaryResponse.QuoteType.ShortName,
-		summaryResponse.QuoteType.LongName,
-		summaryResponse.SummaryProfile.Address1,
-		summaryResponse.SummaryProfile.City,
-		summaryResponse.SummaryProfile.State,
-		summaryResponse.SummaryProfile.Zip,
-		summaryResponse.SummaryProfile.Country,
-		summaryResponse.SummaryProfile.Website,
-		summaryResponse.SummaryProfile.Phone,
-		summaryResponse.SummaryProfile.Sector,
-		summaryResponse.SummaryProfile.Industry,
-		summaryResponse.Price.RegularMarketPrice.Raw,
-		summaryResponse.Price.RegularMarketPreviousClose.Raw,
-		summaryResponse.Price.RegularMarketVolume.Raw,
-		time.Unix(summaryResponse.Price.RegularMarketTime, 0),
-		"",
-		time.Now(),
-		"",
-		time.Now(),
-		time.Now(),
-	}
-	err = ticker.createOrUpdate(deps, sublog)
-	if err != nil {
-		sublog.Error().Err(err).Str("symbol", ticker.TickerSymbol).Msg("failed to create or update ticker")
-		return Ticker{}, err
-	}
-	if ticker.TickerSymbol == "" || ticker.TickerId == 0 {
-		sublog.Fatal().Interface("ticker", ticker).Str("quotetype_symbol", summaryResponse.QuoteType.Symbol).Msg("ticker object is not saved")
-	}
-
-	tickerDescription := TickerDescription{0, "", ticker.TickerId, summaryResponse.SummaryProfile.LongBusinessSummary, time.Now(), time.Now()}
-	if len(summaryResponse.SummaryProfile.LongBusinessSummary) > 0 {
-		tickerDescription.BusinessSummary = summaryResponse.SummaryProfile.LongBusinessSummary
-	} else if len(summaryResponse.AssetProfile.LongBusinessSummary) > 0 {
-		tickerDescription.BusinessSummary = summaryResponse.AssetProfile.LongBusinessSummary
-	}
-	err = tickerDescription.createOrUpdate(deps, sublog)
-	if err != nil {
-		sublog.Error().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to create ticker description")
-	}
-
-	// create upgrade/downgrade recommendations
-	for _, updown := range summaryResponse.UpgradeDowngradeHistory.Histories {
-		updownDate := time.Unix(updown.GradeDate, 0)
-		UpDown := TickerUpDown{0, "", ticker.TickerId, updown.Action, updown.FromGrade, updown.ToGrade, sql.NullTime{Valid: true, Time: updownDate}, updown.Firm, "", time.Now(), time.Now()}
-		UpDown.createIfNew(deps, sublog)
-	}
-
-	// create/update ticker_attributes
-	ticker.createOrUpdateAttribute(deps, sublog, "sector", "", summaryResponse.SummaryProfile.Sector)
-	ticker.createOrUpdateAttribute(deps, sublog, "industry", "", summaryResponse.SummaryProfile.Industry)
-	ticker.createOrUpdateAttribute(deps, sublog, "short_ratio", "", summaryResponse.DefaultKeyStatistics.ShortRatio.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "last_split_date", "", summaryResponse.DefaultKeyStatistics.LastSplitDate.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "last_dividend_date", "", summaryResponse.DefaultKeyStatistics.LastDividendDate.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "shares_short", "", summaryResponse.DefaultKeyStatistics.SharesShort.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "float_shares", "", summaryResponse.DefaultKeyStatistics.FloatShares.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "forward_eps", "", summaryResponse.DefaultKeyStatistics.ForwardEPS.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "enterprize_to_revenue", "", summaryResponse.DefaultKeyStatistics.EnterprizeToRevenue.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "enterprize_to_ebita", "", summaryResponse.DefaultKeyStatistics.EnterprizeToEbita.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "held_percent_insiders", "", summaryResponse.DefaultKeyStatistics.HeldPercentInsiders.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "shares_outstanding", "", summaryResponse.DefaultKeyStatistics.SharesOutstanding.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "fund_inception_date", "", summaryResponse.DefaultKeyStatistics.FundInceptionDate.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "total_assets", "", summaryResponse.DefaultKeyStatistics.TotalAssets.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "fund_family", "", summaryResponse.DefaultKeyStatistics.FundFamily)
-	ticker.createOrUpdateAttribute(deps, sublog, "price_hint", "", summaryResponse.DefaultKeyStatistics.PriceHint.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "ytd_return", "", summaryResponse.DefaultKeyStatistics.YtdReturn.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "three_year_avg_return", "", summaryResponse.DefaultKeyStatistics.ThreeYearAverageReturn.Fmt)
-	ticker.createOrUpdateAttribute(deps, sublog, "five_year_avg_return", "", summaryResponse.DefaultKeyStatistics.FiveYearAverageReturn.Fmt)
-
-	return ticker, nil
+	return decodeYHSummary(response)
 }
 
-// load ticker up-to-date quote
-func fetchTickerQuoteFromYH(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) (yhfinance.YHQuote, error) {
-	secrets := deps.secrets
-
-	apiKey := secrets["yhfinance_rapidapi_key"]
-	apiHost := secrets["yhfinance_rapidapi_host"]
-
-	quote := yhfinance.YHQuote{}
-	quoteParams := map[string]string{"symbols": ticker.TickerSymbol, "region": ticker.TickerMarket}
-	response, err := yhfinance.GetFromYHFinance(&sublog, apiKey, apiHost, "marketQuote", quoteParams)
+func (yh yhfinanceProvider) GetQuote(deps *Dependencies, sublog zerolog.Logger, symbol, region string) (Quote, error) {
+	quoteParams := map[string]string{"symbols": symbol, "region": region}
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "marketQuote", quoteParams)
 	if err != nil {
-		return quote, err
+		return Quote{}, err
 	}
 
-	quoteResponse := yhfinance.YHGetQuotesResponse{}
-	err = json.NewDecoder(strings.NewReader(response)).Decode(&quoteResponse)
+	quotes, err := decodeYHQuotes(response)
 	if err != nil {
-		return quote, err
+		return Quote{}, err
 	}
-	if len(quoteResponse.QuoteResponse.Quotes) == 0 {
-		return quote, fmt.Errorf("failed to get response data back from yhfinance")
+	if len(quotes) == 0 {
+		return Quote{}, fmt.Errorf("failed to get response data back from yhfinance")
 	}
 
-	quote = quoteResponse.QuoteResponse.Quotes[0]
-
-	return quote, nil
+	return quotes[0], nil
 }
 
-func loadMultiTickerQuotes(deps *Dependencies, sublog zerolog.Logger, symbols []string) (map[string]yhfinance.YHQuote, error) {
+func (yh yhfinanceProvider) GetQuotes(deps *Dependencies, sublog zerolog.Logger, symbols []string) (map[string]Quote, error) {
 	redisPool := deps.redisPool
-	secrets := deps.secrets
 
 	redisConn := redisPool.Get()
 	defer redisConn.Close()
 
-	apiKey := secrets["yhfinance_rapidapi_key"]
-	apiHost := secrets["yhfinance_rapidapi_host"]
+	quotes := map[string]Quote{}
 
-	quotes := map[string]yhfinance.YHQuote{}
-
-	start := time.Now()
-	var err error
 	quoteParams := map[string]string{"symbols": strings.Join(symbols, ",")}
-	sublog.Info().Str("symbols", strings.Join(symbols, ",")).Msg("getting multi-symbol quote from yhfinance")
-	fullResponse, err := yhfinance.GetFromYHFinance(&sublog, apiKey, apiHost, "marketQuote", quoteParams)
-	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: yhfinance multi marketQuote")
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "marketQuote", quoteParams)
 	if err != nil {
-		log.Warn().Err(err).Str("symbols", strings.Join(symbols, ",")).Msg("failed to retrieve quote")
 		return quotes, err
 	}
 
-	var quoteResponse yhfinance.YHGetQuotesResponse
-	json.NewDecoder(strings.NewReader(fullResponse)).Decode(&quoteResponse)
-
-	for n, response := range quoteResponse.QuoteResponse.Quotes {
-		symbol := quoteResponse.QuoteResponse.Quotes[n].Symbol
+	quoteList, _ := decodeYHQuotes(response)
+	for _, quote := range quoteList {
+		symbol := quote.Symbol
 		redisKey := "yhfinance/quote/" + symbol
-		_, err = redisConn.Do("SET", redisKey, response, "EX", 20)
+		_, err = redisConn.Do("SET", redisKey, quote, "EX", 20)
 		if err != nil {
 			sublog.Error().Err(err).Str("ticker", symbol).Str("redis_key", redisKey).Msg("failed to save to redis")
 		}
 
 		sublog.Info().Str("symbol", symbol).Msg("found yhfinance quote response for {symbol}")
-		quotes[symbol] = quoteResponse.QuoteResponse.Quotes[n]
+		quotes[symbol] = quote
 	}
 
 	return quotes, nil
 }
 
-// load ticker historical prices
-func fetchTickerEODsFromYH(deps *Dependencies, sublog zerolog.Logger, ticker Ticker) error {
-	secrets := deps.secrets
+func (yh yhfinanceProvider) GetHistorical(deps *Dependencies, sublog zerolog.Logger, symbol string) (HistoricalData, error) {
+	historicalParams := map[string]string{"symbol": symbol}
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "stockHistorical", historicalParams)
+	if err != nil {
+		return HistoricalData{}, err
+	}
 
-	historicalParams := map[string]string{"symbol": ticker.TickerSymbol}
+	return decodeYHHistorical(response)
+}
 
-	apiKey := secrets["yhfinance_rapidapi_key"]
-	apiHost := secrets["yhfinance_rapidapi_host"]
-	if apiKey == "" || apiHost == "" {
-		sublog.Fatal().Msg("apiKey or apiHost secret is missing")
+func (yh yhfinanceProvider) GetNews(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]NewsItem, error) {
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "newsList", yhNewsParams(symbol))
+	if err != nil {
+		return nil, err
 	}
-	response, err := yhfinance.GetFromYHFinance(&sublog, apiKey, apiHost, "stockHistorical", historicalParams)
+
+	return decodeYHNews(response)
+}
+
+func (yh yhfinanceProvider) GetMovers(deps *Dependencies, sublog zerolog.Logger) (map[string][]string, error) {
+	moversParams := map[string]string{"region": "US", "lang": "en-US", "count": "10", "start": "0"}
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "marketMovers", moversParams)
 	if err != nil {
-		sublog.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to retrieve historical prices")
-		return err
+		return nil, err
 	}
 
-	var historicalResponse yhfinance.YHHistoricalDataResponse
-	json.NewDecoder(strings.NewReader(response)).Decode(&historicalResponse)
+	return decodeYHMovers(response)
+}
 
-	var lastErr error
-	for _, price := range historicalResponse.Prices {
-		tickerDaily := TickerDaily{0, "", ticker.TickerId, time.Unix(price.Date, 0), price.Open, price.High, price.Low, price.Close, price.Volume, time.Now(), time.Now()}
-		err = tickerDaily.createOrUpdate(deps, sublog)
-		if err != nil {
-			lastErr = err
-		}
+func (yh yhfinanceProvider) GetFinancials(deps *Dependencies, sublog zerolog.Logger, symbol string) ([]FinancialsValue, error) {
+	financialsParams := map[string]string{"symbol": symbol, "region": "US"}
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "stockFinancials", financialsParams)
+	if err != nil {
+		return nil, err
 	}
-	if lastErr != nil {
-		log.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to load at least one historical price")
+
+	return decodeYHFinancials(response)
+}
+
+func (yh yhfinanceProvider) Search(deps *Dependencies, sublog zerolog.Logger, searchString string) (yhfinance.YHAutoCompleteResponse, error) {
+	searchParams := map[string]string{"q": searchString, "region": "US"}
+	response, err := yhfinance.GetFromYHFinance(&sublog, yh.apiKey, yh.apiHost, "autocomplete", searchParams)
+	if err != nil {
+		return yhfinance.YHAutoCompleteResponse{}, err
 	}
 
-	for _, split := range historicalResponse.Events {
-		tickerSplit := TickerSplit{0, "", ticker.TickerId, time.Unix(split.Date, 0), split.SplitRatio, time.Now(), time.Now()}
-		err = tickerSplit.createIfNew(deps, sublog)
-		if err != nil {
-			lastErr = err
-		}
+	return decodeYHAutoComplete(response)
+}
+
+// misc -----------------------------------------------------------------------
+
+func newYHFinanceProvider(deps *Dependencies) MarketDataProvider {
+	secrets := deps.secrets
+	sublog := deps.logger
+
+	apiKey := secrets["yhfinance_rapidapi_key"]
+	apiHost := secrets["yhfinance_rapidapi_host"]
+	if apiKey == "" || apiHost == "" {
+		sublog.Fatal().Msg("apiKey or apiHost secret is missing")
 	}
-	if lastErr != nil {
-		log.Warn().Err(err).Str("ticker", ticker.TickerSymbol).Msg("failed to load at least one historical split")
+
+	provider := yhfinanceProvider{apiKey: apiKey, apiHost: apiHost}
+	if deps.config.MarketDataRecord {
+		fixtureDir := deps.config.MarketDataFixtureDir
+		sublog.Info().Str("dir", fixtureDir).Msg("recording market data fixtures")
+		return recordingProvider{provider, fixtureDir}
 	}
+	return provider
+}
 
-	return nil
+// leaving out the symbol gets general market news
+func yhNewsParams(symbol string) map[string]string {
+	newsParams := map[string]string{"region": "US", "snippetCount": "28"}
+	if symbol != "" {
+		newsParams["s"] = symbol
+	}
+	return newsParams
 }
 
-// search for ticker and return highest scored quote symbol
-func jumpSearch(deps *Dependencies, sublog zerolog.Logger, searchString string) (SearchResultTicker, error) {
-	var searchResult SearchResultTicker
+func decodeYHSummary(response string) (yhfinance.YHStockSummaryResponse, error) {
+	var summaryResponse yhfinance.YHStockSummaryResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&summaryResponse)
+	return summaryResponse, err
+}
 
-	searchResults, err := listSearch(deps, sublog, searchString, "ticker")
+// the extended-hours fields are read from the same response and matched up
+// by symbol
+func decodeYHQuotes(response string) ([]Quote, error) {
+	var quoteResponse yhfinance.YHGetQuotesResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&quoteResponse)
 	if err != nil {
-		return searchResult, err
+		return []Quote{}, err
 	}
-	if len(searchResults) == 0 {
-		return searchResult, fmt.Errorf("sorry, the search returned zero results")
+	var extendedResponse yhExtendedQuotesResponse
+	err = json.NewDecoder(strings.NewReader(response)).Decode(&extendedResponse)
+	if err != nil {
+		return []Quote{}, err
 	}
 
-	var highestScore float64 = 0
-	for _, result := range searchResults {
-		if result.ResultType == "ticker" && result.Ticker.SearchScore > highestScore {
-			searchResult = result.Ticker
-			highestScore = result.Ticker.SearchScore
+	quotes := make([]Quote, 0, len(quoteResponse.QuoteResponse.Quotes))
+	for _, yhQuote := range quoteResponse.QuoteResponse.Quotes {
+		quote := Quote{YHQuote: yhQuote}
+		for _, extended := range extendedResponse.QuoteResponse.Result {
+			if extended.Symbol != yhQuote.Symbol {
+				continue
+			}
+			quote.PreMarketPrice = extended.PreMarketPrice
+			quote.PreMarketChange = extended.PreMarketChange
+			quote.PreMarketChangePct = extended.PreMarketChangePercent
+			quote.PostMarketPrice = extended.PostMarketPrice
+			quote.PostMarketChange = extended.PostMarketChange
+			quote.PostMarketChangePct = extended.PostMarketChangePercent
+			if extended.PreMarketTime > 0 {
+				quote.PreMarketTime = time.Unix(extended.PreMarketTime, 0)
+			}
+			if extended.PostMarketTime > 0 {
+				quote.PostMarketTime = time.Unix(extended.PostMarketTime, 0)
+			}
 		}
+		quotes = append(quotes, quote)
 	}
-	if searchResult.TickerSymbol == "" {
-		return searchResult, fmt.Errorf("sorry, the search returned zero results")
-	}
-
-	return searchResult, nil
+	return quotes, nil
 }
 
-// search for ticker or news
-func listSearch(deps *Dependencies, sublog zerolog.Logger, searchString string, resultTypes string) ([]SearchResult, error) {
-	secrets := deps.secrets
-
-	apiKey := secrets["yhfinance_rapidapi_key"]
-	apiHost := secrets["yhfinance_rapidapi_host"]
-
-	start := time.Now()
-	searchResults := make([]SearchResult, 100)
-	searchParams := map[string]string{"q": searchString, "region": "US"}
-	response, err := yhfinance.GetFromYHFinance(&sublog, apiKey, apiHost, "autocomplete", searchParams)
-	sublog.Info().Int64("response_time", time.Since(start).Nanoseconds()).Msg("timer: yhfinance autocomplete")
+func decodeYHHistorical(response string) (HistoricalData, error) {
+	var historicalResponse yhfinance.YHHistoricalDataResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&historicalResponse)
 	if err != nil {
-		return searchResults, err
+		return HistoricalData{}, err
 	}
 
-	newsCount := 0
-	tickerCount := 0
-	var searchResponse yhfinance.YHAutoCompleteResponse
-	json.NewDecoder(strings.NewReader(response)).Decode(&searchResponse)
+	var eventsResponse yhHistoricalEventsResponse
+	err = json.NewDecoder(strings.NewReader(response)).Decode(&eventsResponse)
+	if err != nil {
+		return HistoricalData{}, err
+	}
 
-	if resultTypes == "ticker" && len(searchResponse.Quotes) == 0 {
-		return searchResults, fmt.Errorf("sorry, the search returned zero results")
+	historical := HistoricalData{
+		Prices:    make([]HistoricalPrice, 0, len(historicalResponse.Prices)),
+		Splits:    make([]HistoricalSplit, 0, len(historicalResponse.Events)),
+		Dividends: make([]HistoricalDividend, 0, len(eventsResponse.EventsData)),
+	}
+	for _, price := range historicalResponse.Prices {
+		historical.Prices = append(historical.Prices, HistoricalPrice{time.Unix(price.Date, 0), price.Open, price.High, price.Low, price.Close, price.Volume})
+	}
+	// a dividend comes through as an event without a ratio
+	for _, split := range historicalResponse.Events {
+		if split.SplitRatio != "" {
+			historical.Splits = append(historical.Splits, HistoricalSplit{time.Unix(split.Date, 0), split.SplitRatio})
+		}
 	}
-	if resultTypes == "news" && len(searchResponse.News) == 0 {
-		return searchResults, fmt.Errorf("sorry, the search returned zero results")
+	for _, event := range eventsResponse.EventsData {
+		if strings.EqualFold(event.Type, "DIVIDEND") && event.Amount > 0 {
+			historical.Dividends = append(historical.Dividends, HistoricalDividend{time.Unix(event.Date, 0), event.Amount})
+		}
 	}
-	if resultTypes == "both" && len(searchResponse.Quotes)+len(searchResponse.News) == 0 {
-		return searchResults, fmt.Errorf("sorry, the search returned zero results")
+	return historical, nil
+}
+
+func decodeYHAutoComplete(response string) (yhfinance.YHAutoCompleteResponse, error) {
+	var searchResponse yhfinance.YHAutoCompleteResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&searchResponse)
+	return searchResponse, err
+}
+
+func decodeYHNews(response string) ([]NewsItem, error) {
+	var newsResponse yhNewsResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&newsResponse)
+	if err != nil {
+		return nil, err
 	}
 
-	if resultTypes == "news" || resultTypes == "both" {
-		for _, newsResult := range searchResponse.News {
-			newsCount++
-			searchResults = append(searchResults, SearchResult{
-				ResultType: "news",
-				News: SearchResultNews{
-					Publisher:   newsResult.Publisher,
-					Title:       newsResult.Title,
-					Type:        newsResult.Type,
-					URL:         newsResult.URL,
-					PublishDate: time.Unix(newsResult.PublishTime, 0).Format("Jan 2 15:04 MST 2006"),
-				},
-				Ticker: SearchResultTicker{},
-			})
+	news := make([]NewsItem, 0, len(newsResponse.Data.Main.Stream))
+	for _, story := range newsResponse.Data.Main.Stream {
+		content := story.Content
+		item := NewsItem{
+			ExternalId: story.Id,
+			Publisher:  content.Provider.DisplayName,
+			Title:      content.Title,
+			Summary:    content.Summary,
+			URL:        content.ClickThroughURL.URL,
 		}
+		if item.URL == "" {
+			item.URL = content.CanonicalURL.URL
+		}
+		if len(content.Thumbnail.Resolutions) > 0 {
+			item.ImageURL = content.Thumbnail.Resolutions[0].URL
+		}
+		item.Published, _ = time.Parse(time.RFC3339, content.PubDate)
+		for _, stockTicker := range content.Finance.StockTickers {
+			item.Symbols = append(item.Symbols, stockTicker.Symbol)
+		}
+		news = append(news, item)
 	}
+	return news, nil
+}
 
-	if resultTypes == "ticker" || resultTypes == "both" {
-		for _, quoteResult := range searchResponse.Quotes {
-			if quoteResult.Type == "Option" {
-				continue
-			}
-			exchange := Exchange{ExchangeCode: quoteResult.ExchangeCode}
-			err := exchange.getByCode(deps, sublog)
-			if err != nil {
-				sublog.Error().Err(err).Str("symbol", quoteResult.Symbol).Str("exchange_code", quoteResult.ExchangeCode).Msg("skipping {symbol} with unknown {exchange_code}")
-				continue
+// revenue and net income as bars, gross and operating income as lines, and
+// the three margins as percentage lines, for both quarters and years
+func decodeYHFinancials(response string) ([]FinancialsValue, error) {
+	var financialsResponse yhFinancialsResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&financialsResponse)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]FinancialsValue, 0)
+	terms := map[string][]yhIncomeStatement{
+		"Quarterly": financialsResponse.IncomeStatementHistoryQuarterly.IncomeStatementHistory,
+		"Annual":    financialsResponse.IncomeStatementHistory.IncomeStatementHistory,
+	}
+	for term, statements := range terms {
+		for _, statement := range statements {
+			date := time.Unix(int64(statement.EndDate.Raw), 0)
+			add := func(chartType string, isPercentage bool, name string, value float64) {
+				values = append(values, FinancialsValue{term, chartType, isPercentage, name, date, value})
 			}
-			if exchange.ExchangeId > 0 {
-				tickerCount++
-				searchResults = append(searchResults, SearchResult{
-					ResultType: "ticker",
-					News:       SearchResultNews{},
-					Ticker: SearchResultTicker{
-						TickerSymbol: quoteResult.Symbol,
-						ExchangeMic:  exchange.ExchangeMic,
-						Type:         quoteResult.Type,
-						ShortName:    quoteResult.ShortName,
-						LongName:     quoteResult.LongName,
-						SearchScore:  quoteResult.Score,
-					},
-				})
+			revenue := statement.TotalRevenue.Raw
+
+			add("bar", false, "Revenue", revenue)
+			add("bar", false, "Net Income", statement.NetIncome.Raw)
+			add("line", false, "Gross Profit", statement.GrossProfit.Raw)
+			add("line", false, "Operating Income", statement.OperatingIncome.Raw)
+			if revenue != 0 {
+				add("line", true, "Gross Margin", statement.GrossProfit.Raw/revenue*100)
+				add("line", true, "Operating Margin", statement.OperatingIncome.Raw/revenue*100)
+				add("line", true, "Net Margin", statement.NetIncome.Raw/revenue*100)
 			}
 		}
 	}
+	return values, nil
+}
 
-	sublog.Info().Str("search_string", searchString).Int("news_count", newsCount).Int("ticker_count", tickerCount).Msg("Search results")
+func decodeYHMovers(response string) (map[string][]string, error) {
+	var moversResponse yhMoversResponse
+	err := json.NewDecoder(strings.NewReader(response)).Decode(&moversResponse)
+	if err != nil {
+		return nil, err
+	}
 
-	return searchResults, nil
+	movers := map[string][]string{}
+	for _, list := range moversResponse.Finance.Result {
+		moverType, ok := yhMoverTypes[list.CanonicalName]
+		if !ok {
+			continue
+		}
+		for _, quote := range list.Quotes {
+			movers[moverType] = append(movers[moverType], quote.Symbol)
+		}
+	}
+	return movers, nil
 }